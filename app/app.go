@@ -5,9 +5,11 @@ import (
 	"claude-squad/keys"
 	"claude-squad/log"
 	"claude-squad/session"
+	"claude-squad/session/git"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -19,6 +21,9 @@ import (
 
 const GlobalInstanceLimit = 10
 
+// shellWindowName is the name of the companion window opened by the shell key.
+const shellWindowName = "shell"
+
 // Run is the main entrypoint into the application.
 func Run(ctx context.Context, program string, autoYes bool) error {
 	p := tea.NewProgram(
@@ -42,6 +47,20 @@ const (
 	stateHelp
 	// stateConfirm is the state when a confirmation modal is displayed.
 	stateConfirm
+	// stateHistory is the state when the preview tab is browsing a session's scrollback.
+	stateHistory
+	// stateQueue is the state when the prompt queue panel is displayed.
+	stateQueue
+	// stateFanOut is the state when the user is entering the programs and prompt of a fan-out.
+	stateFanOut
+	// stateCompare is the state when the sessions of a fan-out are compared.
+	stateCompare
+	// stateDiff is the state when the diff tab is navigated file by file.
+	stateDiff
+	// stateComment is the state when the user is entering a review comment on a line of the diff.
+	stateComment
+	// stateConflicts is the state when the sessions that changed the same files as the selected one are displayed.
+	stateConflicts
 )
 
 type home struct {
@@ -51,11 +70,20 @@ type home struct {
 
 	program string
 	autoYes bool
+	// programFromConfig is true if program is the configured default program, so it follows config changes.
+	// autoYesFromFlag is true if auto-yes was turned on with a flag, so config changes don't turn it off.
+	programFromConfig bool
+	autoYesFromFlag   bool
 
 	// storage is the interface for saving/loading data to/from the app's state
 	storage *session.Storage
 	// appConfig stores persistent application configuration
 	appConfig *config.Config
+	// configWatcher reloads appConfig when the config files change. Changes are sent to configMsgs until
+	// configDone is closed.
+	configWatcher *config.Watcher
+	configMsgs    chan tea.Msg
+	configDone    chan struct{}
 	// appState stores persistent application state like seen help screens
 	appState config.AppState
 
@@ -69,6 +97,12 @@ type home struct {
 
 	// promptAfterName tracks if we should enter prompt mode after naming
 	promptAfterName bool
+	// promptToQueue is true if the prompt being entered goes into the selected instance's queue.
+	promptToQueue bool
+	// fanOutPrograms holds the programs of the fan-out being entered, once they're entered. The prompt comes next.
+	fanOutPrograms []string
+	// autoPausing holds the instances the idle policy is pausing in the background, which the tick leaves alone.
+	autoPausing map[*session.Instance]bool
 
 	// keySent is used to manage underlining menu items
 	keySent bool
@@ -91,11 +125,17 @@ type home struct {
 	textOverlay *overlay.TextOverlay
 	// confirmationOverlay displays confirmation modals
 	confirmationOverlay *overlay.ConfirmationOverlay
+	// queuePanel displays the prompt queue of the selected instance
+	queuePanel *ui.QueuePanel
+	// comparePanel displays the sessions of the selected instance's fan-out side by side
+	comparePanel *ui.ComparePanel
+	// conflictPanel displays the sessions that changed the same files as the selected instance
+	conflictPanel *ui.ConflictPanel
 }
 
 func newHome(ctx context.Context, program string, autoYes bool) *home {
-	// Load application config
-	appConfig := config.LoadConfig()
+	// Load application config, with the current repository's overrides
+	appConfig := config.LoadRepoConfig(".")
 
 	// Load application state
 	appState := config.LoadState()
@@ -119,8 +159,15 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		autoYes:      autoYes,
 		state:        stateDefault,
 		appState:     appState,
+
+		programFromConfig: program == appConfig.DefaultProgram,
+		autoYesFromFlag:   autoYes && !appConfig.AutoYes,
 	}
 	h.list = ui.NewList(&h.spinner, autoYes)
+	if err := keys.Rebind(appConfig.KeyBindings); err != nil {
+		log.ErrorLog.Printf("failed to apply key bindings: %v", err)
+	}
+	h.watchConfig()
 
 	// Load saved instances
 	instances, err := storage.LoadInstances()
@@ -162,6 +209,15 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	if m.textOverlay != nil {
 		m.textOverlay.SetWidth(int(float32(msg.Width) * 0.6))
 	}
+	if m.queuePanel != nil {
+		m.queuePanel.SetWidth(int(float32(msg.Width) * 0.6))
+	}
+	if m.comparePanel != nil {
+		m.comparePanel.SetWidth(int(float32(msg.Width) * 0.8))
+	}
+	if m.conflictPanel != nil {
+		m.conflictPanel.SetWidth(int(float32(msg.Width) * 0.8))
+	}
 
 	previewWidth, previewHeight := m.tabbedWindow.GetPreviewSize()
 	if err := m.list.SetSessionPreviewSize(previewWidth, previewHeight); err != nil {
@@ -180,6 +236,7 @@ func (m *home) Init() tea.Cmd {
 			return previewTickMsg{}
 		},
 		tickUpdateMetadataCmd,
+		m.listenForConfig,
 	)
 }
 
@@ -201,27 +258,52 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case tickUpdateMetadataMessage:
 		for _, instance := range m.list.GetInstances() {
-			if !instance.Started() || instance.Paused() {
+			if !instance.Started() || instance.Paused() || m.autoPausing[instance] {
 				continue
 			}
-			updated, prompt := instance.HasUpdated()
-			if updated {
-				instance.SetStatus(session.Running)
-			} else {
-				if prompt {
-					instance.TapEnter()
+			// A crashed session has no program to watch, but its worktree can still change.
+			if !instance.Crashed() && !instance.DetectCrash() {
+				updated, prompt := instance.HasUpdated()
+				if updated {
+					instance.SetStatus(session.Running)
 				} else {
-					instance.SetStatus(session.Ready)
+					if prompt {
+						instance.TapEnter()
+					} else {
+						instance.SetStatus(session.Ready)
+					}
+				}
+				if _, err := instance.DeliverQueued(); err != nil {
+					log.ErrorLog.Print(err)
 				}
 			}
 			if err := instance.UpdateDiffStats(); err != nil {
 				log.WarningLog.Printf("could not update diff stats: %v", err)
 			}
 		}
-		return m, tickUpdateMetadataCmd
+		m.list.SetConflicts(session.FindConflicts(m.list.GetInstances()))
+		return m, tea.Batch(tickUpdateMetadataCmd, m.enforceIdlePolicy())
+	case autoPausedMsg:
+		for _, instance := range msg.instances {
+			delete(m.autoPausing, instance)
+		}
+		if msg.err != nil {
+			log.ErrorLog.Print(msg.err)
+		}
+		if msg.paused > 0 {
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return m, m.handleError(err)
+			}
+		}
+		return m, nil
+	case mergeResultMsg:
+		if m.conflictPanel != nil && m.conflictPanel.Instance() == msg.instance {
+			m.conflictPanel.SetMergeResult(msg.other, msg.result, msg.err)
+		}
+		return m, nil
 	case tea.MouseMsg:
-		// Handle mouse wheel scrolling in the diff view
-		if m.tabbedWindow.IsInDiffTab() {
+		// Handle mouse wheel scrolling in the diff view and the history view
+		if m.tabbedWindow.IsInDiffTab() || m.tabbedWindow.IsInHistory() {
 			if msg.Action == tea.MouseActionPress {
 				switch msg.Button {
 				case tea.MouseButtonWheelUp:
@@ -234,6 +316,10 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, nil
+	case configChangedMsg:
+		return m, tea.Batch(m.applyConfig(msg.cfg, msg.changed), m.listenForConfig)
+	case configErrorMsg:
+		return m, tea.Batch(m.handleError(fmt.Errorf("config not reloaded: %w", msg.err)), m.listenForConfig)
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 	case tea.WindowSizeMsg:
@@ -257,6 +343,7 @@ func (m *home) handleQuit() (tea.Model, tea.Cmd) {
 	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
 		return m, m.handleError(err)
 	}
+	m.stopConfigWatcher()
 	return m, tea.Quit
 }
 
@@ -267,7 +354,9 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 		m.keySent = false
 		return nil, false
 	}
-	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm {
+	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm || m.state == stateHistory ||
+		m.state == stateQueue || m.state == stateFanOut || m.state == stateCompare || m.state == stateDiff ||
+		m.state == stateComment || m.state == stateConflicts {
 		return nil, false
 	}
 	// If it's in the global keymap, we should try to highlight it.
@@ -276,7 +365,8 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 		return nil, false
 	}
 
-	if m.list.GetSelectedInstance() != nil && m.list.GetSelectedInstance().Paused() && name == keys.KeyEnter {
+	if selected := m.list.GetSelectedInstance(); selected != nil && (selected.Paused() || selected.Crashed()) &&
+		name == keys.KeyEnter {
 		return nil, false
 	}
 	if name == keys.KeyShiftDown || name == keys.KeyShiftUp {
@@ -401,13 +491,24 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				if selected == nil {
 					return m, nil
 				}
-				if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
+				if m.promptToQueue {
+					if err := selected.Enqueue(m.textInputOverlay.GetValue()); err != nil {
+						return m, m.handleError(err)
+					}
+				} else if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
 					return m, m.handleError(err)
 				}
 			}
 
 			// Close the overlay and reset state
 			m.textInputOverlay = nil
+			if m.promptToQueue {
+				// Go back to the queue panel
+				m.promptToQueue = false
+				m.state = stateQueue
+				m.menu.SetState(ui.StateDefault)
+				return m, tea.WindowSize()
+			}
 			m.state = stateDefault
 			return m, tea.Sequence(
 				tea.WindowSize(),
@@ -433,6 +534,110 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		return m, nil
 	}
 
+	// Handle queue state
+	if m.state == stateQueue {
+		action, err := m.queuePanel.HandleKeyPress(msg)
+		if err != nil {
+			return m, m.handleError(err)
+		}
+		switch action {
+		case ui.QueueActionAdd:
+			m.textInputOverlay = overlay.NewTextInputOverlay("Queue prompt", "")
+			m.promptToQueue = true
+			m.state = statePrompt
+			m.menu.SetState(ui.StatePrompt)
+			return m, tea.WindowSize()
+		case ui.QueueActionClose:
+			m.queuePanel = nil
+			m.state = stateDefault
+		}
+		return m, nil
+	}
+
+	if m.state == stateFanOut {
+		return m.handleFanOutState(msg)
+	}
+
+	// Handle compare state
+	if m.state == stateCompare {
+		switch m.comparePanel.HandleKeyPress(msg) {
+		case ui.CompareActionKeep:
+			keep := m.comparePanel.Selected()
+			siblings := len(m.list.GetGroup(keep.Group)) - 1
+			m.comparePanel = nil
+			message := fmt.Sprintf("[!] Keep '%s' and kill %d other session(s)?", keep.Title, siblings)
+			return m, m.confirmAction(message, m.keepFanOut(keep))
+		case ui.CompareActionClose:
+			m.comparePanel = nil
+			m.state = stateDefault
+		}
+		return m, nil
+	}
+
+	// Handle conflicts state
+	if m.state == stateConflicts {
+		switch m.conflictPanel.HandleKeyPress(msg) {
+		case ui.ConflictActionMerge:
+			return m, simulateMerge(m.conflictPanel.Instance(), m.conflictPanel.Selected())
+		case ui.ConflictActionClose:
+			m.conflictPanel = nil
+			m.state = stateDefault
+		}
+		return m, nil
+	}
+
+	// Handle diff navigation state
+	if m.state == stateDiff {
+		switch m.tabbedWindow.HandleDiffKey(msg) {
+		case ui.DiffActionComment:
+			path, line := m.tabbedWindow.DiffCommentTarget()
+			m.textInputOverlay = overlay.NewTextInputOverlay(fmt.Sprintf("Comment on %s:%d", path, line), "")
+			m.state = stateComment
+			return m, tea.WindowSize()
+		case ui.DiffActionReview:
+			selected := m.list.GetSelectedInstance()
+			if selected == nil {
+				return m, nil
+			}
+			if _, err := selected.SendReview(); err != nil {
+				return m, m.handleError(err)
+			}
+			m.tabbedWindow.UpdateDiff(selected)
+		case ui.DiffActionClose:
+			m.state = stateDefault
+		}
+		return m, nil
+	}
+
+	// Handle review comment state
+	if m.state == stateComment {
+		if !m.textInputOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		value, submitted := m.textInputOverlay.GetValue(), m.textInputOverlay.IsSubmitted()
+		m.textInputOverlay = nil
+		m.state = stateDiff
+		selected := m.list.GetSelectedInstance()
+		if !submitted || selected == nil {
+			return m, nil
+		}
+		path, line := m.tabbedWindow.DiffCommentTarget()
+		if _, err := selected.AddComment(session.ReviewComment{Path: path, StartLine: line, Body: value}); err != nil {
+			return m, m.handleError(err)
+		}
+		m.tabbedWindow.UpdateDiff(selected)
+		return m, nil
+	}
+
+	// Handle history state
+	if m.state == stateHistory {
+		if m.tabbedWindow.HandleHistoryKey(msg) {
+			m.state = stateDefault
+			return m, m.instanceChanged()
+		}
+		return m, nil
+	}
+
 	// Handle quit commands first
 	if msg.String() == "ctrl+c" || msg.String() == "q" {
 		return m.handleQuit()
@@ -455,6 +660,9 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			Title:   "",
 			Path:    ".",
 			Program: m.program,
+			Backend: m.appConfig.TerminalBackend,
+
+			HistoryLimit: m.appConfig.HistoryLimit,
 		})
 		if err != nil {
 			return m, m.handleError(err)
@@ -476,6 +684,9 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			Title:   "",
 			Path:    ".",
 			Program: m.program,
+			Backend: m.appConfig.TerminalBackend,
+
+			HistoryLimit: m.appConfig.HistoryLimit,
 		})
 		if err != nil {
 			return m, m.handleError(err)
@@ -507,6 +718,32 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		m.tabbedWindow.Toggle()
 		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
 		return m, m.instanceChanged()
+	case keys.KeyFiles:
+		if !m.tabbedWindow.IsInDiffTab() || m.list.GetSelectedInstance() == nil {
+			return m, nil
+		}
+		m.tabbedWindow.FocusDiff()
+		m.state = stateDiff
+		return m, nil
+	case keys.KeySplit:
+		if !m.tabbedWindow.IsInDiffTab() {
+			return m, nil
+		}
+		m.tabbedWindow.ToggleDiffSplit()
+		return m, nil
+	case keys.KeyHistory:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || m.tabbedWindow.IsInDiffTab() || !selected.Started() || selected.Paused() ||
+			selected.Crashed() {
+			return m, nil
+		}
+		content, err := selected.Scrollback(0, -1)
+		if err != nil {
+			return m, m.handleError(err)
+		}
+		m.tabbedWindow.EnterHistory(content)
+		m.state = stateHistory
+		return m, nil
 	case keys.KeyKill:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
@@ -585,7 +822,11 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
-		if err := selected.Resume(); err != nil {
+		resume := selected.Resume
+		if selected.Crashed() {
+			resume = selected.Revive
+		}
+		if err := resume(); err != nil {
 			return m, m.handleError(err)
 		}
 		return m, tea.WindowSize()
@@ -608,6 +849,65 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			m.state = stateDefault
 		})
 		return m, nil
+	case keys.KeyQueue:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		m.queuePanel = ui.NewQueuePanel(selected)
+		m.state = stateQueue
+		return m, tea.WindowSize()
+	case keys.KeyFanOut:
+		if m.list.NumInstances() >= GlobalInstanceLimit {
+			return m, m.handleError(
+				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+		}
+		// Start with the default program, so adding one per line is all it takes.
+		m.textInputOverlay = overlay.NewTextInputOverlay("Fan out: programs to compare, one per line", m.program)
+		m.fanOutPrograms = nil
+		m.state = stateFanOut
+		m.menu.SetState(ui.StatePrompt)
+		return m, tea.WindowSize()
+	case keys.KeyCompare:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || selected.Group == "" {
+			return m, nil
+		}
+		m.comparePanel = ui.NewComparePanel(selected.Group, m.list.GetGroup(selected.Group), selected)
+		m.state = stateCompare
+		return m, tea.WindowSize()
+	case keys.KeyConflicts:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		m.conflictPanel = ui.NewConflictPanel(selected, m.list.GetConflicts(selected))
+		m.state = stateConflicts
+		return m, tea.WindowSize()
+	case keys.KeyShell:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() || selected.Paused() || !selected.TmuxAlive() {
+			return m, nil
+		}
+		if !selected.HasWindow(shellWindowName) {
+			if err := selected.OpenWindow(shellWindowName, ""); err != nil {
+				return m, m.handleError(err)
+			}
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return m, m.handleError(err)
+			}
+		}
+		// Show help screen before attaching
+		m.showHelpScreen(helpTypeInstanceAttach, func() {
+			ch, err := selected.AttachWindow(shellWindowName)
+			if err != nil {
+				m.handleError(err)
+				return
+			}
+			<-ch
+			m.state = stateDefault
+		})
+		return m, nil
 	default:
 		return m, nil
 	}
@@ -653,6 +953,67 @@ type previewTickMsg struct{}
 
 type tickUpdateMetadataMessage struct{}
 
+// mergeResultMsg is the outcome of simulating the merge of instance and other.
+type mergeResultMsg struct {
+	instance, other *session.Instance
+	result          *git.MergeResult
+	err             error
+}
+
+// simulateMerge simulates the merge of instance and other in the background, since it runs git.
+func simulateMerge(instance, other *session.Instance) tea.Cmd {
+	return func() tea.Msg {
+		result, err := session.SimulateMerge(instance, other)
+		return mergeResultMsg{instance: instance, other: other, result: result, err: err}
+	}
+}
+
+// autoPausedMsg is the outcome of the idle policy pausing instances. paused counts the ones it paused.
+type autoPausedMsg struct {
+	instances []*session.Instance
+	paused    int
+	err       error
+}
+
+// enforceIdlePolicy pauses the instances that went idle, and the least recently active ones over the running limit,
+// in the background, since pausing commits the changes and removes the worktree.
+func (m *home) enforceIdlePolicy() tea.Cmd {
+	if len(m.autoPausing) > 0 {
+		return nil
+	}
+	policy := session.NewIdlePolicy(m.appConfig)
+	if !policy.Enabled() {
+		return nil
+	}
+	now := time.Now()
+	selected := policy.Select(m.list.GetInstances(), now)
+	if len(selected) == 0 {
+		return nil
+	}
+
+	// Pause them in list order, so they're paused in a predictable order.
+	var instances []*session.Instance
+	m.autoPausing = make(map[*session.Instance]bool, len(selected))
+	for _, instance := range m.list.GetInstances() {
+		if _, ok := selected[instance]; ok {
+			instances = append(instances, instance)
+			m.autoPausing[instance] = true
+		}
+	}
+	return func() tea.Msg {
+		var paused int
+		var errs []error
+		for _, instance := range instances {
+			if err := policy.Pause(instance, selected[instance], now); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			paused++
+		}
+		return autoPausedMsg{instances: instances, paused: paused, err: errors.Join(errs...)}
+	}
+}
+
 type instanceChangedMsg struct{}
 
 // tickUpdateMetadataCmd is the callback to update the metadata of the instances every 500ms. Note that we iterate
@@ -714,7 +1075,7 @@ func (m *home) View() string {
 		m.errBox.String(),
 	)
 
-	if m.state == statePrompt {
+	if m.state == statePrompt || m.state == stateFanOut || m.state == stateComment {
 		if m.textInputOverlay == nil {
 			log.ErrorLog.Printf("text input overlay is nil")
 		}
@@ -729,6 +1090,12 @@ func (m *home) View() string {
 			log.ErrorLog.Printf("confirmation overlay is nil")
 		}
 		return overlay.PlaceOverlay(0, 0, m.confirmationOverlay.Render(), mainView, true, true)
+	} else if m.state == stateQueue {
+		return overlay.PlaceOverlay(0, 0, m.queuePanel.Render(), mainView, true, true)
+	} else if m.state == stateCompare {
+		return overlay.PlaceOverlay(0, 0, m.comparePanel.Render(), mainView, true, true)
+	} else if m.state == stateConflicts {
+		return overlay.PlaceOverlay(0, 0, m.conflictPanel.Render(), mainView, true, true)
 	}
 
 	return mainView
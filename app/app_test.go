@@ -464,3 +464,22 @@ func TestConfirmationModalVisualAppearance(t *testing.T) {
 	// Test that the danger indicator is preserved
 	assert.Contains(t, rendered, "[!")
 }
+
+// TestFanOutTitle tests that fan-out titles come from the prompt and don't clash with existing sessions
+func TestFanOutTitle(t *testing.T) {
+	spinner := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := ui.NewList(&spinner, false)
+	h := &home{list: list}
+	programs := []string{"claude", "aider --model sonnet"}
+
+	assert.Equal(t, "fix-the-login-form", h.fanOutTitle("Fix the login form: it's broken!", programs))
+	assert.Equal(t, "add-retries-to-the", h.fanOutTitle("add retries to the HTTP client", programs))
+	assert.Equal(t, "fan-out", h.fanOutTitle("???", programs))
+
+	list.AddInstance(&session.Instance{Title: "fix-the-login-form-aider"})
+	assert.Equal(t, "fix-the-login-form-2", h.fanOutTitle("fix the login form", programs))
+
+	// Another fan-out's group is taken too, even if its sessions ran other programs
+	list.AddInstance(&session.Instance{Title: "add-retries-codex", Group: "add-retries"})
+	assert.Equal(t, "add-retries-2", h.fanOutTitle("add retries", programs))
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/keys"
+	"claude-squad/log"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configChangedMsg is sent when the config files change.
+type configChangedMsg struct {
+	cfg     *config.Config
+	changed []string
+}
+
+// configErrorMsg is sent when an edited config is invalid and was rejected.
+type configErrorMsg struct {
+	err error
+}
+
+// watchConfig starts reloading the config of the current repository when the config files change. Changes are
+// delivered to the update loop by listenForConfig.
+func (m *home) watchConfig() {
+	m.configMsgs = make(chan tea.Msg)
+	m.configDone = make(chan struct{})
+	send := func(msg tea.Msg) {
+		select {
+		case m.configMsgs <- msg:
+		case <-m.configDone:
+		}
+	}
+
+	m.configWatcher = config.NewWatcher(".", m.appConfig, config.DefaultWatchInterval)
+	m.configWatcher.OnChange = func(cfg *config.Config, changed []string) {
+		send(configChangedMsg{cfg: cfg, changed: changed})
+	}
+	m.configWatcher.OnError = func(err error) {
+		send(configErrorMsg{err: err})
+	}
+	m.configWatcher.Start()
+}
+
+// stopConfigWatcher stops the watcher started by watchConfig.
+func (m *home) stopConfigWatcher() {
+	if m.configWatcher == nil {
+		return
+	}
+	close(m.configDone)
+	m.configWatcher.Stop()
+	m.configWatcher = nil
+}
+
+// listenForConfig waits for the next config change.
+func (m *home) listenForConfig() tea.Msg {
+	if m.configMsgs == nil {
+		return nil
+	}
+	select {
+	case msg := <-m.configMsgs:
+		return msg
+	case <-m.configDone:
+		return nil
+	}
+}
+
+// applyConfig switches the running app to cfg, including the diff options of its instances. The program and auto-yes
+// only follow the config if they weren't overridden on the command line.
+func (m *home) applyConfig(cfg *config.Config, changed []string) tea.Cmd {
+	log.InfoLog.Printf("config changed: %s", strings.Join(changed, ", "))
+	m.appConfig = cfg
+	if m.programFromConfig {
+		m.program = cfg.DefaultProgram
+	}
+	if !m.autoYesFromFlag {
+		m.setAutoYes(cfg.AutoYes)
+	}
+	for _, instance := range m.list.GetInstances() {
+		instance.SetDiffOptions(cfg.Diff)
+	}
+	// The config was validated, so this only fails if the keys changed underneath it.
+	if err := keys.Rebind(cfg.KeyBindings); err != nil {
+		return m.handleError(fmt.Errorf("failed to apply key bindings: %w", err))
+	}
+	return nil
+}
+
+// setAutoYes turns auto-yes on or off for the app and all of its instances.
+func (m *home) setAutoYes(autoYes bool) {
+	m.autoYes = autoYes
+	m.list.SetAutoYes(autoYes)
+	for _, instance := range m.list.GetInstances() {
+		instance.AutoYes = autoYes
+	}
+}
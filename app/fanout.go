@@ -0,0 +1,177 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxFanOutTitle is the length of the group title derived from a fan-out's prompt. The program name is added to it
+// for each session's title.
+const maxFanOutTitle = 20
+
+// handleFanOutState handles key presses while the programs, and then the prompt, of a fan-out are entered.
+func (m *home) handleFanOutState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.textInputOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+
+	submitted := m.textInputOverlay.IsSubmitted()
+	value := m.textInputOverlay.GetValue()
+	m.textInputOverlay = nil
+	if submitted && m.fanOutPrograms == nil {
+		var programs []string
+		for _, line := range strings.Split(value, "\n") {
+			if program := strings.TrimSpace(line); program != "" {
+				programs = append(programs, program)
+			}
+		}
+		if len(programs) > 0 {
+			m.fanOutPrograms = programs
+			m.textInputOverlay = overlay.NewTextInputOverlay(
+				fmt.Sprintf("Fan out: prompt for %d programs", len(programs)), "")
+			return m, tea.WindowSize()
+		}
+	}
+
+	programs := m.fanOutPrograms
+	m.fanOutPrograms = nil
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+	if !submitted || programs == nil {
+		return m, tea.WindowSize()
+	}
+	if err := m.startFanOut(programs, value); err != nil {
+		return m, tea.Batch(tea.WindowSize(), m.handleError(err))
+	}
+	return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
+
+// startFanOut starts a session for each program, all from the same base commit, and queues prompt for each. If any
+// of them fails to start, the ones already started are killed.
+func (m *home) startFanOut(programs []string, prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("prompt cannot be empty")
+	}
+	if m.list.NumInstances()+len(programs) > GlobalInstanceLimit {
+		return fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit)
+	}
+
+	instances, err := session.NewFanOut(session.InstanceOptions{
+		Title:   m.fanOutTitle(prompt, programs),
+		Path:    ".",
+		Backend: m.appConfig.TerminalBackend,
+
+		HistoryLimit: m.appConfig.HistoryLimit,
+	}, programs)
+	if err != nil {
+		return err
+	}
+
+	for i, instance := range instances {
+		finalize := m.list.AddInstance(instance)
+		err := instance.Start(true)
+		if err == nil {
+			finalize()
+			instance.AutoYes = m.autoYes
+			// Queue the prompt, so each program gets it once it's ready for input.
+			err = instance.Enqueue(prompt)
+		}
+		if err != nil {
+			for _, added := range instances[:i+1] {
+				m.list.KillInstance(added)
+			}
+			return fmt.Errorf("failed to start %s: %w", instance.Title, err)
+		}
+	}
+	m.list.SetSelectedInstance(m.list.NumInstances() - len(instances))
+	return m.storage.SaveInstances(m.list.GetInstances())
+}
+
+// fanOutTitle derives the group title of a fan-out from its prompt, ex. "fix-the-login-form", numbered if another
+// fan-out has the title or the titles of its sessions are taken.
+func (m *home) fanOutTitle(prompt string, programs []string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(strings.ToLower(prompt)) {
+		word = strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+			return -1
+		}, word)
+		if word == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			word = "-" + word
+		}
+		if b.Len()+len(word) > maxFanOutTitle {
+			break
+		}
+		b.WriteString(word)
+	}
+	base := b.String()
+	if base == "" {
+		base = "fan-out"
+	}
+
+	taken := make(map[string]bool)
+	groups := make(map[string]bool)
+	for _, instance := range m.list.GetInstances() {
+		taken[instance.Title] = true
+		groups[instance.Group] = true
+	}
+	for n := 1; ; n++ {
+		title := base
+		if n > 1 {
+			title = fmt.Sprintf("%s-%d", base, n)
+		}
+		// Sharing a group would make keeping a session of one fan-out kill the sessions of the other
+		free := !groups[title]
+		for _, sibling := range session.FanOutTitles(title, programs) {
+			free = free && !taken[sibling]
+		}
+		if free {
+			return title
+		}
+	}
+}
+
+// keepFanOut returns an action that kills the other sessions of keep's fan-out and takes keep out of the group.
+func (m *home) keepFanOut(keep *session.Instance) tea.Cmd {
+	return func() tea.Msg {
+		for _, sibling := range m.list.GetGroup(keep.Group) {
+			if sibling == keep {
+				continue
+			}
+			worktree, err := sibling.GetGitWorktree()
+			if err != nil {
+				return err
+			}
+			checkedOut, err := worktree.IsBranchCheckedOut()
+			if err != nil {
+				return err
+			}
+			if checkedOut {
+				return fmt.Errorf("instance %s is currently checked out", sibling.Title)
+			}
+
+			// Delete from storage first
+			if err := m.storage.DeleteInstance(sibling.Title); err != nil {
+				return err
+			}
+			m.list.KillInstance(sibling)
+		}
+
+		keep.Group = ""
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return err
+		}
+		return instanceChangedMsg{}
+	}
+}
@@ -47,19 +47,30 @@ func (h helpType) ToContent(instance *session.Instance) string {
 			headerStyle.Render("Managing:"),
 			keyStyle.Render("n")+descStyle.Render("         - Create a new session"),
 			keyStyle.Render("N")+descStyle.Render("         - Create a new session with a prompt"),
+			keyStyle.Render("F")+descStyle.Render("         - Fan out: run one prompt in a session per program"),
+			keyStyle.Render("C")+descStyle.Render("         - Compare a fan-out's sessions and keep the best one"),
+			keyStyle.Render("M")+descStyle.Render("         - Show the sessions that changed the same files (⚠) and simulate merges"),
 			keyStyle.Render("D")+descStyle.Render("         - Kill (delete) the selected session"),
 			keyStyle.Render("↑/j, ↓/k")+descStyle.Render("  - Navigate between sessions"),
 			keyStyle.Render("↵/o")+descStyle.Render("       - Attach to the selected session"),
+			keyStyle.Render("s")+descStyle.Render("         - Open a shell in the selected session's worktree"),
+			keyStyle.Render("Q")+descStyle.Render("         - Queue follow-up prompts for the selected session"),
 			keyStyle.Render("ctrl-q")+descStyle.Render("    - Detach from session"),
 			"",
 			headerStyle.Render("Handoff:"),
 			keyStyle.Render("p")+descStyle.Render("         - Commit and push branch to github"),
 			keyStyle.Render("c")+descStyle.Render("         - Checkout: commit changes and pause session"),
-			keyStyle.Render("r")+descStyle.Render("         - Resume a paused session"),
+			keyStyle.Render("r")+descStyle.Render("         - Resume a paused session or restart a crashed one"),
 			"",
 			headerStyle.Render("Other:"),
 			keyStyle.Render("tab")+descStyle.Render("       - Switch between preview and diff tabs"),
 			keyStyle.Render("shift-↓/↑")+descStyle.Render(" - Scroll in diff view"),
+			keyStyle.Render("f")+descStyle.Render("         - Browse the diff file by file: n/N jumps between hunks, t hides the files"),
+			keyStyle.Render("c")+descStyle.Render("         - While browsing the diff, comment on the line under the cursor; x deletes a comment"),
+			keyStyle.Render("r")+descStyle.Render("         - While browsing the diff, send the new comments to the session as a review"),
+			keyStyle.Render("v")+descStyle.Render("         - Show the diff side by side, when the window is wide enough"),
+			keyStyle.Render("</>")+descStyle.Render("       - While browsing the diff, step through its state at the end of each turn"),
+			keyStyle.Render("h")+descStyle.Render("         - Browse and search the session's scrollback history"),
 			keyStyle.Render("q")+descStyle.Render("         - Quit the application"),
 		)
 		return content
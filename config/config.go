@@ -15,10 +15,20 @@ import (
 const (
 	ConfigFileName = "config.json"
 	defaultProgram = "claude"
+	// HomeEnvVar overrides the configuration directory.
+	HomeEnvVar = "CLAUDE_SQUAD_HOME"
 )
 
-// GetConfigDir returns the path to the application's configuration directory
+// GetConfigDir returns the path to the application's configuration directory. It's ~/.claude-squad unless
+// CLAUDE_SQUAD_HOME is set.
 func GetConfigDir() (string, error) {
+	if dir := os.Getenv(HomeEnvVar); dir != "" {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s: %w", HomeEnvVar, err)
+		}
+		return absDir, nil
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get config home directory: %w", err)
@@ -36,6 +46,87 @@ type Config struct {
 	DaemonPollInterval int `json:"daemon_poll_interval"`
 	// BranchPrefix is the prefix used for git branches created by the application.
 	BranchPrefix string `json:"branch_prefix"`
+	// BaseBranch is the branch or commit new session branches start from. Empty uses the repository's HEAD.
+	BaseBranch string `json:"base_branch,omitempty"`
+	// TerminalBackend is the backend programs run in: "tmux" or "headless". Empty uses tmux if it's installed
+	// and falls back to headless otherwise.
+	TerminalBackend string `json:"terminal_backend"`
+	// HistoryLimit is the number of scrollback lines each tmux session retains. Zero uses the tmux server's
+	// default (2000 unless changed in tmux.conf).
+	HistoryLimit int `json:"history_limit"`
+	// IdlePauseMinutes pauses sessions that have been ready with no new output for this many minutes, which
+	// commits their changes and frees their terminal and worktree. Zero disables it.
+	IdlePauseMinutes int `json:"idle_pause_minutes,omitempty"`
+	// MaxRunningSessions caps the number of sessions that aren't paused. When there are more, the least recently
+	// active ones are paused. Zero means no limit.
+	MaxRunningSessions int `json:"max_running_sessions,omitempty"`
+	// WorktreeRoot is the directory new worktrees are created in. Relative paths are relative to the repository
+	// root, ex. "../{repo}-worktrees" for a sibling of the repository, and "~/" is the home directory. It may use
+	// the same placeholders as WorktreeName, but must not be inside the repository. Empty uses the worktrees
+	// directory in the config directory.
+	WorktreeRoot string `json:"worktree_root,omitempty"`
+	// WorktreeName is the template for the directory name of new worktrees, ex. "{repo}-{title}". See
+	// WorktreeTemplateVars for the placeholders. If the directory already exists, a "-2", "-3", ... suffix is
+	// added. Empty uses DefaultWorktreeName.
+	WorktreeName string `json:"worktree_name,omitempty"`
+	// WorktreeSetup prepares new worktrees before the program starts and runs cleanup before they're removed.
+	WorktreeSetup WorktreeSetup `json:"worktree_setup"`
+	// Diff configures the diffs shown for sessions: which files are left out and how large they may get.
+	Diff DiffOptions `json:"diff"`
+	// KeyBindings rebinds TUI actions to other keys, ex. {"kill": ["X"]}. See keys.ActionNames for the actions.
+	KeyBindings map[string][]string `json:"key_bindings,omitempty"`
+	// TrustedRepos lists the roots of the repositories whose config file may set the keys in TrustedRepoKeys. It's
+	// only read from the global config and the environment, never from a repository's config.
+	TrustedRepos []string `json:"trusted_repos,omitempty"`
+}
+
+// DefaultWorktreeName is the worktree name template used when WorktreeName is empty.
+const DefaultWorktreeName = "{title}_{id}"
+
+// WorktreeTemplateVars are the placeholders of WorktreeRoot and WorktreeName: the name of the repository's
+// directory, the session title and branch name made safe for paths, and a unique hex timestamp.
+var WorktreeTemplateVars = []string{"repo", "title", "branch", "id"}
+
+// WorktreeSetup configures how session worktrees are prepared and torn down. A fresh worktree is a bare checkout,
+// so untracked files the program needs (ex. .env, node_modules) are copied or symlinked from the main repository.
+type WorktreeSetup struct {
+	// Copy lists files, directories or globs, relative to the repository root, to copy from the main repository
+	// into new worktrees. Paths that already exist in the worktree are left untouched.
+	Copy []string `json:"copy,omitempty"`
+	// Symlink is like Copy, but the worktree gets symlinks to the files in the main repository.
+	Symlink []string `json:"symlink,omitempty"`
+	// PostSetup lists shell commands run in the worktree after it's created and before the program starts.
+	PostSetup []string `json:"post_setup,omitempty"`
+	// PreCleanup lists shell commands run in the worktree before it's removed.
+	PreCleanup []string `json:"pre_cleanup,omitempty"`
+}
+
+const (
+	// DefaultDiffMaxFileLines is the most changed lines of a file shown when Diff.MaxFileLines is zero.
+	DefaultDiffMaxFileLines = 2000
+	// DefaultDiffMaxBytes is the largest a diff gets when Diff.MaxBytes is zero.
+	DefaultDiffMaxBytes = 1 << 20
+)
+
+// DiffOptions configures session diffs. The size limits keep a diff small enough to store and send with every
+// change; a file over them keeps its line counts but not its hunks.
+type DiffOptions struct {
+	// IgnoreWhitespace compares lines ignoring whitespace, like git diff -w. Files whose only changes are in
+	// whitespace are left out.
+	IgnoreWhitespace bool `json:"ignore_whitespace,omitempty"`
+	// Exclude lists gitignore-style patterns of files left out of diffs, ex. "vendor/", "*.lock" or "*.pb.go".
+	Exclude []string `json:"exclude,omitempty"`
+	// IncludeGenerated includes files marked linguist-generated in .gitattributes, which are left out by default.
+	IncludeGenerated bool `json:"include_generated,omitempty"`
+	// MaxFileLines is the most changed lines shown for a file. Larger files are summarized by their line counts.
+	// Zero uses DefaultDiffMaxFileLines.
+	MaxFileLines int `json:"max_file_lines,omitempty"`
+	// MaxBytes is the largest a diff gets. Files that don't fit are summarized by their line counts. Zero uses
+	// DefaultDiffMaxBytes.
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// KeepTurnDiffs keeps the whole diff each time the program finishes a turn, so the diff history can step through
+	// how the change evolved. Otherwise only the line counts are kept.
+	KeepTurnDiffs bool `json:"keep_turn_diffs,omitempty"`
 }
 
 // DefaultConfig returns the default configuration
@@ -50,6 +141,7 @@ func DefaultConfig() *Config {
 		DefaultProgram:     program,
 		AutoYes:            false,
 		DaemonPollInterval: 1000,
+		HistoryLimit:       10000,
 		BranchPrefix: func() string {
 			user, err := user.Current()
 			if err != nil || user == nil || user.Username == "" {
@@ -109,14 +201,40 @@ func GetClaudeCommand() (string, error) {
 	return "", fmt.Errorf("claude command not found in aliases or PATH")
 }
 
+// LoadConfig is like ReadConfig, but if the config can't be loaded the error is logged and the default config is
+// returned, so callers always get a usable config.
 func LoadConfig() *Config {
-	configDir, err := GetConfigDir()
+	cfg, err := ReadConfig()
 	if err != nil {
-		log.ErrorLog.Printf("failed to get config directory: %v", err)
+		log.ErrorLog.Printf("%v; using the default config", err)
 		return DefaultConfig()
 	}
+	return cfg
+}
+
+// ReadConfig loads the config file, applies the CLAUDE_SQUAD_* environment overrides and validates the result. If
+// the file doesn't exist, the default config is saved to it.
+func ReadConfig() (*Config, error) {
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config after applying environment overrides: %w", err)
+	}
+	return cfg, nil
+}
 
-	configPath := filepath.Join(configDir, ConfigFileName)
+// ReadConfigFile loads and validates the config file, without environment overrides. Keys missing from the file,
+// ex. ones added by a newer version, get their default values and are written back to the file.
+func ReadConfigFile() (*Config, error) {
+	configPath, err := ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -125,24 +243,88 @@ func LoadConfig() *Config {
 			if saveErr := saveConfig(defaultCfg); saveErr != nil {
 				log.WarningLog.Printf("failed to save default config: %v", saveErr)
 			}
-			return defaultCfg
+			return defaultCfg, nil
 		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
 
-		log.WarningLog.Printf("failed to get config file: %v", err)
-		return DefaultConfig()
+	config, missing, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
 	}
+	if len(missing) > 0 {
+		if err := saveConfig(config); err != nil {
+			log.WarningLog.Printf("failed to add %s to config file: %v", strings.Join(missing, ", "), err)
+		}
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates the contents of a config file. Missing keys get their default values.
+func ParseConfig(data []byte) (*Config, error) {
+	config, _, err := parseConfig(data)
+	return config, err
+}
 
+// parseConfig is ParseConfig, also returning the keys that were missing from data.
+func parseConfig(data []byte) (*Config, []string, error) {
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		log.ErrorLog.Printf("failed to parse config file: %v", err)
-		return DefaultConfig()
+	if err := decodeConfig(data, &config); err != nil {
+		return nil, nil, err
 	}
 
-	return &config
+	missing, err := missingKeys(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(missing) > 0 {
+		// DefaultConfig looks up the claude command, so only build it when it's needed.
+		config = *DefaultConfig()
+		if err := decodeConfig(data, &config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &config, missing, nil
 }
 
-// saveConfig saves the configuration to disk
+// missingKeys returns the top level keys that are always written to the config file but are missing from data.
+func missingKeys(data []byte) ([]string, error) {
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	all, err := toConfigObject(&Config{})
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, key := range sortedKeys(all) {
+		if _, ok := present[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+// ConfigFilePath returns the path of the global config file.
+func ConfigFilePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, ConfigFileName), nil
+}
+
+// saveConfig validates the configuration and saves it to disk
 func saveConfig(config *Config) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return fmt.Errorf("failed to get config directory: %w", err)
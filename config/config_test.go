@@ -237,3 +237,129 @@ func TestSaveConfig(t *testing.T) {
 		assert.Equal(t, testConfig.BranchPrefix, loadedConfig.BranchPrefix)
 	})
 }
+
+func TestReadConfig(t *testing.T) {
+	writeConfig := func(t *testing.T, content string) string {
+		tempHome := t.TempDir()
+		t.Setenv("HOME", tempHome)
+		configPath := filepath.Join(tempHome, ".claude-squad", ConfigFileName)
+		require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0755))
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+		return configPath
+	}
+
+	t.Run("reports invalid config files", func(t *testing.T) {
+		for content, want := range map[string]string{
+			"{\n  \"auto_yes\": true,\n}":                   "syntax error at line 3, column 1",
+			`{"auto_yes": "yes"}`:                           `auto_yes: expected true or false, got string`,
+			`{"default_programme": "aider"}`:                `unknown key "default_programme"`,
+			`{"worktree_setup": {"copy": ".env"}}`:          `worktree_setup.copy: expected a list, got string`,
+			`{"daemon_poll_interval": 0}`:                   `daemon_poll_interval: must be a positive number`,
+			`{"terminal_backend": "screen"}`:                `terminal_backend: must be "tmux", "headless" or empty`,
+			`{"worktree_setup": {"symlink": ["/etc"]}}`:     `worktree_setup.symlink: pattern "/etc" must be relative`,
+			`{"branch_prefix": "my branch/"}`:               `branch_prefix: "my branch/" must not contain whitespace`,
+			`{"key_bindings": {"nuke": ["x"]}}`:             `key_bindings: unknown action "nuke"`,
+			`{"worktree_root": "~/wt/{project}"}`:           `worktree_root: unknown placeholder {project}`,
+			`{"worktree_name": "{repo}/{title}"}`:           `worktree_name: "{repo}/{title}" must not contain path separators`,
+			`{"max_running_sessions": -2}`:                  `max_running_sessions: must not be negative`,
+			`{"diff": {"exclude": ["vendor/", " "]}}`:       `diff.exclude: patterns must not be empty`,
+			`{"diff": {"max_bytes": -1}}`:                   `diff.max_bytes: must not be negative`,
+			`{"history_limit": -1, "base_branch": "--all"}`: "base_branch: \"--all\" must not start with \"-\"\nhistory_limit: must not be negative",
+		} {
+			configPath := writeConfig(t, content)
+			_, err := ReadConfig()
+			require.Error(t, err, content)
+			assert.Contains(t, err.Error(), configPath)
+			assert.Contains(t, err.Error(), want)
+
+			// The config file is left untouched.
+			data, err := os.ReadFile(configPath)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		}
+	})
+
+	t.Run("fills in and saves missing keys", func(t *testing.T) {
+		configPath := writeConfig(t, `{"default_program": "aider", "branch_prefix": "me/"}`)
+		cfg, err := ReadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "aider", cfg.DefaultProgram)
+		assert.Equal(t, 1000, cfg.DaemonPollInterval)
+		assert.Equal(t, 10000, cfg.HistoryLimit)
+
+		data, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"history_limit": 10000`)
+		assert.Contains(t, string(data), `"default_program": "aider"`)
+	})
+
+	t.Run("applies environment overrides", func(t *testing.T) {
+		configPath := writeConfig(t, `{"default_program": "aider", "daemon_poll_interval": 1000}`)
+		t.Setenv("CLAUDE_SQUAD_AUTO_YES", "true")
+		t.Setenv("CLAUDE_SQUAD_HISTORY_LIMIT", "500")
+		t.Setenv("CLAUDE_SQUAD_WORKTREE_SETUP_COPY", ".env, .env.local")
+		cfg, err := ReadConfig()
+		require.NoError(t, err)
+		assert.True(t, cfg.AutoYes)
+		assert.Equal(t, 500, cfg.HistoryLimit)
+		assert.Equal(t, []string{".env", ".env.local"}, cfg.WorktreeSetup.Copy)
+
+		// Overrides aren't written to the config file.
+		file, err := ReadConfigFile()
+		require.NoError(t, err)
+		assert.False(t, file.AutoYes)
+		data, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), ".env")
+
+		t.Setenv("CLAUDE_SQUAD_HISTORY_LIMIT", "lots")
+		_, err = ReadConfig()
+		assert.ErrorContains(t, err, "invalid CLAUDE_SQUAD_HISTORY_LIMIT")
+		t.Setenv("CLAUDE_SQUAD_HISTORY_LIMIT", "-1")
+		_, err = ReadConfig()
+		assert.ErrorContains(t, err, "history_limit: must not be negative")
+	})
+
+	t.Run("CLAUDE_SQUAD_HOME overrides the config directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "squad")
+		t.Setenv(HomeEnvVar, dir)
+		configDir, err := GetConfigDir()
+		require.NoError(t, err)
+		assert.Equal(t, dir, configDir)
+
+		require.NoError(t, SaveConfig(&Config{DefaultProgram: "aider", DaemonPollInterval: 1000}))
+		assert.FileExists(t, filepath.Join(dir, ConfigFileName))
+		assert.Equal(t, "aider", LoadConfig().DefaultProgram)
+	})
+}
+
+func TestConfigGetSet(t *testing.T) {
+	cfg := &Config{DefaultProgram: "claude", DaemonPollInterval: 1000}
+
+	require.NoError(t, cfg.Set("auto_yes", "true"))
+	require.NoError(t, cfg.Set("history_limit", "42"))
+	require.NoError(t, cfg.Set("default_program", "aider --model sonnet"))
+	require.NoError(t, cfg.Set("worktree_setup.post_setup", `["npm ci", "make build"]`))
+	require.NoError(t, cfg.Set("worktree_setup.copy", ".env,config/*.json"))
+	assert.True(t, cfg.AutoYes)
+	assert.Equal(t, 42, cfg.HistoryLimit)
+	assert.Equal(t, "aider --model sonnet", cfg.DefaultProgram)
+	assert.Equal(t, []string{"npm ci", "make build"}, cfg.WorktreeSetup.PostSetup)
+	assert.Equal(t, []string{".env", "config/*.json"}, cfg.WorktreeSetup.Copy)
+
+	value, err := cfg.Get("worktree_setup.post_setup")
+	require.NoError(t, err)
+	assert.Equal(t, `["npm ci","make build"]`, value)
+	value, err = cfg.Get("history_limit")
+	require.NoError(t, err)
+	assert.Equal(t, "42", value)
+
+	assert.ErrorContains(t, cfg.Set("auto_yes", "sometimes"), "expected true or false")
+	assert.ErrorContains(t, cfg.Set("history_limit", "1e3"), "expected a number")
+	assert.ErrorContains(t, cfg.Set("autoyes", "true"), `unknown key "autoyes"`)
+	_, err = cfg.Get("worktree_setup")
+	assert.ErrorContains(t, err, "is an object")
+
+	assert.Contains(t, Keys(), "worktree_setup.pre_cleanup")
+	assert.Equal(t, "CLAUDE_SQUAD_WORKTREE_SETUP_PRE_CLEANUP", EnvVar("worktree_setup.pre_cleanup"))
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config values. The variable for a key is the
+// prefix followed by the upper cased key with dots replaced by underscores, ex. CLAUDE_SQUAD_AUTO_YES or
+// CLAUDE_SQUAD_WORKTREE_SETUP_COPY.
+const EnvPrefix = "CLAUDE_SQUAD_"
+
+// Keys returns the dotted JSON keys of all config values, ex. auto_yes and worktree_setup.copy.
+func Keys() []string {
+	return appendKeys(nil, "", reflect.TypeOf(Config{}))
+}
+
+func appendKeys(keys []string, prefix string, t reflect.Type) []string {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + jsonName(field)
+		if field.Type.Kind() == reflect.Struct {
+			keys = appendKeys(keys, key+".", field.Type)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// EnvVar returns the name of the environment variable that overrides key.
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Get returns the JSON encoded value of key.
+func (c *Config) Get(key string) (string, error) {
+	v, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	return string(data), nil
+}
+
+// Set parses value and sets key to it. Booleans and numbers use their usual text form. Lists are given as a JSON
+// list or as comma separated values, objects as JSON. The config isn't validated; use Validate afterwards.
+func (c *Config) Set(key, value string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", key, value)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: expected a number, got %q", key, value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Slice:
+		list, err := parseList(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		m := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(value), m.Interface()); err != nil {
+			return fmt.Errorf("%s: expected a JSON object, got %s", key, value)
+		}
+		v.Set(m.Elem())
+	default:
+		return fmt.Errorf("%s can't be set directly", key)
+	}
+	return nil
+}
+
+// ChangedKeys returns the keys whose values differ between two configs.
+func ChangedKeys(before, after *Config) []string {
+	var changed []string
+	for _, key := range Keys() {
+		a, _ := before.Get(key)
+		b, _ := after.Get(key)
+		if a != b {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// parseList parses a JSON list of strings or comma separated values.
+func parseList(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		var list []string
+		if err := json.Unmarshal([]byte(value), &list); err != nil {
+			return nil, fmt.Errorf("expected a list of strings, got %s", value)
+		}
+		return list, nil
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// applyEnv applies the CLAUDE_SQUAD_* environment variables over the config.
+func (c *Config) applyEnv() error {
+	for _, key := range Keys() {
+		value, ok := os.LookupEnv(EnvVar(key))
+		if !ok {
+			continue
+		}
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvVar(key), err)
+		}
+	}
+	return nil
+}
+
+// field returns the settable struct field for key.
+func (c *Config) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown key %q", key)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if jsonName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	if v.Kind() == reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%q is an object; use one of its keys, ex. %s.%s", key, key,
+			jsonName(v.Type().Field(0)))
+	}
+	return v, nil
+}
+
+// jsonName returns the JSON key of a struct field.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
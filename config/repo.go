@@ -0,0 +1,308 @@
+package config
+
+import (
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RepoConfigFileName is the name of the per-repository config file, checked into the root of a repository. Its
+// fields are the same as the global config's; the ones it sets override the global values for sessions in that
+// repository.
+const RepoConfigFileName = ".claude-squad.json"
+
+// TrustedRepoKeys are the keys a repository's config may only set once the repository is trusted (see
+// Config.TrustedRepos). They run commands or approve the program's prompts, so without trust a cloned repository
+// could run anything as soon as a session is started in it.
+var TrustedRepoKeys = []string{"auto_yes", "default_program", "worktree_setup.post_setup", "worktree_setup.pre_cleanup"}
+
+// Sources of a config value.
+const (
+	// SourceDefault means the value isn't set in any config file.
+	SourceDefault = "default"
+	// SourceGlobal means the value comes from the global config file.
+	SourceGlobal = "global"
+	// SourceRepo means the value comes from the repository's config file.
+	SourceRepo = "repo"
+	// SourceEnv means the value comes from a CLAUDE_SQUAD_* environment variable.
+	SourceEnv = "env"
+)
+
+// Setting is one value of the effective config and where it came from.
+type Setting struct {
+	// Key is the JSON key of the value. Keys of nested objects are joined with dots (ex. worktree_setup.copy).
+	Key string
+	// Value is the JSON encoded value.
+	Value string
+	// Source is SourceDefault, SourceGlobal, SourceRepo or SourceEnv.
+	Source string
+	// Path is the config file or environment variable the value was read from. It's empty for defaults.
+	Path string
+}
+
+// FindRepoConfig returns the path of the repository config file for dir, or an empty string if there is none. It
+// looks in the root of the git repository containing dir.
+func FindRepoConfig(dir string) string {
+	root := FindRepoRoot(dir)
+	if root == "" {
+		return ""
+	}
+	path := filepath.Join(root, RepoConfigFileName)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// FindRepoRoot returns the absolute path of the root of the git repository containing dir, or an empty string if
+// dir isn't in one.
+func FindRepoRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Trusts returns true if the repository whose root is root is in TrustedRepos.
+func (c *Config) Trusts(root string) bool {
+	for _, trusted := range c.TrustedRepos {
+		if filepath.Clean(trusted) == filepath.Clean(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRepoConfig loads the global config merged with the config of the repository containing dir. Errors are
+// logged and fall back like LoadConfig.
+func LoadRepoConfig(dir string) *Config {
+	cfg := LoadConfig()
+	merged, err := cfg.ForRepo(dir)
+	if err != nil {
+		log.ErrorLog.Printf("%v; ignoring the repository config", err)
+		return cfg
+	}
+	return merged
+}
+
+// ReadRepoConfig is like LoadRepoConfig, but returns errors instead of falling back.
+func ReadRepoConfig(dir string) (*Config, error) {
+	cfg, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ForRepo(dir)
+}
+
+// ForRepo returns a copy of the config with the values set in the config of the repository containing dir applied
+// over it. Environment overrides take precedence over both.
+func (c *Config) ForRepo(dir string) (*Config, error) {
+	path, repo, err := c.readRepoConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return c, nil
+	}
+	merged, err := mergeConfig(c, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply repository config %s: %w", path, err)
+	}
+	if err := merged.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := merged.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config after applying repository config %s: %w", path, err)
+	}
+	return merged, nil
+}
+
+// EffectiveConfig returns the config for sessions in the repository containing dir, along with each of its values
+// and where it came from.
+func EffectiveConfig(dir string) (*Config, []Setting, error) {
+	globalPath, err := ConfigFilePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	globalCfg, err := ReadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := globalCfg.ForRepo(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	global, err := readConfigObject(globalPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	repoPath, repo, err := globalCfg.readRepoConfig(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	effective, err := toConfigObject(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	var settings []Setting
+	for _, key := range sortedKeys(effective) {
+		settings = appendSettings(settings, key, effective[key], global[key], repo[key], globalPath, repoPath)
+	}
+	return cfg, settings, nil
+}
+
+// appendSettings appends the settings for the value at key, descending into nested objects. global and repo are
+// the values at the same key in the config files, or nil if they aren't set there.
+func appendSettings(settings []Setting, key string, value, global, repo any, globalPath, repoPath string) []Setting {
+	if obj, ok := value.(map[string]any); ok {
+		globalObj, _ := global.(map[string]any)
+		repoObj, _ := repo.(map[string]any)
+		for _, k := range sortedKeys(obj) {
+			settings = appendSettings(settings, key+"."+k, obj[k], globalObj[k], repoObj[k], globalPath, repoPath)
+		}
+		return settings
+	}
+
+	setting := Setting{Key: key, Source: SourceDefault}
+	_, inEnv := os.LookupEnv(EnvVar(key))
+	switch {
+	case inEnv:
+		setting.Source, setting.Path = SourceEnv, EnvVar(key)
+	case repo != nil:
+		setting.Source, setting.Path = SourceRepo, repoPath
+	case global != nil:
+		setting.Source, setting.Path = SourceGlobal, globalPath
+	}
+	encoded, _ := json.Marshal(value)
+	setting.Value = string(encoded)
+	return append(settings, setting)
+}
+
+// readRepoConfig reads the config of the repository containing dir as a JSON object, without the keys it isn't
+// allowed to set. It returns an empty path if the repository has no config.
+func (c *Config) readRepoConfig(dir string) (string, map[string]any, error) {
+	path := FindRepoConfig(dir)
+	if path == "" {
+		return "", nil, nil
+	}
+	repo, err := readConfigObject(path)
+	if err != nil {
+		return "", nil, err
+	}
+	delete(repo, "trusted_repos")
+	if c.Trusts(filepath.Dir(path)) {
+		return path, repo, nil
+	}
+	var ignored []string
+	for _, key := range TrustedRepoKeys {
+		if deleteKey(repo, key) {
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		log.WarningLog.Printf("ignoring %s in %s since the repository isn't trusted; run claude-squad config trust "+
+			"in it to allow them", strings.Join(ignored, ", "), path)
+	}
+	return path, repo, nil
+}
+
+// deleteKey deletes the value at key, whose parts are joined with dots, from obj. Returns true if it was set.
+func deleteKey(obj map[string]any, key string) bool {
+	first, rest, nested := strings.Cut(key, ".")
+	value, ok := obj[first]
+	if !ok {
+		return false
+	}
+	if !nested {
+		delete(obj, first)
+		return true
+	}
+	child, ok := value.(map[string]any)
+	return ok && deleteKey(child, rest)
+}
+
+// mergeConfig returns a copy of cfg with the values in override applied over it. Nested objects are merged key by
+// key; any other value, including lists, replaces the original.
+func mergeConfig(cfg *Config, override map[string]any) (*Config, error) {
+	base, err := toConfigObject(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mergeObjects(base, override)
+
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var merged Config
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &merged, nil
+}
+
+func mergeObjects(dst, src map[string]any) {
+	for key, value := range src {
+		srcObj, srcIsObj := value.(map[string]any)
+		dstObj, dstIsObj := dst[key].(map[string]any)
+		if srcIsObj && dstIsObj {
+			mergeObjects(dstObj, srcObj)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// readConfigObject reads a config file as a JSON object. Like the config itself, the file may only contain known
+// keys with values of the right type.
+func readConfigObject(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := decodeConfig(data, &Config{}); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return obj, nil
+}
+
+// toConfigObject converts cfg to a JSON object.
+func toConfigObject(cfg *Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return obj, nil
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoConfig(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	require.NoError(t, SaveConfig(&Config{
+		DefaultProgram:     "claude",
+		DaemonPollInterval: 1000,
+		BranchPrefix:       "me/",
+		WorktreeSetup:      WorktreeSetup{Copy: []string{".env"}},
+	}))
+
+	repo := t.TempDir()
+	subdir := filepath.Join(repo, "pkg", "sub")
+	require.NoError(t, os.MkdirAll(subdir, 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0755))
+
+	t.Run("no repository config", func(t *testing.T) {
+		assert.Empty(t, FindRepoConfig(subdir))
+		cfg := LoadRepoConfig(subdir)
+		assert.Equal(t, "claude", cfg.DefaultProgram)
+		assert.Equal(t, "me/", cfg.BranchPrefix)
+	})
+
+	repoConfig := filepath.Join(repo, RepoConfigFileName)
+	require.NoError(t, os.WriteFile(repoConfig, []byte(`{
+		"default_program": "aider",
+		"auto_yes": true,
+		"base_branch": "main",
+		"worktree_setup": {"post_setup": ["npm ci"]}
+	}`), 0644))
+
+	t.Run("untrusted repository config can't run commands", func(t *testing.T) {
+		assert.Equal(t, repoConfig, FindRepoConfig(subdir))
+		cfg := LoadRepoConfig(subdir)
+		assert.Equal(t, "claude", cfg.DefaultProgram)
+		assert.False(t, cfg.AutoYes)
+		assert.Equal(t, WorktreeSetup{Copy: []string{".env"}}, cfg.WorktreeSetup)
+		assert.Equal(t, "main", cfg.BaseBranch)
+
+		_, settings, err := EffectiveConfig(subdir)
+		require.NoError(t, err)
+		for _, setting := range settings {
+			if setting.Key == "default_program" || setting.Key == "worktree_setup.post_setup" {
+				assert.NotEqual(t, SourceRepo, setting.Source, setting.Key)
+			}
+		}
+
+		// A repository can't trust itself.
+		require.NoError(t, os.WriteFile(repoConfig, []byte(`{"default_program": "aider", "trusted_repos": [".", "`+
+			repo+`"]}`), 0644))
+		assert.Equal(t, "claude", LoadRepoConfig(subdir).DefaultProgram)
+	})
+
+	require.NoError(t, os.WriteFile(repoConfig, []byte(`{
+		"default_program": "aider",
+		"auto_yes": true,
+		"base_branch": "main",
+		"worktree_setup": {"post_setup": ["npm ci"]}
+	}`), 0644))
+	global, err := ReadConfigFile()
+	require.NoError(t, err)
+	global.TrustedRepos = []string{FindRepoRoot(subdir)}
+	require.NoError(t, SaveConfig(global))
+	assert.Equal(t, repo, FindRepoRoot(subdir))
+
+	t.Run("merges repository config over global config", func(t *testing.T) {
+		assert.Equal(t, repoConfig, FindRepoConfig(subdir))
+		cfg := LoadRepoConfig(subdir)
+		assert.Equal(t, "aider", cfg.DefaultProgram)
+		assert.True(t, cfg.AutoYes)
+		assert.Equal(t, "main", cfg.BaseBranch)
+		assert.Equal(t, "me/", cfg.BranchPrefix)
+		assert.Equal(t, WorktreeSetup{Copy: []string{".env"}, PostSetup: []string{"npm ci"}}, cfg.WorktreeSetup)
+
+		// The global config is left untouched.
+		assert.Equal(t, "claude", LoadConfig().DefaultProgram)
+	})
+
+	t.Run("reports where each value came from", func(t *testing.T) {
+		_, settings, err := EffectiveConfig(subdir)
+		require.NoError(t, err)
+
+		sources := make(map[string]Setting)
+		for _, setting := range settings {
+			sources[setting.Key] = setting
+		}
+		globalConfig := filepath.Join(tempHome, ".claude-squad", ConfigFileName)
+		assert.Equal(t, Setting{Key: "default_program", Value: `"aider"`, Source: SourceRepo, Path: repoConfig},
+			sources["default_program"])
+		assert.Equal(t, Setting{Key: "branch_prefix", Value: `"me/"`, Source: SourceGlobal, Path: globalConfig},
+			sources["branch_prefix"])
+		assert.Equal(t, Setting{Key: "worktree_setup.copy", Value: `[".env"]`, Source: SourceGlobal, Path: globalConfig},
+			sources["worktree_setup.copy"])
+		assert.Equal(t, Setting{Key: "worktree_setup.post_setup", Value: `["npm ci"]`, Source: SourceRepo, Path: repoConfig},
+			sources["worktree_setup.post_setup"])
+		assert.Equal(t, SourceGlobal, sources["history_limit"].Source)
+	})
+
+	t.Run("environment overrides repository config", func(t *testing.T) {
+		t.Setenv("CLAUDE_SQUAD_DEFAULT_PROGRAM", "codex")
+		assert.Equal(t, "codex", LoadRepoConfig(subdir).DefaultProgram)
+
+		_, settings, err := EffectiveConfig(subdir)
+		require.NoError(t, err)
+		for _, setting := range settings {
+			if setting.Key == "default_program" {
+				assert.Equal(t, Setting{Key: "default_program", Value: `"codex"`, Source: SourceEnv,
+					Path: "CLAUDE_SQUAD_DEFAULT_PROGRAM"}, setting)
+			}
+		}
+	})
+
+	t.Run("invalid repository config is ignored", func(t *testing.T) {
+		for _, content := range []string{`{"default_program": `, `{"default_programme": "aider"}`, `{"base_branch": "-x"}`} {
+			require.NoError(t, os.WriteFile(repoConfig, []byte(content), 0644))
+			assert.Equal(t, "claude", LoadRepoConfig(subdir).DefaultProgram)
+			_, err := ReadRepoConfig(subdir)
+			assert.ErrorContains(t, err, repoConfig)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"claude-squad/keys"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Validate checks the config values, returning an error that lists every invalid value.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if strings.TrimSpace(c.DefaultProgram) == "" {
+		invalid("default_program", "must not be empty")
+	}
+	if c.DaemonPollInterval <= 0 {
+		invalid("daemon_poll_interval", "must be a positive number of milliseconds, got %d", c.DaemonPollInterval)
+	}
+	if err := validateRefName(c.BranchPrefix); err != nil {
+		invalid("branch_prefix", "%v", err)
+	}
+	if err := validateRefName(c.BaseBranch); err != nil {
+		invalid("base_branch", "%v", err)
+	}
+	switch c.TerminalBackend {
+	case "", "tmux", "headless":
+	default:
+		invalid("terminal_backend", `must be "tmux", "headless" or empty, got %q`, c.TerminalBackend)
+	}
+	if err := validateTemplate(c.WorktreeRoot); err != nil {
+		invalid("worktree_root", "%v", err)
+	}
+	if err := validateTemplate(c.WorktreeName); err != nil {
+		invalid("worktree_name", "%v", err)
+	} else if strings.ContainsAny(c.WorktreeName, `/\`) {
+		invalid("worktree_name", "%q must not contain path separators; use worktree_root for directories",
+			c.WorktreeName)
+	} else if name := strings.Trim(c.WorktreeName, "."); c.WorktreeName != "" && name == "" {
+		invalid("worktree_name", "%q is not a valid directory name", c.WorktreeName)
+	}
+	if c.HistoryLimit < 0 {
+		invalid("history_limit", "must not be negative, got %d", c.HistoryLimit)
+	}
+	if c.IdlePauseMinutes < 0 {
+		invalid("idle_pause_minutes", "must not be negative, got %d", c.IdlePauseMinutes)
+	}
+	if c.MaxRunningSessions < 0 {
+		invalid("max_running_sessions", "must not be negative, got %d", c.MaxRunningSessions)
+	}
+
+	if c.Diff.MaxFileLines < 0 {
+		invalid("diff.max_file_lines", "must not be negative, got %d", c.Diff.MaxFileLines)
+	}
+	if c.Diff.MaxBytes < 0 {
+		invalid("diff.max_bytes", "must not be negative, got %d", c.Diff.MaxBytes)
+	}
+	for _, pattern := range c.Diff.Exclude {
+		if strings.TrimSpace(pattern) == "" {
+			invalid("diff.exclude", "patterns must not be empty")
+		}
+	}
+
+	for key, patterns := range map[string][]string{
+		"worktree_setup.copy":    c.WorktreeSetup.Copy,
+		"worktree_setup.symlink": c.WorktreeSetup.Symlink,
+	} {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil || pattern == "" {
+				invalid(key, "invalid pattern %q", pattern)
+			} else if filepath.IsAbs(pattern) {
+				invalid(key, "pattern %q must be relative to the repository root", pattern)
+			}
+		}
+	}
+	for key, commands := range map[string][]string{
+		"worktree_setup.post_setup":  c.WorktreeSetup.PostSetup,
+		"worktree_setup.pre_cleanup": c.WorktreeSetup.PreCleanup,
+	} {
+		for _, command := range commands {
+			if strings.TrimSpace(command) == "" {
+				invalid(key, "commands must not be empty")
+			}
+		}
+	}
+
+	if err := keys.ValidateBindings(c.KeyBindings); err != nil {
+		invalid("key_bindings", "%v", err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	// Map iteration order is random; keep the output stable.
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// validateRefName checks that name can be used in a git branch name. Empty names are allowed.
+func validateRefName(name string) error {
+	switch {
+	case name == "":
+		return nil
+	case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "/"):
+		return fmt.Errorf("%q must not start with %q", name, name[:1])
+	case strings.Contains(name, ".."), strings.Contains(name, "@{"), strings.Contains(name, "//"):
+		return fmt.Errorf("%q is not a valid git branch name", name)
+	case strings.ContainsAny(name, " \t\n~^:?*[\\"):
+		return fmt.Errorf("%q must not contain whitespace or any of ~^:?*[\\", name)
+	}
+	return nil
+}
+
+// templateVarRegex matches the placeholders of worktree templates.
+var templateVarRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// validateTemplate checks that a worktree template only uses known placeholders.
+func validateTemplate(template string) error {
+	for _, match := range templateVarRegex.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(WorktreeTemplateVars, match[1]) {
+			return fmt.Errorf("unknown placeholder %s in %q, expected one of {%s}", match[0], template,
+				strings.Join(WorktreeTemplateVars, "}, {"))
+		}
+	}
+	return nil
+}
+
+// decodeConfig decodes a config file into cfg, rejecting unknown keys and values of the wrong type. Errors name the
+// offending key or the line and column of a syntax error.
+func decodeConfig(data []byte, cfg *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(cfg)
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &syntaxErr):
+		// Offset is just past the offending character.
+		line, col := position(data, max(syntaxErr.Offset-1, 0))
+		return fmt.Errorf("syntax error at line %d, column %d: %s", line, col, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		line, col := position(data, typeErr.Offset)
+		return fmt.Errorf("%s: expected %s, got %s (line %d, column %d)", typeErr.Field, describeType(typeErr.Type),
+			typeErr.Value, line, col)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown key %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return fmt.Errorf("unexpected end of file")
+	default:
+		return err
+	}
+}
+
+// describeType names a Go type the way it appears in JSON.
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "a list"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Int, reflect.Int64:
+		return "a number"
+	case reflect.Bool:
+		return "true or false"
+	default:
+		return "a " + t.Kind().String()
+	}
+}
+
+// position converts a byte offset in data to a 1-based line and column.
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher checks the config files for changes.
+const DefaultWatchInterval = time.Second
+
+// Watcher reloads the config when the global config file, or the repository config file it covers, changes. Edits
+// that don't validate are rejected: they're reported to OnError and the last valid config stays in effect.
+type Watcher struct {
+	// OnChange is called with the new config and the keys that changed. It's called from the watcher's goroutine.
+	OnChange func(cfg *Config, changed []string)
+	// OnError is called when an edited config can't be loaded. It's called from the watcher's goroutine.
+	OnError func(err error)
+
+	dir      string
+	interval time.Duration
+
+	mu      sync.Mutex
+	current *Config
+	// stamps are the modification times and sizes of the watched files at the last check.
+	stamps map[string]fileStamp
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher for the config of the repository containing dir, or only the global config if dir
+// is empty. current is the config in use, which changes are reported against.
+func NewWatcher(dir string, current *Config, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &Watcher{
+		dir:      dir,
+		interval: interval,
+		current:  current,
+	}
+	w.stamps = w.stat()
+	return w
+}
+
+// Start starts checking the config files in the background until Stop is called.
+func (w *Watcher) Start() {
+	w.stopCh = make(chan struct{})
+	w.doneCh = make(chan struct{})
+	go func() {
+		defer close(w.doneCh)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+}
+
+// Stop stops the watcher started by Start and waits for it to finish.
+func (w *Watcher) Stop() {
+	if w.stopCh == nil {
+		return
+	}
+	close(w.stopCh)
+	<-w.doneCh
+	w.stopCh = nil
+}
+
+// Current returns the last valid config.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// SetCurrent replaces the config changes are reported against, ex. after saving a config, so the save isn't reported
+// as a change.
+func (w *Watcher) SetCurrent(cfg *Config) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = cfg
+	w.stamps = w.stat()
+}
+
+// Check reloads the config if a watched file changed since the last check. It's called periodically after Start,
+// but can also be called directly.
+func (w *Watcher) Check() {
+	w.mu.Lock()
+	stamps := w.stat()
+	if equalStamps(stamps, w.stamps) {
+		w.mu.Unlock()
+		return
+	}
+	w.stamps = stamps
+
+	cfg, err := w.load()
+	if err != nil {
+		w.mu.Unlock()
+		if w.OnError != nil {
+			w.OnError(err)
+		}
+		return
+	}
+	changed := ChangedKeys(w.current, cfg)
+	if len(changed) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.current = cfg
+	w.mu.Unlock()
+
+	if w.OnChange != nil {
+		w.OnChange(cfg, changed)
+	}
+}
+
+func (w *Watcher) load() (*Config, error) {
+	if w.dir == "" {
+		return ReadConfig()
+	}
+	return ReadRepoConfig(w.dir)
+}
+
+// stat returns the stamps of the watched files that exist.
+func (w *Watcher) stat() map[string]fileStamp {
+	var paths []string
+	if path, err := ConfigFilePath(); err == nil {
+		paths = append(paths, path)
+	}
+	if w.dir != "" {
+		if path := FindRepoConfig(w.dir); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+func equalStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath, err := ConfigFilePath()
+	require.NoError(t, err)
+	require.NoError(t, SaveConfig(&Config{DefaultProgram: "claude", DaemonPollInterval: 1000, HistoryLimit: 10}))
+	current, err := ReadConfig()
+	require.NoError(t, err)
+
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0755))
+
+	var changes [][]string
+	var errs []error
+	w := NewWatcher(repo, current, 0)
+	w.OnChange = func(cfg *Config, changed []string) { changes = append(changes, changed) }
+	w.OnError = func(err error) { errs = append(errs, err) }
+
+	w.Check()
+	assert.Empty(t, changes, "nothing changed")
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"default_program": "aider", "daemon_poll_interval": 250,
+		"history_limit": 10, "branch_prefix": "", "auto_yes": false, "terminal_backend": ""}`), 0644))
+	w.Check()
+	require.Equal(t, [][]string{{"default_program", "daemon_poll_interval"}}, changes)
+	assert.Equal(t, 250, w.Current().DaemonPollInterval)
+
+	// Invalid edits are rejected and the last valid config stays in effect.
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"default_program": "aider", "daemon_poll_interval": -1}`), 0644))
+	w.Check()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "daemon_poll_interval")
+	assert.Equal(t, 250, w.Current().DaemonPollInterval)
+	w.Check()
+	assert.Len(t, errs, 1, "the same edit is only reported once")
+
+	// The repository config is watched too.
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"default_program": "aider", "daemon_poll_interval": 250,
+		"history_limit": 10, "branch_prefix": "", "auto_yes": false, "terminal_backend": ""}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, RepoConfigFileName),
+		[]byte(`{"base_branch": "main", "key_bindings": {"kill": ["X"]}}`), 0644))
+	w.Check()
+	require.Len(t, changes, 2)
+	assert.Equal(t, []string{"base_branch", "key_bindings"}, changes[1])
+	assert.Equal(t, "main", w.Current().BaseBranch)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, RepoConfigFileName),
+		[]byte(`{"key_bindings": {"kill": ["n"]}}`), 0644))
+	w.Check()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[1].Error(), `key "n" is bound to both`)
+}
@@ -9,7 +9,9 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -33,7 +35,20 @@ func RunDaemon(cfg *config.Config) error {
 		instance.AutoYes = true
 	}
 
-	pollInterval := time.Duration(cfg.DaemonPollInterval) * time.Millisecond
+	var pollInterval atomic.Int64
+	pollInterval.Store(int64(time.Duration(cfg.DaemonPollInterval) * time.Millisecond))
+
+	// Pick up config changes, ex. a new poll interval, without restarting the daemon.
+	watcher := config.NewWatcher("", cfg, config.DefaultWatchInterval)
+	watcher.OnChange = func(cfg *config.Config, changed []string) {
+		log.InfoLog.Printf("config changed: %s", strings.Join(changed, ", "))
+		pollInterval.Store(int64(time.Duration(cfg.DaemonPollInterval) * time.Millisecond))
+	}
+	watcher.OnError = func(err error) {
+		log.ErrorLog.Printf("ignoring invalid config: %v", err)
+	}
+	watcher.Start()
+	defer watcher.Stop()
 
 	// If we get an error for a session, it's likely that we'll keep getting the error. Log every 30 seconds.
 	everyN := log.NewEvery(60 * time.Second)
@@ -43,7 +58,7 @@ func RunDaemon(cfg *config.Config) error {
 	stopCh := make(chan struct{})
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTimer(pollInterval)
+		ticker := time.NewTimer(time.Duration(pollInterval.Load()))
 		for {
 			for _, instance := range instances {
 				// We only store started instances, but check anyway.
@@ -67,7 +82,7 @@ func RunDaemon(cfg *config.Config) error {
 			}
 
 			<-ticker.C
-			ticker.Reset(pollInterval)
+			ticker.Reset(time.Duration(pollInterval.Load()))
 		}
 	}()
 
@@ -0,0 +1,461 @@
+// Package doctor cross-references the sessions in the state file with the tmux sessions, worktrees and branches
+// they own, across all known repositories, and repairs the inconsistencies it finds one at a time.
+package doctor
+
+import (
+	"claude-squad/cmd"
+	"claude-squad/config"
+	"claude-squad/session"
+	"claude-squad/session/git"
+	"claude-squad/session/tmux"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Kind is the kind of problem an Issue describes.
+type Kind string
+
+const (
+	// KindMissingRepo is a session whose repository is gone.
+	KindMissingRepo Kind = "missing_repo"
+	// KindMissingWorktree is a running session whose worktree is gone.
+	KindMissingWorktree Kind = "missing_worktree"
+	// KindDeadSession is a running session whose tmux session is gone.
+	KindDeadSession Kind = "dead_session"
+	// KindMissingBranch is a paused session whose branch is gone, so it can't be resumed.
+	KindMissingBranch Kind = "missing_branch"
+	// KindOrphanTmux is a claude-squad tmux session that doesn't belong to a running session.
+	KindOrphanTmux Kind = "orphan_tmux"
+	// KindOrphanWorktree is a claude-squad worktree that doesn't belong to any session.
+	KindOrphanWorktree Kind = "orphan_worktree"
+	// KindStaleWorktree is a worktree registered with a repository whose directory is gone.
+	KindStaleWorktree Kind = "stale_worktree"
+	// KindOrphanDir is a directory in the worktree directory that isn't a worktree of any repository.
+	KindOrphanDir Kind = "orphan_dir"
+	// KindOrphanBranch is a branch claude-squad created that doesn't belong to any session or worktree.
+	KindOrphanBranch Kind = "orphan_branch"
+)
+
+// Issue is a problem found by Check.
+type Issue struct {
+	Kind Kind
+	// Session is the title of the session the issue concerns, if any.
+	Session string
+	// Repo is the repository the issue concerns, if any.
+	Repo string
+	// Description describes the problem.
+	Description string
+	// Repair describes what Fix does.
+	Repair string
+	// Destructive is true if the repair may discard work, ex. uncommitted changes or commits only on a branch.
+	Destructive bool
+
+	fix func() error
+}
+
+// Fix repairs the issue.
+func (i *Issue) Fix() error {
+	return i.fix()
+}
+
+// Report is the result of Check.
+type Report struct {
+	// Sessions is the number of sessions in the state file.
+	Sessions int
+	// Repos are the repositories that were checked.
+	Repos []string
+	// Issues are the problems found, sessions first.
+	Issues []*Issue
+}
+
+// Doctor checks the sessions in a state file against the rest of the system. Sessions are read from the state file
+// directly rather than loaded, so checking doesn't restore or start anything. The checks assume claude-squad isn't
+// running: a running instance may be creating or removing resources, and overwrites the state file when it exits.
+type Doctor struct {
+	state   config.InstanceStorage
+	cmdExec cmd.Executor
+}
+
+// New creates a Doctor for the sessions in state.
+func New(state config.InstanceStorage, cmdExec cmd.Executor) *Doctor {
+	return &Doctor{state: state, cmdExec: cmdExec}
+}
+
+// check holds what's known while running the checks.
+type check struct {
+	report *Report
+	// repos are the repositories to check.
+	repos map[string]bool
+	// worktrees are the worktree paths referenced by sessions.
+	worktrees map[string]bool
+	// branches are the branches referenced by sessions, keyed by repository.
+	branches map[string]map[string]bool
+}
+
+// Check runs all checks. The repositories of the sessions and of the worktrees in the default worktree directory
+// are always checked; repos adds more, ex. the repository in the current directory.
+func (d *Doctor) Check(repos ...string) (*Report, error) {
+	instances, err := d.loadInstances()
+	if err != nil {
+		return nil, err
+	}
+	tmuxSessions, err := tmux.ListSessions(d.cmdExec)
+	if err != nil {
+		return nil, err
+	}
+	alive := make(map[string]bool, len(tmuxSessions))
+	for _, name := range tmuxSessions {
+		alive[name] = true
+	}
+
+	c := &check{
+		report:    &Report{Sessions: len(instances)},
+		repos:     make(map[string]bool),
+		worktrees: make(map[string]bool),
+		branches:  make(map[string]map[string]bool),
+	}
+	for _, repo := range repos {
+		if root, err := git.RepoRoot(repo); err == nil {
+			c.repos[canonicalPath(root)] = true
+		}
+	}
+
+	expectedTmux := make(map[string]bool)
+	pausedTmux := make(map[string]string)
+	for _, data := range instances {
+		name := tmux.SessionName(data.Title)
+		if data.Status == session.Paused {
+			pausedTmux[name] = data.Title
+		} else if data.Status != session.Crashed && session.ResolveBackend(data.Backend) == session.BackendTmux {
+			expectedTmux[name] = true
+		}
+		d.checkSession(c, data, alive[name])
+	}
+
+	for _, name := range tmuxSessions {
+		if expectedTmux[name] {
+			continue
+		}
+		description := fmt.Sprintf("tmux session %s doesn't belong to any session", name)
+		if title, ok := pausedTmux[name]; ok {
+			description = fmt.Sprintf("tmux session %s is still running, but session %q is paused", name, title)
+		}
+		c.add(&Issue{
+			Kind:        KindOrphanTmux,
+			Session:     pausedTmux[name],
+			Description: description,
+			Repair:      "kill the tmux session",
+			fix: func() error {
+				return tmux.KillSession(d.cmdExec, name)
+			},
+		})
+	}
+
+	// Worktrees in the default directory point to their repositories, which may not be known otherwise.
+	worktreesDir, err := git.DefaultWorktreeDirectory()
+	if err != nil {
+		return nil, err
+	}
+	worktreesDir = canonicalPath(worktreesDir)
+	entries, err := os.ReadDir(worktreesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read worktree directory: %w", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := canonicalPath(filepath.Join(worktreesDir, entry.Name()))
+		dirs = append(dirs, path)
+		if repo, err := git.WorktreeRepo(path); err == nil && isDir(repo) {
+			c.repos[canonicalPath(repo)] = true
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, repo := range sortedKeys(c.repos) {
+		c.report.Repos = append(c.report.Repos, repo)
+		if err := c.checkRepo(repo, worktreesDir, registered); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range dirs {
+		if registered[path] || c.worktrees[path] {
+			continue
+		}
+		c.add(&Issue{
+			Kind:        KindOrphanDir,
+			Description: fmt.Sprintf("directory %s isn't a worktree of any repository", path),
+			Repair:      "delete the directory",
+			Destructive: true,
+			fix: func() error {
+				return os.RemoveAll(path)
+			},
+		})
+	}
+
+	return c.report, nil
+}
+
+// checkSession checks the resources of one session.
+func (d *Doctor) checkSession(c *check, data session.InstanceData, tmuxAlive bool) {
+	title := data.Title
+	repo := canonicalPath(data.Worktree.RepoPath)
+	worktree := canonicalPath(data.Worktree.WorktreePath)
+	branch := data.Worktree.BranchName
+
+	// .git is a file in linked worktrees and submodules
+	if _, err := os.Stat(filepath.Join(repo, ".git")); err != nil {
+		c.add(&Issue{
+			Kind:        KindMissingRepo,
+			Session:     title,
+			Repo:        repo,
+			Description: fmt.Sprintf("session %q belongs to the repository %s, which is gone", title, repo),
+			Repair:      "remove the session",
+			fix: func() error {
+				return d.removeSession(title)
+			},
+		})
+		return
+	}
+
+	c.repos[repo] = true
+	c.worktrees[worktree] = true
+	if c.branches[repo] == nil {
+		c.branches[repo] = make(map[string]bool)
+	}
+	c.branches[repo][branch] = true
+
+	if data.Status == session.Paused {
+		if !git.BranchExists(repo, branch) {
+			c.add(&Issue{
+				Kind:    KindMissingBranch,
+				Session: title,
+				Repo:    repo,
+				Description: fmt.Sprintf("session %q is paused, but its branch %s is gone, so it can't be resumed",
+					title, branch),
+				Repair: "remove the session",
+				fix: func() error {
+					return d.removeSession(title)
+				},
+			})
+		}
+		return
+	}
+
+	if !isDir(worktree) {
+		c.add(&Issue{
+			Kind:        KindMissingWorktree,
+			Session:     title,
+			Repo:        repo,
+			Description: fmt.Sprintf("session %q is running, but its worktree %s is gone", title, worktree),
+			Repair:      "mark the session paused, so it's recreated from its branch on resume",
+			fix: func() error {
+				return d.markPaused(title)
+			},
+		})
+		return
+	}
+
+	// Headless sessions end with the process that started them, so there's nothing to check them against. Crashed
+	// sessions are known to be dead; they're revived from the app.
+	if data.Status != session.Crashed && session.ResolveBackend(data.Backend) == session.BackendTmux && !tmuxAlive {
+		c.add(&Issue{
+			Kind:    KindDeadSession,
+			Session: title,
+			Repo:    repo,
+			Description: fmt.Sprintf("session %q is marked running, but its tmux session %s is gone", title,
+				tmux.SessionName(title)),
+			Repair: fmt.Sprintf("pause the session: commit its changes to %s and remove the worktree", branch),
+			fix: func() error {
+				return d.pause(data)
+			},
+		})
+	}
+}
+
+// checkRepo checks the worktrees and branches of a repository. It adds the paths of the repository's worktrees to
+// registered.
+func (c *check) checkRepo(repo, worktreesDir string, registered map[string]bool) error {
+	worktrees, err := git.ListWorktrees(repo)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", repo, err)
+	}
+	// Only branches claude-squad created are its business, not the user's own, even if they share the branch prefix.
+	branches, err := git.ListCreatedBranches(repo)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", repo, err)
+	}
+	created := make(map[string]bool, len(branches))
+	for _, branch := range branches {
+		created[branch] = true
+	}
+
+	checkedOut := make(map[string]bool)
+	for _, wt := range worktrees {
+		path := canonicalPath(wt.Path)
+		registered[path] = true
+		checkedOut[wt.Branch] = true
+		if wt.Main || c.worktrees[path] {
+			continue
+		}
+
+		if wt.Prunable {
+			c.add(&Issue{
+				Kind:        KindStaleWorktree,
+				Repo:        repo,
+				Description: fmt.Sprintf("worktree %s is registered with %s, but its directory is gone", path, repo),
+				Repair:      "prune the repository's worktrees",
+				fix: func() error {
+					return git.PruneWorktrees(repo)
+				},
+			})
+			continue
+		}
+
+		// Only worktrees claude-squad would have created are its business.
+		if filepath.Dir(path) != worktreesDir && !created[wt.Branch] {
+			continue
+		}
+		dirty, err := git.IsWorktreeDirty(path)
+		if err != nil {
+			return fmt.Errorf("failed to check worktree %s: %w", path, err)
+		}
+		description := fmt.Sprintf("worktree %s doesn't belong to any session", path)
+		repair := "remove the worktree, keeping its branch"
+		if dirty {
+			description += " and has uncommitted changes"
+			repair = "remove the worktree and its uncommitted changes, keeping its branch"
+		}
+		c.add(&Issue{
+			Kind:        KindOrphanWorktree,
+			Repo:        repo,
+			Description: description,
+			Repair:      repair,
+			Destructive: dirty,
+			fix: func() error {
+				return git.RemoveWorktree(repo, path)
+			},
+		})
+	}
+
+	for _, branch := range branches {
+		if c.branches[repo][branch] || checkedOut[branch] {
+			continue
+		}
+		c.add(&Issue{
+			Kind:        KindOrphanBranch,
+			Repo:        repo,
+			Description: fmt.Sprintf("branch %s in %s doesn't belong to any session", branch, repo),
+			Repair:      "delete the branch",
+			Destructive: true,
+			fix: func() error {
+				return git.DeleteBranch(repo, branch)
+			},
+		})
+	}
+	return nil
+}
+
+func (c *check) add(issue *Issue) {
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+// pause does what pausing the session would have done if its tmux session were alive: it commits the worktree's
+// changes to the session's branch, removes the worktree and marks the session paused.
+func (d *Doctor) pause(data session.InstanceData) error {
+	wt := git.NewGitWorktreeFromStorage(data.Worktree.RepoPath, data.Worktree.WorktreePath,
+		data.Worktree.SessionName, data.Worktree.BranchName, data.Worktree.BaseCommitSHA)
+	commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused by doctor)", data.Title,
+		time.Now().Format(time.RFC822))
+	if err := wt.CommitChanges(commitMsg); err != nil {
+		return err
+	}
+	if err := wt.Remove(); err != nil {
+		return err
+	}
+	if err := wt.Prune(); err != nil {
+		return err
+	}
+	return d.markPaused(data.Title)
+}
+
+// markPaused marks the session paused in the state file.
+func (d *Doctor) markPaused(title string) error {
+	return d.updateInstances(func(instances []session.InstanceData) ([]session.InstanceData, error) {
+		for i := range instances {
+			if instances[i].Title == title {
+				instances[i].Status = session.Paused
+				instances[i].UpdatedAt = time.Now()
+				return instances, nil
+			}
+		}
+		return nil, fmt.Errorf("session not found: %s", title)
+	})
+}
+
+// removeSession removes the session from the state file.
+func (d *Doctor) removeSession(title string) error {
+	return d.updateInstances(func(instances []session.InstanceData) ([]session.InstanceData, error) {
+		for i := range instances {
+			if instances[i].Title == title {
+				return append(instances[:i], instances[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("session not found: %s", title)
+	})
+}
+
+// updateInstances reads the sessions from the state file, updates them and writes them back.
+func (d *Doctor) updateInstances(update func([]session.InstanceData) ([]session.InstanceData, error)) error {
+	instances, err := d.loadInstances()
+	if err != nil {
+		return err
+	}
+	instances, err = update(instances)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(instances)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instances: %w", err)
+	}
+	return d.state.SaveInstances(data)
+}
+
+func (d *Doctor) loadInstances() ([]session.InstanceData, error) {
+	var instances []session.InstanceData
+	if err := json.Unmarshal(d.state.GetInstances(), &instances); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
+	}
+	return instances, nil
+}
+
+// canonicalPath resolves symlinks in path where possible, so paths reported by git and stored in the state file
+// compare equal.
+func canonicalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
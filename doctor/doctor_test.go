@@ -0,0 +1,205 @@
+package doctor
+
+import (
+	"claude-squad/cmd/cmd_test"
+	"claude-squad/log"
+	"claude-squad/session"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+
+	os.Exit(m.Run())
+}
+
+// memoryState keeps instances in memory instead of the state file.
+type memoryState struct {
+	instances json.RawMessage
+}
+
+func (s *memoryState) SaveInstances(instancesJSON json.RawMessage) error {
+	s.instances = instancesJSON
+	return nil
+}
+
+func (s *memoryState) GetInstances() json.RawMessage {
+	return s.instances
+}
+
+func (s *memoryState) DeleteAllInstances() error {
+	s.instances = json.RawMessage("[]")
+	return nil
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestDoctor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	runGit(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+	// Branches claude-squad created are marked. The others are the user's, whatever their names.
+	createBranch := func(branch string) {
+		runGit(t, repo, "branch", branch)
+		runGit(t, repo, "config", "branch."+branch+".claudesquad", "true")
+	}
+	runGit(t, repo, "branch", "feature")
+	runGit(t, repo, "branch", "cs/mine")
+	createBranch("cs/leftover")
+	createBranch("cs/paused")
+
+	worktrees := t.TempDir()
+	addWorktree := func(name string) string {
+		path := filepath.Join(worktrees, name)
+		runGit(t, repo, "worktree", "add", "-q", "-b", "cs/"+name, path)
+		runGit(t, repo, "config", "branch.cs/"+name+".claudesquad", "true")
+		return path
+	}
+	// The user's own worktree is left alone.
+	runGit(t, repo, "worktree", "add", "-q", "-b", "cs/mine-too", filepath.Join(worktrees, "mine"))
+	livePath := addWorktree("live")
+	deadPath := addWorktree("dead")
+	require.NoError(t, os.WriteFile(filepath.Join(deadPath, "work.txt"), []byte("work"), 0644))
+	addWorktree("orphan")
+	stalePath := addWorktree("stale")
+	require.NoError(t, os.RemoveAll(stalePath))
+	junkPath := filepath.Join(home, ".claude-squad", "worktrees", "junk")
+	require.NoError(t, os.MkdirAll(junkPath, 0755))
+
+	newInstance := func(title string, status session.Status, repoPath, worktreePath string) session.InstanceData {
+		return session.InstanceData{
+			Title:   title,
+			Status:  status,
+			Backend: session.BackendTmux,
+			Worktree: session.GitWorktreeData{
+				RepoPath:     repoPath,
+				WorktreePath: worktreePath,
+				SessionName:  title,
+				BranchName:   "cs/" + title,
+			},
+		}
+	}
+	instances := []session.InstanceData{
+		newInstance("live", session.Running, repo, livePath),
+		newInstance("dead", session.Running, repo, deadPath),
+		newInstance("gone", session.Ready, repo, filepath.Join(worktrees, "gone")),
+		newInstance("paused", session.Paused, repo, filepath.Join(worktrees, "paused")),
+		newInstance("nobranch", session.Paused, repo, filepath.Join(worktrees, "nobranch")),
+		newInstance("norepo", session.Running, filepath.Join(t.TempDir(), "missing"), filepath.Join(worktrees, "norepo")),
+	}
+	createBranch("cs/gone")
+	data, err := json.Marshal(instances)
+	require.NoError(t, err)
+	state := &memoryState{instances: data}
+
+	tmuxSessions := []string{"claudesquad_live", "claudesquad_paused", "claudesquad_stray", "other"}
+	cmdExec := cmd_test.MockCmdExec{
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte(strings.Join(tmuxSessions, "\n") + "\n"), nil
+		},
+		RunFunc: func(cmd *exec.Cmd) error {
+			if cmd.Args[1] == "kill-session" {
+				name := strings.TrimPrefix(cmd.Args[2], "-t=")
+				for i, session := range tmuxSessions {
+					if session == name {
+						tmuxSessions = append(tmuxSessions[:i], tmuxSessions[i+1:]...)
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	d := New(state, cmdExec)
+	report, err := d.Check()
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Sessions)
+	assert.Equal(t, []string{repo}, report.Repos)
+
+	type found struct {
+		Kind        Kind
+		Session     string
+		Destructive bool
+	}
+	var issues []found
+	for _, issue := range report.Issues {
+		issues = append(issues, found{issue.Kind, issue.Session, issue.Destructive})
+	}
+	assert.Equal(t, []found{
+		{KindDeadSession, "dead", false},
+		{KindMissingWorktree, "gone", false},
+		{KindMissingBranch, "nobranch", false},
+		{KindMissingRepo, "norepo", false},
+		{KindOrphanTmux, "paused", false},
+		{KindOrphanTmux, "", false},
+		{KindOrphanWorktree, "", false},
+		{KindStaleWorktree, "", false},
+		{KindOrphanBranch, "", true},
+		{KindOrphanDir, "", true},
+	}, issues)
+
+	for _, issue := range report.Issues {
+		require.NoError(t, issue.Fix(), issue.Description)
+	}
+
+	t.Run("repairs", func(t *testing.T) {
+		var after []session.InstanceData
+		require.NoError(t, json.Unmarshal(state.GetInstances(), &after))
+		statuses := make(map[string]session.Status)
+		for _, data := range after {
+			statuses[data.Title] = data.Status
+		}
+		assert.Equal(t, map[string]session.Status{
+			"live":   session.Running,
+			"dead":   session.Paused,
+			"gone":   session.Paused,
+			"paused": session.Paused,
+		}, statuses)
+
+		// The dead session's work was committed to its branch before its worktree was removed.
+		assert.NoDirExists(t, deadPath)
+		output, err := exec.Command("git", "-C", repo, "show", "--name-only", "--format=", "cs/dead").Output()
+		require.NoError(t, err)
+		assert.Equal(t, "work.txt", strings.TrimSpace(string(output)))
+
+		assert.Equal(t, []string{"claudesquad_live", "other"}, tmuxSessions)
+		assert.NoDirExists(t, junkPath)
+	})
+
+	t.Run("check after repairs", func(t *testing.T) {
+		report, err := d.Check()
+		require.NoError(t, err)
+		// Removing the orphaned and stale worktrees kept their branches, which are now orphans themselves.
+		var descriptions []string
+		for _, issue := range report.Issues {
+			assert.Equal(t, KindOrphanBranch, issue.Kind)
+			descriptions = append(descriptions, issue.Description)
+		}
+		require.Len(t, descriptions, 2)
+		assert.Contains(t, descriptions[0], "cs/orphan")
+		assert.Contains(t, descriptions[1], "cs/stale")
+	})
+}
@@ -1,6 +1,10 @@
 package keys
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 )
 
@@ -24,13 +28,21 @@ const (
 	KeyResume
 	KeyPrompt // New key for entering a prompt
 	KeyHelp   // Key for showing help screen
+	KeyHistory
+	KeyShell     // Key for attaching to the session's companion shell
+	KeyQueue     // Key for showing the session's prompt queue
+	KeyFanOut    // Key for running one prompt across several programs
+	KeyCompare   // Key for comparing the sessions of a fan-out
+	KeyFiles     // Key for navigating the files and hunks of the diff
+	KeySplit     // Key for switching the diff between unified and side by side
+	KeyConflicts // Key for showing the sessions that changed the same files
 
 	// Diff keybindings
 	KeyShiftUp
 	KeyShiftDown
 )
 
-// GlobalKeyStringsMap is a global, immutable map string to keybinding.
+// GlobalKeyStringsMap is a global map string to keybinding. It's only replaced by Rebind.
 var GlobalKeyStringsMap = map[string]KeyName{
 	"up":         KeyUp,
 	"k":          KeyUp,
@@ -49,9 +61,17 @@ var GlobalKeyStringsMap = map[string]KeyName{
 	"r":          KeyResume,
 	"p":          KeySubmit,
 	"?":          KeyHelp,
+	"h":          KeyHistory,
+	"s":          KeyShell,
+	"Q":          KeyQueue,
+	"F":          KeyFanOut,
+	"C":          KeyCompare,
+	"f":          KeyFiles,
+	"v":          KeySplit,
+	"M":          KeyConflicts,
 }
 
-// GlobalkeyBindings is a global, immutable map of KeyName tot keybinding.
+// GlobalkeyBindings is a global map of KeyName tot keybinding. It's only replaced by Rebind.
 var GlobalkeyBindings = map[KeyName]key.Binding{
 	KeyUp: key.NewBinding(
 		key.WithKeys("up", "k"),
@@ -109,6 +129,38 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("r"),
 		key.WithHelp("r", "resume"),
 	),
+	KeyHistory: key.NewBinding(
+		key.WithKeys("h"),
+		key.WithHelp("h", "history"),
+	),
+	KeyShell: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "shell"),
+	),
+	KeyQueue: key.NewBinding(
+		key.WithKeys("Q"),
+		key.WithHelp("Q", "queue"),
+	),
+	KeyFanOut: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "fan out"),
+	),
+	KeyCompare: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "compare"),
+	),
+	KeyFiles: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "files"),
+	),
+	KeySplit: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "split"),
+	),
+	KeyConflicts: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "conflicts"),
+	),
 
 	// -- Special keybindings --
 
@@ -117,3 +169,106 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithHelp("enter", "submit name"),
 	),
 }
+
+// ActionNames maps the action names used in the key_bindings config to the keys they rebind.
+var ActionNames = map[string]KeyName{
+	"up":          KeyUp,
+	"down":        KeyDown,
+	"scroll_up":   KeyShiftUp,
+	"scroll_down": KeyShiftDown,
+	"open":        KeyEnter,
+	"new":         KeyNew,
+	"prompt":      KeyPrompt,
+	"kill":        KeyKill,
+	"quit":        KeyQuit,
+	"push":        KeySubmit,
+	"checkout":    KeyCheckout,
+	"resume":      KeyResume,
+	"tab":         KeyTab,
+	"help":        KeyHelp,
+	"history":     KeyHistory,
+	"shell":       KeyShell,
+	"queue":       KeyQueue,
+	"fan_out":     KeyFanOut,
+	"compare":     KeyCompare,
+	"files":       KeyFiles,
+	"split":       KeySplit,
+	"conflicts":   KeyConflicts,
+}
+
+// defaultKeyBindings are the built in bindings that Rebind starts from.
+var defaultKeyBindings = GlobalkeyBindings
+
+// Rebind replaces the keys of the actions in bindings, ex. {"kill": ["X"]}. Actions that aren't in bindings get
+// their default keys back. If an action is unknown, has no keys or shares a key with another action, nothing is
+// changed and an error is returned. The maps aren't guarded, so call it from the goroutine that reads them.
+func Rebind(bindings map[string][]string) error {
+	keyStrings, keyBindings, err := resolveBindings(bindings)
+	if err != nil {
+		return err
+	}
+	GlobalKeyStringsMap = keyStrings
+	GlobalkeyBindings = keyBindings
+	return nil
+}
+
+// ValidateBindings checks bindings the same way Rebind does, without applying them.
+func ValidateBindings(bindings map[string][]string) error {
+	_, _, err := resolveBindings(bindings)
+	return err
+}
+
+func resolveBindings(bindings map[string][]string) (map[string]KeyName, map[KeyName]key.Binding, error) {
+	overrides := make(map[KeyName][]string)
+	for _, action := range sortedActions(bindings) {
+		name, ok := ActionNames[action]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown action %q, expected one of %s", action,
+				strings.Join(sortedActions(ActionNames), ", "))
+		}
+		if len(bindings[action]) == 0 {
+			return nil, nil, fmt.Errorf("action %q has no keys", action)
+		}
+		overrides[name] = bindings[action]
+	}
+
+	keyStrings := make(map[string]KeyName)
+	keyBindings := make(map[KeyName]key.Binding, len(defaultKeyBindings))
+	for name, binding := range defaultKeyBindings {
+		keyBindings[name] = binding
+	}
+	// Go through the actions in a fixed order so conflicts are always reported the same way.
+	for _, action := range sortedActions(ActionNames) {
+		name := ActionNames[action]
+		binding := defaultKeyBindings[name]
+		if keys, ok := overrides[name]; ok {
+			binding = key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(keys, "/"), binding.Help().Desc))
+			keyBindings[name] = binding
+		}
+		for _, k := range binding.Keys() {
+			if other, ok := keyStrings[k]; ok {
+				return nil, nil, fmt.Errorf("key %q is bound to both %q and %q", k, actionName(other), action)
+			}
+			keyStrings[k] = name
+		}
+	}
+	return keyStrings, keyBindings, nil
+}
+
+func actionName(name KeyName) string {
+	for action, n := range ActionNames {
+		if n == name {
+			return action
+		}
+	}
+	return fmt.Sprint(int(name))
+}
+
+func sortedActions[V any](m map[string]V) []string {
+	actions := make([]string, 0, len(m))
+	for action := range m {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"bufio"
 	"claude-squad/app"
 	cmd2 "claude-squad/cmd"
 	"claude-squad/config"
 	"claude-squad/daemon"
+	"claude-squad/doctor"
 	"claude-squad/log"
+	"claude-squad/pkg/engine"
 	"claude-squad/session"
 	"claude-squad/session/git"
+	"claude-squad/session/recording"
 	"claude-squad/session/tmux"
+	"claude-squad/tasks"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -22,7 +34,24 @@ var (
 	programFlag string
 	autoYesFlag bool
 	daemonFlag  bool
-	rootCmd     = &cobra.Command{
+
+	replaySpeed     float64
+	replayIdleLimit time.Duration
+
+	recordWidth  int
+	recordHeight int
+	recordTitle  string
+
+	effectiveFlag bool
+
+	doctorFixFlag bool
+	doctorYesFlag bool
+
+	runConcurrency int
+	runIdleAfter   time.Duration
+	runTimeout     time.Duration
+
+	rootCmd = &cobra.Command{
 		Use:   "claude-squad",
 		Short: "Claude Squad - Manage multiple AI agents like Claude Code, Aider, Codex, and Amp.",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -31,8 +60,12 @@ var (
 			defer log.Close()
 
 			if daemonFlag {
-				cfg := config.LoadConfig()
-				err := daemon.RunDaemon(cfg)
+				cfg, err := config.ReadConfig()
+				if err != nil {
+					log.ErrorLog.Printf("failed to load config: %v", err)
+					return err
+				}
+				err = daemon.RunDaemon(cfg)
 				log.ErrorLog.Printf("failed to start daemon %v", err)
 				return err
 			}
@@ -47,7 +80,10 @@ var (
 				return fmt.Errorf("error: claude-squad must be run from within a git repository")
 			}
 
-			cfg := config.LoadConfig()
+			cfg, err := config.ReadRepoConfig(currentDir)
+			if err != nil {
+				return fmt.Errorf("%w\nfix the config or run 'claude-squad config edit'", err)
+			}
 
 			// Program flag overrides config
 			program := cfg.DefaultProgram
@@ -83,6 +119,16 @@ var (
 			defer log.Close()
 
 			state := config.LoadState()
+			// Worktrees can live outside the default worktree directory, so find them before forgetting the instances.
+			var worktrees []string
+			var instances []session.InstanceData
+			if err := json.Unmarshal(state.GetInstances(), &instances); err != nil {
+				log.ErrorLog.Printf("failed to read instances: %v", err)
+			}
+			for _, data := range instances {
+				worktrees = append(worktrees, data.Worktree.WorktreePath)
+			}
+
 			storage, err := session.NewStorage(state)
 			if err != nil {
 				return fmt.Errorf("failed to initialize storage: %w", err)
@@ -97,7 +143,7 @@ var (
 			}
 			fmt.Println("Tmux sessions have been cleaned up")
 
-			if err := git.CleanupWorktrees(); err != nil {
+			if err := git.CleanupWorktrees(worktrees...); err != nil {
 				return fmt.Errorf("failed to cleanup worktrees: %w", err)
 			}
 			fmt.Println("Worktrees have been cleaned up")
@@ -112,6 +158,104 @@ var (
 		},
 	}
 
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Find and repair orphaned sessions, worktrees, branches and tmux sessions",
+		Long: `Cross-reference the stored sessions with tmux sessions, worktrees and branches in all known repositories,
+and report orphans and inconsistencies, ex. a session marked running whose tmux session is gone.
+
+With --fix, each repair is confirmed before it's applied. Run it while claude-squad isn't running.`,
+		// Errors are about the state of the system, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			report, err := doctor.New(config.LoadState(), cmd2.MakeExecutor()).Check(currentDir)
+			if err != nil {
+				return err
+			}
+			return runDoctor(report)
+		},
+	}
+
+	runCmd = &cobra.Command{
+		Use:   "run <tasks.yaml>",
+		Short: "Create a session for each task in a YAML or JSON manifest and wait for them to finish",
+		Long: `Create a session for each task in the manifest, a few at a time, and send it the task's prompt followed by its
+follow-up prompts, each once the program is ready for input again. A task is finished once its session has sat
+idle with nothing left to send. Then a summary of every task is printed. The sessions are kept, so their work can
+be reviewed in the TUI.
+
+  concurrency: 3
+  tasks:
+    - title: typed-config
+      repo: ~/src/app
+      base: main
+      program: claude
+      prompt: Replace the config map with a typed struct
+      follow_ups:
+        - Now add tests
+        - Now run the linter and fix the issues
+      auto_yes: true`,
+		Args: cobra.ExactArgs(1),
+		// Errors are about the tasks, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			if runIdleAfter <= 0 {
+				return fmt.Errorf("--idle must be positive, got %s", runIdleAfter)
+			}
+			manifest, err := tasks.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg := config.LoadConfig()
+			if session.ResolveBackend(cfg.TerminalBackend) == session.BackendHeadless {
+				fmt.Println("Warning: tmux is not in use, so the sessions stop when this command exits")
+			}
+			eng, err := engine.New(cfg, config.LoadState())
+			if err != nil {
+				return err
+			}
+			if err := eng.Start(cmd.Context()); err != nil {
+				return err
+			}
+			defer eng.Close()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			runner := tasks.NewRunner(eng)
+			runner.Concurrency = runConcurrency
+			runner.IdleAfter = runIdleAfter
+			runner.Timeout = runTimeout
+			runner.Progress = os.Stdout
+			results := runner.Run(ctx, manifest)
+
+			fmt.Println()
+			if err := tasks.WriteSummary(os.Stdout, results); err != nil {
+				return err
+			}
+			failed := 0
+			for _, result := range results {
+				if !result.OK() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d tasks did not finish", failed, len(results))
+			}
+			return nil
+		},
+	}
+
 	debugCmd = &cobra.Command{
 		Use:   "debug",
 		Short: "Print debug information like config paths",
@@ -130,6 +274,204 @@ var (
 		},
 	}
 
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	configShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print the global config, or with --effective the config used in the current repository",
+		Args:  cobra.NoArgs,
+		// Errors are about the config, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			if !effectiveFlag {
+				cfg, err := config.ReadConfig()
+				if err != nil {
+					return err
+				}
+				configJson, err := json.MarshalIndent(cfg, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal config: %w", err)
+				}
+				fmt.Println(string(configJson))
+				return nil
+			}
+
+			_, settings, err := config.EffectiveConfig(".")
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+			for _, setting := range settings {
+				source := setting.Source
+				if setting.Path != "" {
+					source = fmt.Sprintf("%s (%s)", setting.Source, setting.Path)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, setting.Value, source)
+			}
+			return w.Flush()
+		},
+	}
+
+	configGetCmd = &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config value as used in the current repository",
+		Long: "Print a config value as used in the current repository. Keys of nested values are joined with dots, " +
+			"ex. worktree_setup.copy.",
+		Args: cobra.ExactArgs(1),
+		// Errors are about the config, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.ReadRepoConfig(".")
+			if err != nil {
+				return err
+			}
+			value, err := cfg.Get(args[0])
+			if err != nil {
+				return err
+			}
+			// Print strings without quotes so they're easy to use in scripts.
+			var str string
+			if json.Unmarshal([]byte(value), &str) == nil {
+				value = str
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+
+	configSetCmd = &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value in the global config",
+		Long: "Set a value in the global config. Lists are given as JSON or comma separated values, " +
+			"ex. claude-squad config set worktree_setup.copy .env,.env.local. The config is validated before it's saved.",
+		Args: cobra.ExactArgs(2),
+		// Errors are about the config, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.ReadConfigFile()
+			if err != nil {
+				return err
+			}
+			if err := cfg.Set(args[0], args[1]); err != nil {
+				return err
+			}
+			if err := config.SaveConfig(cfg); err != nil {
+				return err
+			}
+			if envVar := config.EnvVar(args[0]); os.Getenv(envVar) != "" {
+				fmt.Printf("note: %s is set and overrides this value\n", envVar)
+			}
+			return nil
+		},
+	}
+
+	configTrustCmd = &cobra.Command{
+		Use:   "trust [dir]",
+		Short: "Let the config of the repository containing dir run commands",
+		Long: "Let the config of the repository containing dir (the current directory by default) set " +
+			strings.Join(config.TrustedRepoKeys, ", ") + ". A repository's config can't set them until it's trusted, " +
+			"since they run commands or approve the program's prompts.",
+		Args: cobra.MaximumNArgs(1),
+		// Errors are about the config, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			root := config.FindRepoRoot(dir)
+			if root == "" {
+				return fmt.Errorf("%s is not in a git repository", dir)
+			}
+			cfg, err := config.ReadConfigFile()
+			if err != nil {
+				return err
+			}
+			if cfg.Trusts(root) {
+				fmt.Printf("%s is already trusted\n", root)
+				return nil
+			}
+			cfg.TrustedRepos = append(cfg.TrustedRepos, root)
+			if err := config.SaveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Printf("Trusted %s\n", root)
+			return nil
+		},
+	}
+
+	configEditCmd = &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the global config in $EDITOR",
+		Long:  "Edit the global config in $EDITOR. The config is only saved if it's valid.",
+		Args:  cobra.NoArgs,
+		// Errors are about the config, not the command line.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			return editConfig()
+		},
+	}
+
+	replayCmd = &cobra.Command{
+		Use:   "replay <session>",
+		Short: "Play back the terminal recording of a session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := recording.Latest(args[0])
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no recording found for session %s", args[0])
+			} else if err != nil {
+				return fmt.Errorf("failed to find recording: %w", err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			// Clear the screen so the recording starts from a blank terminal.
+			fmt.Print("\x1b[H\x1b[2J")
+			return recording.Play(f, os.Stdout, replaySpeed, replayIdleLimit)
+		},
+	}
+
+	// recordCmd is run by tmux pipe-pane to record a pane's output. It's only for internal use.
+	recordCmd = &cobra.Command{
+		Use:    "record <path>",
+		Short:  "Record stdin to an asciicast file",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recorder, err := recording.Open(args[0], recordWidth, recordHeight, recordTitle)
+			if err != nil {
+				return err
+			}
+			defer recorder.Close()
+
+			_, err = io.Copy(recorder, os.Stdin)
+			return err
+		},
+	}
+
 	versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Print the version number of claude-squad",
@@ -154,9 +496,153 @@ func init() {
 		panic(err)
 	}
 
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "s", 1, "Playback speed multiplier (e.g. 2 plays twice as fast)")
+	replayCmd.Flags().DurationVar(&replayIdleLimit, "idle-limit", 2*time.Second,
+		"Shorten pauses longer than this (0 keeps the original timing)")
+
+	recordCmd.Flags().IntVar(&recordWidth, "width", 80, "Terminal width")
+	recordCmd.Flags().IntVar(&recordHeight, "height", 24, "Terminal height")
+	recordCmd.Flags().StringVar(&recordTitle, "title", "", "Recording title")
+
+	configShowCmd.Flags().BoolVar(&effectiveFlag, "effective", false,
+		"Print the global config merged with the current repository's "+config.RepoConfigFileName+
+			" and where each value came from")
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configTrustCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorFixFlag, "fix", false, "Offer to repair each issue")
+	doctorCmd.Flags().BoolVar(&doctorYesFlag, "yes", false,
+		"With --fix, apply the repairs without asking, except those that may discard work")
+
+	runCmd.Flags().IntVarP(&runConcurrency, "concurrency", "c", 0, fmt.Sprintf(
+		"Number of tasks to work on at once (default: the manifest's concurrency, or %d)", tasks.DefaultConcurrency))
+	runCmd.Flags().DurationVar(&runIdleAfter, "idle", tasks.DefaultIdleAfter,
+		"How long a session has to sit idle with nothing left to send to count as finished")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Give up on a task after this long (0 means no limit)")
+
 	rootCmd.AddCommand(debugCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(recordCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(runCmd)
+}
+
+// editConfig opens a copy of the config file in the user's editor until it's valid or the user gives up, then
+// replaces the config file with it.
+func editConfig() error {
+	// Make sure the config file exists and is up to date before editing it.
+	if _, err := config.ReadConfigFile(); err != nil {
+		fmt.Println(err)
+	}
+	path, err := config.ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "config-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		// Run the editor through the shell so $EDITOR can contain arguments, ex. "code --wait".
+		editorCmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", tmp.Name())
+		editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := editorCmd.Run(); err != nil {
+			return fmt.Errorf("failed to run editor %q: %w", editor, err)
+		}
+
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read edited config: %w", err)
+		}
+		if _, err := config.ParseConfig(edited); err != nil {
+			fmt.Printf("invalid config: %v\nedit again? [Y/n] ", err)
+			answer, _ := stdin.ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "n" || answer == "no" {
+				return fmt.Errorf("config not saved")
+			}
+			continue
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("saved %s\n", path)
+		return nil
+	}
+}
+
+// runDoctor prints the issues in report and, with --fix, repairs the ones the user confirms.
+func runDoctor(report *doctor.Report) error {
+	fmt.Printf("checked %d sessions in %d repositories\n", report.Sessions, len(report.Repos))
+	if len(report.Issues) == 0 {
+		fmt.Println("no problems found")
+		return nil
+	}
+	for i, issue := range report.Issues {
+		fmt.Printf("%d. %s\n   repair: %s\n", i+1, issue.Description, issue.Repair)
+	}
+	if !doctorFixFlag {
+		fmt.Println("run 'claude-squad doctor --fix' to repair them")
+		return nil
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	var failed, skipped int
+	for i, issue := range report.Issues {
+		if doctorYesFlag && issue.Destructive {
+			fmt.Printf("%d. skipped, since it may discard work: %s\n", i+1, issue.Repair)
+			skipped++
+			continue
+		}
+		if !doctorYesFlag {
+			fmt.Printf("%d. %s? [y/N] ", i+1, issue.Repair)
+			answer, _ := stdin.ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+				skipped++
+				continue
+			}
+		}
+		if err := issue.Fix(); err != nil {
+			fmt.Printf("%d. failed: %v\n", i+1, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%d. fixed\n", i+1)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to repair %d problems", failed)
+	}
+	if skipped > 0 {
+		fmt.Printf("%d problems left\n", skipped)
+	}
+	return nil
 }
 
 func main() {
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Engine is the main facade for the session management SDK.
@@ -19,6 +20,9 @@ type Engine struct {
 	eventBus *EventBus
 	cfg      *config.Config
 	started  bool
+
+	// watcher reloads the config, if WatchConfig was called
+	watcher *config.Watcher
 }
 
 // New creates a new Engine instance.
@@ -76,6 +80,15 @@ func (e *Engine) Start(ctx context.Context) error {
 // Close shuts down the engine and cleans up resources.
 // This should be called when the application exits.
 func (e *Engine) Close() error {
+	// Stop the config watcher first, its callback takes the lock
+	e.mu.Lock()
+	watcher := e.watcher
+	e.watcher = nil
+	e.mu.Unlock()
+	if watcher != nil {
+		watcher.Stop()
+	}
+	
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	
@@ -108,6 +121,134 @@ func (e *Engine) StartSession(ctx context.Context, opts SessionOpts) (string, er
 	return e.mgr.Create(opts)
 }
 
+// Diff returns the session's changes against the commit its branch started from, parsed per file. The engine
+// refreshes it every half second while the session runs and publishes a diff event when it changes; a paused
+// session keeps the diff it had when it was paused.
+func (e *Engine) Diff(sessionID string) (*DiffStats, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Diff(sessionID)
+}
+
+// DiffWithOptions computes the session's diff with opts instead of the diff options in the config, ex. to show it
+// ignoring whitespace on request. It doesn't change the diff Diff returns or diff events publish. Paused sessions have
+// no worktree to diff.
+func (e *Engine) DiffWithOptions(sessionID string, opts DiffOptions) (*DiffStats, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.DiffWithOptions(sessionID, opts)
+}
+
+// FanOut starts a session for each of programs, to compare how they handle the same prompt. The sessions share
+// opts.Title as their Group and are titled after it and their program, ex. "fix-login-aider". They all start from
+// the commit opts.BaseRef resolves to when FanOut is called, and opts.Prompt is queued for each, so every program
+// gets it once it's ready for input. If any session fails to start, the ones already started are killed.
+// Returns the session IDs in the order of programs.
+func (e *Engine) FanOut(ctx context.Context, opts SessionOpts, programs []string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.FanOut(opts, programs)
+}
+
+// Compare returns the sessions of a fan-out group, in the order they were started, with their diff stats, the
+// files they changed and how long each program took to become ready after the prompt.
+func (e *Engine) Compare(group string) ([]Comparison, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Compare(group)
+}
+
+// Keep settles a fan-out on one session: it kills the other sessions of its group and takes it out of the group.
+// If some sibling can't be killed, the group is left as it is and the error says which.
+func (e *Engine) Keep(sessionID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Keep(sessionID)
+}
+
+// Conflicts returns what a session changed that other sessions of the same repository changed too, from their last
+// diffs: the files both changed, and the lines of each that both changed or changed right next to each other, which
+// are likely to conflict when the sessions are merged. Sessions without common files are left out.
+func (e *Engine) Conflicts(sessionID string) ([]Conflict, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Conflicts(sessionID)
+}
+
+// SimulateMerge merges the changes of two sessions of the same repository in memory with git merge-tree, and
+// reports the files that conflict. Uncommitted changes are included, and no worktree or branch is touched. It needs
+// git 2.38 or later.
+func (e *Engine) SimulateMerge(sessionA, sessionB string) (*MergeResult, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.SimulateMerge(sessionA, sessionB)
+}
+
+// ExportPatch returns a session's changes against its base commit, uncommitted changes and untracked files included.
+// PatchFormatDiff returns one unified diff that git apply takes; PatchFormatMail returns git format-patch output that
+// git am takes, with a mail per commit on the session's branch and one more for its uncommitted changes. The patch is
+// empty if the session changed nothing.
+func (e *Engine) ExportPatch(sessionID string, format PatchFormat) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return "", fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.ExportPatch(sessionID, format)
+}
+
+// ApplyPatch brings the changes a source session made to files into the worktree of a target session of the same
+// repository, on top of the target's own changes, ex. to take one program's helper function into another's session.
+// files are relative to the repository root; empty takes all of the source's changes. Either every file applies, or
+// the target is left as it was.
+func (e *Engine) ApplyPatch(targetID, sourceID string, files []string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.ApplyPatch(targetID, sourceID, files)
+}
+
 // Pause pauses the specified session.
 // This stops the tmux session and removes the worktree while preserving the branch.
 func (e *Engine) Pause(sessionID string) error {
@@ -134,6 +275,137 @@ func (e *Engine) Resume(sessionID string) error {
 	return e.mgr.Resume(sessionID)
 }
 
+// Revive restarts a crashed session (StatusCrashed) in its existing worktree.
+// This starts a fresh terminal session running the program and queues the last prompt again, if there was one,
+// ahead of the other queued prompts so it's sent first once the program is ready for input.
+func (e *Engine) Revive(sessionID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Revive(sessionID)
+}
+
+// Enqueue adds a prompt to the end of the session's queue. The engine sends queued prompts one at a time, each time
+// the session becomes ready for input, and publishes a queue event whenever the queue changes.
+func (e *Engine) Enqueue(sessionID, prompt string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Enqueue(sessionID, prompt)
+}
+
+// Queue returns the prompts waiting to be sent to the session, next one first.
+func (e *Engine) Queue(sessionID string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Queue(sessionID)
+}
+
+// MoveQueued moves the queued prompt at index from to index to. Indexes are positions in Queue, starting at 0, and
+// prompt is the prompt at from there. If the queue changed since, ex. because its first prompt was sent, it fails.
+func (e *Engine) MoveQueued(sessionID string, from, to int, prompt string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.MoveQueued(sessionID, from, to, prompt)
+}
+
+// CancelQueued removes the queued prompt at index from the session's queue. Like in MoveQueued, prompt is the prompt
+// expected there.
+func (e *Engine) CancelQueued(sessionID string, index int, prompt string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.CancelQueued(sessionID, index, prompt)
+}
+
+// Review adds comments to the session's changes and queues every comment not sent yet as one prompt, so the program
+// addresses them once it's ready for input. comments may be empty to send the ones added with AddComment, ex. in the
+// TUI. Returns the prompt. Each comment is on lines of the session's worktree, numbered as in its current version of
+// the file; the comments move with their lines, and are resolved once the lines change. They're kept with the session
+// and published in a review event whenever they change.
+func (e *Engine) Review(sessionID string, comments []ReviewComment) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return "", fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Review(sessionID, comments)
+}
+
+// AddComment adds a review comment to the session's changes without sending it. Returns the comment with its ID.
+func (e *Engine) AddComment(sessionID string, comment ReviewComment) (ReviewComment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return ReviewComment{}, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.AddComment(sessionID, comment)
+}
+
+// Comments returns the session's review comments, sent and resolved ones included, in the order they were added.
+func (e *Engine) Comments(sessionID string) ([]ReviewComment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Comments(sessionID)
+}
+
+// DeleteComment removes a review comment from the session.
+func (e *Engine) DeleteComment(sessionID, commentID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.DeleteComment(sessionID, commentID)
+}
+
+// DiffHistory returns snapshots of the size of the session's diff over time, oldest first. A snapshot is taken each
+// time the program finishes a turn, going from running to ready, and every few seconds while the diff changes in
+// between. Turn snapshots carry the whole diff when diff.keep_turn_diffs is set.
+func (e *Engine) DiffHistory(sessionID string) ([]DiffSnapshot, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.DiffHistory(sessionID)
+}
+
 // Kill terminates the specified session and cleans up all resources.
 func (e *Engine) Kill(sessionID string) error {
 	e.mu.RLock()
@@ -176,6 +448,72 @@ func (e *Engine) Get(sessionID string) (*SessionInfo, error) {
 	return &info, nil
 }
 
+// Scrollback returns lines fromLine through toLine, inclusive, of the session's terminal history followed by
+// its visible screen. Line 0 is the oldest line still retained (see config HistoryLimit); a negative toLine
+// reads through the last visible line. Lines include ANSI escape sequences.
+func (e *Engine) Scrollback(sessionID string, fromLine, toLine int) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return "", fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Scrollback(sessionID, fromLine, toLine)
+}
+
+// OpenWindow starts command in a new companion window of the session, in the session's worktree.
+// An empty command runs the user's shell. Companion windows are closed when the session is paused
+// or killed, and reopened when it is resumed.
+func (e *Engine) OpenWindow(sessionID, name, command string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.OpenWindow(sessionID, name, command)
+}
+
+// CloseWindow terminates the named companion window of the session.
+func (e *Engine) CloseWindow(sessionID, name string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.CloseWindow(sessionID, name)
+}
+
+// SendKeys writes keys to the named companion window of the session. An empty window writes to the
+// session's program. No enter keystroke is added; include "\r" to submit.
+func (e *Engine) SendKeys(sessionID, window, keys string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.SendKeys(sessionID, window, keys)
+}
+
+// Capture returns the visible screen of the named companion window of the session, including ANSI
+// escape sequences. An empty window captures the session's program.
+func (e *Engine) Capture(sessionID, window string) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	
+	if !e.started {
+		return "", fmt.Errorf("engine not started")
+	}
+	
+	return e.mgr.Capture(sessionID, window)
+}
+
 // Events returns a channel that receives events for the specified session.
 // If sessionID is empty, receives events for all sessions.
 // The returned channel will be closed when the engine is shut down.
@@ -198,11 +536,57 @@ func (e *Engine) UpdateConfig(cfg *config.Config) error {
 	if cfg == nil {
 		return fmt.Errorf("config cannot be nil")
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
 	
+	changed := config.ChangedKeys(e.cfg, cfg)
 	e.cfg = cfg
-	e.mgr.cfg = cfg
+	e.mgr.setConfig(cfg)
 	
-	return e.store.SaveConfig(cfg)
+	if err := e.store.SaveConfig(cfg); err != nil {
+		return err
+	}
+	if e.watcher != nil {
+		// The watcher would otherwise report the saved file as another change
+		e.watcher.SetCurrent(cfg)
+	}
+	if len(changed) > 0 {
+		e.eventBus.Publish(createEvent("", EventConfigChanged, ConfigChangedEvent{Keys: changed}))
+	}
+	return nil
+}
+
+// WatchConfig reloads the configuration whenever the config file changes, checking every interval (zero uses
+// config.DefaultWatchInterval). New values apply to sessions created afterwards, except for the idle policy, which
+// applies right away. Each reload publishes a config_changed event; an invalid edit is rejected, keeping the current
+// config, and published as a config_changed event with an error.
+func (e *Engine) WatchConfig(interval time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	if e.watcher != nil {
+		return fmt.Errorf("config watcher already running")
+	}
+	
+	watcher := config.NewWatcher("", e.cfg, interval)
+	watcher.OnChange = func(cfg *config.Config, changed []string) {
+		e.mu.Lock()
+		e.cfg = cfg
+		e.mgr.setConfig(cfg)
+		e.mu.Unlock()
+		e.eventBus.Publish(createEvent("", EventConfigChanged, ConfigChangedEvent{Keys: changed}))
+	}
+	watcher.OnError = func(err error) {
+		e.eventBus.Publish(createEvent("", EventConfigChanged, ConfigChangedEvent{Error: err.Error()}))
+	}
+	watcher.Start()
+	e.watcher = watcher
+	
+	return nil
 }
 
 // GetConfig returns the current configuration.
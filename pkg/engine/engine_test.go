@@ -2,8 +2,15 @@ package engine
 
 import (
 	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
 	"context"
 	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,6 +47,48 @@ func (m *MockStateManager) SetHelpScreensSeen(seen uint32) error {
 	return nil
 }
 
+// newTestEngine starts an engine running cat in headless sessions, with a fresh home directory. It's closed when the
+// test ends.
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+	
+	cfg := &config.Config{DefaultProgram: "cat", DaemonPollInterval: 1000, BranchPrefix: "test/",
+		TerminalBackend: session.BackendHeadless}
+	engine, err := New(cfg, &MockStateManager{})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	return engine
+}
+
+// newTestRepo creates a git repository with files committed, or an empty commit if there are none
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, output)
+		}
+	}
+	return repo
+}
+
 func TestEngineCreation(t *testing.T) {
 	cfg := &config.Config{
 		DefaultProgram:     "echo test",
@@ -205,10 +254,660 @@ func TestEngineErrorHandling(t *testing.T) {
 		t.Fatal("Kill should fail when engine not started")
 	}
 	
+	_, err = engine.Scrollback("test", 0, -1)
+	if err == nil {
+		t.Fatal("Scrollback should fail when engine not started")
+	}
+	
+	err = engine.OpenWindow("test", "shell", "")
+	if err == nil {
+		t.Fatal("OpenWindow should fail when engine not started")
+	}
+	
+	_, err = engine.Capture("test", "shell")
+	if err == nil {
+		t.Fatal("Capture should fail when engine not started")
+	}
+	
 	_, err = engine.Events("")
 	if err == nil {
 		t.Fatal("Events should fail when engine not started")
 	}
+	
+	err = engine.WatchConfig(0)
+	if err == nil {
+		t.Fatal("WatchConfig should fail when engine not started")
+	}
+}
+
+func TestEngineWatchConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{
+		DefaultProgram:     "echo test",
+		DaemonPollInterval: 1000,
+		BranchPrefix:       "test/",
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	configPath, err := config.ConfigFilePath()
+	if err != nil {
+		t.Fatalf("Failed to get config path: %v", err)
+	}
+	
+	engine, err := New(cfg, &MockStateManager{})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	defer engine.Close()
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	eventCh, err := engine.Events("")
+	if err != nil {
+		t.Fatalf("Failed to subscribe to events: %v", err)
+	}
+	if err := engine.WatchConfig(10 * time.Millisecond); err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	if err := engine.WatchConfig(0); err == nil {
+		t.Fatal("WatchConfig should fail when already watching")
+	}
+	
+	nextConfigEvent := func() ConfigChangedEvent {
+		for {
+			select {
+			case event := <-eventCh:
+				if event.Kind == EventConfigChanged {
+					return event.Payload.(ConfigChangedEvent)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Timed out waiting for config_changed event")
+			}
+		}
+	}
+	
+	updated := `{"default_program": "aider", "daemon_poll_interval": 1000, "branch_prefix": "test/",
+		"auto_yes": false, "terminal_backend": "", "history_limit": 0}`
+	if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	event := nextConfigEvent()
+	if event.Error != "" || len(event.Keys) != 1 || event.Keys[0] != "default_program" {
+		t.Fatalf("Expected default_program to change, got %+v", event)
+	}
+	if engine.GetConfig().DefaultProgram != "aider" {
+		t.Fatalf("Expected default program 'aider', got '%s'", engine.GetConfig().DefaultProgram)
+	}
+	
+	if err := os.WriteFile(configPath, []byte(`{"default_program": "`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	event = nextConfigEvent()
+	if event.Error == "" {
+		t.Fatalf("Expected an error for an invalid config, got %+v", event)
+	}
+	if engine.GetConfig().DefaultProgram != "aider" {
+		t.Fatal("Invalid config should not replace the current config")
+	}
+}
+
+func TestEngineRevive(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	defer log.Close()
+	
+	// A session whose headless program died with the process that ran it, like after a reboot
+	repo := newTestRepo(t, nil)
+	worktreePath := filepath.Join(t.TempDir(), "task")
+	if output, err := exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", "test/task",
+		worktreePath).CombinedOutput(); err != nil {
+		t.Fatalf("git worktree add failed: %s", output)
+	}
+	stored, err := json.Marshal([]session.InstanceData{{
+		Title:      "task",
+		Path:       repo,
+		Branch:     "test/task",
+		Status:     session.Running,
+		Program:    "cat",
+		Backend:    session.BackendHeadless,
+		LastPrompt: "hello again",
+		Worktree: session.GitWorktreeData{
+			RepoPath:     repo,
+			WorktreePath: worktreePath,
+			SessionName:  "task",
+			BranchName:   "test/task",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to marshal sessions: %v", err)
+	}
+	
+	cfg := &config.Config{DefaultProgram: "cat", DaemonPollInterval: 1000, BranchPrefix: "test/"}
+	engine, err := New(cfg, &MockStateManager{instancesData: stored})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	defer engine.Close()
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	
+	info, err := engine.Get("task")
+	if err != nil {
+		t.Fatalf("Dead session should still be loaded: %v", err)
+	}
+	if info.Status != StatusCrashed {
+		t.Fatalf("Expected status %s, got %s", StatusCrashed, info.Status)
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		t.Fatalf("Worktree of a crashed session should be kept: %v", err)
+	}
+	
+	eventCh, err := engine.Events("task")
+	if err != nil {
+		t.Fatalf("Failed to subscribe to events: %v", err)
+	}
+	if err := engine.Revive("task"); err != nil {
+		t.Fatalf("Failed to revive session: %v", err)
+	}
+	if err := engine.Revive("task"); err == nil {
+		t.Fatal("Reviving a running session should fail")
+	}
+	
+	deadline := time.After(10 * time.Second)
+	revived, delivered := false, false
+	for !revived || !delivered {
+		select {
+		case event := <-eventCh:
+			// The session may also go from running to ready while it's revived
+			if state, ok := event.Payload.(StateEvent); ok && state.Previous == StatusCrashed {
+				if state.Current != StatusRunning {
+					t.Fatalf("Expected crashed -> running, got %+v", state)
+				}
+				revived = true
+			}
+			// The last prompt is queued, and sent once the program is ready for input
+			if queueEvent, ok := event.Payload.(QueueEvent); ok && queueEvent.Delivered == "hello again" {
+				delivered = true
+			}
+		case <-deadline:
+			t.Fatalf("Timed out waiting for the session to be revived (%v) and its last prompt sent (%v)",
+				revived, delivered)
+		}
+	}
+	// cat echoes the prompt that was sent again
+	if content, err := engine.Capture("task", ""); err != nil || !strings.Contains(content, "hello again") {
+		t.Fatalf("Expected the last prompt to be sent again, got %q (%v)", content, err)
+	}
+	
+	if err := engine.Kill("task"); err != nil {
+		t.Fatalf("Failed to kill session: %v", err)
+	}
+}
+
+func TestEngineQueue(t *testing.T) {
+	engine := newTestEngine(t)
+	repo := newTestRepo(t, nil)
+	
+	id, err := engine.StartSession(context.Background(), SessionOpts{Title: "queue", Path: repo})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer engine.Kill(id)
+	eventCh, err := engine.Events(id)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to events: %v", err)
+	}
+	
+	// The session is still starting up, so nothing is sent while the queue is arranged
+	for _, prompt := range []string{"first", "second", "third"} {
+		if err := engine.Enqueue(id, prompt); err != nil {
+			t.Fatalf("Failed to enqueue %q: %v", prompt, err)
+		}
+	}
+	if err := engine.Enqueue(id, " "); err == nil {
+		t.Error("Expected error when enqueuing an empty prompt")
+	}
+	if err := engine.MoveQueued(id, 2, 0, "third"); err != nil {
+		t.Fatalf("Failed to move prompt: %v", err)
+	}
+	if err := engine.CancelQueued(id, 2, "first"); err == nil {
+		t.Error("Expected error when cancelling a prompt that moved")
+	}
+	if err := engine.CancelQueued(id, 2, "second"); err != nil {
+		t.Fatalf("Failed to cancel prompt: %v", err)
+	}
+	if err := engine.CancelQueued(id, 2, "second"); err == nil {
+		t.Error("Expected error when cancelling a prompt that isn't queued")
+	}
+	queue, err := engine.Queue(id)
+	if err != nil {
+		t.Fatalf("Failed to get queue: %v", err)
+	}
+	if strings.Join(queue, ",") != "third,first" {
+		t.Fatalf("Expected queue [third first], got %v", queue)
+	}
+	
+	// The prompts are sent one at a time, each once cat has echoed the previous one and gone quiet
+	var delivered []string
+	deadline := time.After(10 * time.Second)
+	for len(delivered) < 2 {
+		select {
+		case event := <-eventCh:
+			if queueEvent, ok := event.Payload.(QueueEvent); ok && queueEvent.Delivered != "" {
+				delivered = append(delivered, queueEvent.Delivered)
+				if len(queueEvent.Prompts) != 2-len(delivered) {
+					t.Fatalf("Expected %d prompts left after sending %q, got %v", 2-len(delivered),
+						queueEvent.Delivered, queueEvent.Prompts)
+				}
+			}
+		case <-deadline:
+			t.Fatalf("Timed out waiting for queued prompts, sent %v", delivered)
+		}
+	}
+	if strings.Join(delivered, ",") != "third,first" {
+		t.Fatalf("Expected prompts to be sent in queue order, got %v", delivered)
+	}
+	content, err := engine.Capture(id, "")
+	if err != nil {
+		t.Fatalf("Failed to capture session: %v", err)
+	}
+	if strings.Index(content, "third") > strings.Index(content, "first") {
+		t.Fatalf("Expected cat to echo third before first, got %q", content)
+	}
+}
+
+func TestEngineQueueAfterRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	defer log.Close()
+	repo := newTestRepo(t, nil)
+	
+	// A session paused when the engine stopped is restored paused, with nothing watching it
+	cfg := &config.Config{DefaultProgram: "cat", DaemonPollInterval: 1000, BranchPrefix: "test/",
+		TerminalBackend: session.BackendHeadless}
+	state := &MockStateManager{}
+	engine, err := New(cfg, state)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	id, err := engine.StartSession(context.Background(), SessionOpts{Title: "restart", Path: repo})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := engine.Pause(id); err != nil {
+		t.Fatalf("Failed to pause session: %v", err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("Failed to close engine: %v", err)
+	}
+	
+	engine, err = New(cfg, state)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	defer engine.Close()
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	defer engine.Kill(id)
+	eventCh, err := engine.Events(id)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to events: %v", err)
+	}
+	
+	// Resuming it starts watching it, so its queue is sent
+	if err := engine.Enqueue(id, "after restart"); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	if err := engine.Resume(id); err != nil {
+		t.Fatalf("Failed to resume session: %v", err)
+	}
+	deadline := time.After(10 * time.Second)
+	for delivered := false; !delivered; {
+		select {
+		case event := <-eventCh:
+			if queueEvent, ok := event.Payload.(QueueEvent); ok && queueEvent.Delivered == "after restart" {
+				delivered = true
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for the queued prompt to be sent after resuming")
+		}
+	}
+}
+
+func TestEngineReview(t *testing.T) {
+	engine := newTestEngine(t)
+	repo := newTestRepo(t, map[string]string{"main.go": "package main\n\nvar x = 1\n"})
+	
+	id, err := engine.StartSession(context.Background(), SessionOpts{Title: "review", Path: repo})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer engine.Kill(id)
+	eventCh, err := engine.Events(id)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to events: %v", err)
+	}
+	
+	comment, err := engine.AddComment(id, ReviewComment{Path: "main.go", StartLine: 3, Body: "rename x"})
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	if _, err := engine.AddComment(id, ReviewComment{Path: "missing.go", StartLine: 1, Body: "?"}); err == nil {
+		t.Error("Expected error when commenting on a file that doesn't exist")
+	}
+	prompt, err := engine.Review(id, []ReviewComment{{Path: "main.go", StartLine: 1, Body: "add a doc comment"}})
+	if err != nil {
+		t.Fatalf("Failed to send review: %v", err)
+	}
+	if !strings.Contains(prompt, "In main.go:3, where you have:\n    var x = 1\nrename x\n") ||
+		!strings.Contains(prompt, "In main.go:1") {
+		t.Errorf("Expected both comments in the prompt, got %q", prompt)
+	}
+	if _, err := engine.Review(id, nil); err == nil {
+		t.Error("Expected error when sending a review with no new comments")
+	}
+	
+	// Changing the commented line resolves its comment
+	wrapper, err := engine.mgr.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := wrapper.instance.GetGitWorktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "main.go"),
+		[]byte("package main\n\nvar count = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(10 * time.Second)
+	for resolved := false; !resolved; {
+		select {
+		case event := <-eventCh:
+			if review, ok := event.Payload.(ReviewEvent); ok {
+				for _, c := range review.Comments {
+					resolved = resolved || (c.ID == comment.ID && c.Resolved)
+				}
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for the comment to be resolved")
+		}
+	}
+	comments, err := engine.Comments(id)
+	if err != nil || len(comments) != 2 || comments[1].Resolved || !comments[1].Sent {
+		t.Fatalf("Expected the other comment to stay open, got %+v, %v", comments, err)
+	}
+	if err := engine.DeleteComment(id, comment.ID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+}
+
+func TestEngineConflicts(t *testing.T) {
+	engine := newTestEngine(t)
+	repo := newTestRepo(t, map[string]string{"main.go": "package main\n\nvar x = 1\n"})
+	
+	// Both sessions change the same line
+	var ids []string
+	for _, title := range []string{"one", "two"} {
+		id, err := engine.StartSession(context.Background(), SessionOpts{Title: title, Path: repo})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		defer engine.Kill(id)
+		eventCh, err := engine.Events(id)
+		if err != nil {
+			t.Fatalf("Failed to subscribe to events: %v", err)
+		}
+		wrapper, err := engine.mgr.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		worktree, err := wrapper.instance.GetGitWorktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "main.go"),
+			[]byte("package main\n\nvar x = "+title+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.After(10 * time.Second)
+		for changed := false; !changed; {
+			select {
+			case event := <-eventCh:
+				if diff, ok := event.Payload.(DiffEvent); ok && diff.Stats != nil && len(diff.Stats.Files) == 1 {
+					changed = true
+				}
+			case <-deadline:
+				t.Fatalf("Timed out waiting for the diff of %s", id)
+			}
+		}
+		ids = append(ids, id)
+	}
+	
+	// The diff history took a snapshot of the change
+	history, err := engine.DiffHistory(ids[0])
+	if err != nil {
+		t.Fatalf("Failed to get diff history: %v", err)
+	}
+	if n := len(history); n == 0 || history[n-1].Added != 1 || history[n-1].Removed != 1 || history[n-1].Files != 1 {
+		t.Errorf("Expected a snapshot of one changed line, got %+v", history)
+	}
+	if _, err := engine.DiffHistory("missing"); err == nil {
+		t.Error("Expected an error for a missing session")
+	}
+	
+	conflicts, err := engine.Conflicts(ids[0])
+	if err != nil {
+		t.Fatalf("Failed to get conflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].ID != ids[1] || len(conflicts[0].Files) != 1 ||
+		conflicts[0].Files[0].Path != "main.go" ||
+		!reflect.DeepEqual(conflicts[0].Files[0].Lines, []LineRange{{Start: 3, End: 3}}) {
+		t.Fatalf("Expected line 3 of main.go to conflict with %s, got %+v", ids[1], conflicts)
+	}
+	if _, err := engine.Conflicts("missing"); err == nil {
+		t.Error("Expected error when getting the conflicts of a session that doesn't exist")
+	}
+	
+	result, err := engine.SimulateMerge(ids[0], ids[1])
+	if err != nil {
+		t.Fatalf("Failed to simulate merge: %v", err)
+	}
+	if result.Clean || !reflect.DeepEqual(result.Conflicts, []string{"main.go"}) {
+		t.Errorf("Expected main.go to conflict, got %+v", result)
+	}
+	if _, err := engine.SimulateMerge(ids[0], ids[0]); err == nil {
+		t.Error("Expected error when merging a session with itself")
+	}
+}
+
+func TestEnginePatch(t *testing.T) {
+	engine := newTestEngine(t)
+	repo := newTestRepo(t, map[string]string{"main.go": "package main\n", "helper.go": "package main\n"})
+	
+	// Both sessions change both files
+	var ids, worktrees []string
+	for _, title := range []string{"one", "two"} {
+		id, err := engine.StartSession(context.Background(), SessionOpts{Title: title, Path: repo})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		defer engine.Kill(id)
+		wrapper, err := engine.mgr.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		worktree, err := wrapper.instance.GetGitWorktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"main.go", "helper.go"} {
+			if err := os.WriteFile(filepath.Join(worktree.GetWorktreePath(), name),
+				[]byte("package main\n\n// "+title+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids = append(ids, id)
+		worktrees = append(worktrees, worktree.GetWorktreePath())
+	}
+	
+	patch, err := engine.ExportPatch(ids[0], PatchFormatDiff)
+	if err != nil {
+		t.Fatalf("Failed to export patch: %v", err)
+	}
+	if !strings.Contains(patch, "diff --git a/helper.go b/helper.go") || !strings.Contains(patch, "+// one") {
+		t.Errorf("Expected the patch to have the changes of %s, got %q", ids[0], patch)
+	}
+	mail, err := engine.ExportPatch(ids[0], PatchFormatMail)
+	if err != nil {
+		t.Fatalf("Failed to export patch: %v", err)
+	}
+	if !strings.Contains(mail, "Subject: [PATCH] Uncommitted changes of one") {
+		t.Errorf("Expected a mail with the uncommitted changes, got %q", mail)
+	}
+	if _, err := engine.ExportPatch(ids[0], "zip"); err == nil {
+		t.Error("Expected error when exporting a patch in an unknown format")
+	}
+	
+	// Taking the helper of one into two needs two's helper to be as it was
+	if err := engine.ApplyPatch(ids[1], ids[0], []string{"helper.go"}); err == nil {
+		t.Error("Expected error when the patch doesn't apply")
+	}
+	if err := os.WriteFile(filepath.Join(worktrees[1], "helper.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.ApplyPatch(ids[1], ids[0], []string{"helper.go"}); err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	for name, expected := range map[string]string{"helper.go": "// one", "main.go": "// two"} {
+		content, err := os.ReadFile(filepath.Join(worktrees[1], name))
+		if err != nil || !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %s of %s to have %q, got %q, %v", name, ids[1], expected, content, err)
+		}
+	}
+	if err := engine.ApplyPatch(ids[1], ids[0], []string{"missing.go"}); err == nil {
+		t.Error("Expected error when applying a file the source didn't change")
+	}
+}
+
+func TestEngineFanOut(t *testing.T) {
+	engine := newTestEngine(t)
+	repo := newTestRepo(t, nil)
+	
+	// A base that doesn't resolve fails the whole fan-out before anything starts
+	if _, err := engine.FanOut(context.Background(), SessionOpts{Title: "fan", Path: repo, BaseRef: "missing"},
+		[]string{"cat", "cat -u"}); err == nil {
+		t.Fatal("Expected error when fanning out from a missing base")
+	}
+	if sessions := engine.List(); len(sessions) != 0 {
+		t.Fatalf("Expected no sessions after a failed fan-out, got %d", len(sessions))
+	}
+	
+	ids, err := engine.FanOut(context.Background(), SessionOpts{Title: "fan", Path: repo, Prompt: "hello"},
+		[]string{"cat", "cat -u"})
+	if err != nil {
+		t.Fatalf("Failed to fan out: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 sessions, got %v", ids)
+	}
+	if _, err := engine.FanOut(context.Background(), SessionOpts{Title: "fan", Path: repo}, []string{"cat -n"}); err == nil {
+		t.Error("Expected error when fanning out with the title of another fan-out")
+	}
+	
+	// Each program gets the prompt once it's ready for input
+	eventChs := make([]<-chan Event, len(ids))
+	for i, id := range ids {
+		if eventChs[i], err = engine.Events(id); err != nil {
+			t.Fatalf("Failed to subscribe to events: %v", err)
+		}
+	}
+	for i, id := range ids {
+		deadline := time.After(10 * time.Second)
+		for delivered := false; !delivered; {
+			select {
+			case event := <-eventChs[i]:
+				if queueEvent, ok := event.Payload.(QueueEvent); ok && queueEvent.Delivered == "hello" {
+					delivered = true
+				}
+			case <-deadline:
+				t.Fatalf("Timed out waiting for the prompt to be sent to %s", id)
+			}
+		}
+	}
+	
+	comparisons, err := engine.Compare("fan")
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if len(comparisons) != 2 {
+		t.Fatalf("Expected 2 comparisons, got %d", len(comparisons))
+	}
+	for i, expected := range []struct{ title, program string }{{"fan-cat", "cat"}, {"fan-cat-2", "cat -u"}} {
+		if comparisons[i].ID != ids[i] || comparisons[i].Title != expected.title ||
+			comparisons[i].Program != expected.program {
+			t.Errorf("Expected comparison %d to be %s running %q, got %+v", i, expected.title, expected.program,
+				comparisons[i])
+		}
+	}
+	if _, err := engine.Compare("missing"); err == nil {
+		t.Error("Expected error when comparing a group that doesn't exist")
+	}
+	if diff, err := engine.Diff(ids[0]); err != nil || len(diff.Files) != 0 {
+		t.Errorf("Expected an empty diff for a session that changed nothing, got %+v, %v", diff, err)
+	}
+	if _, err := engine.Diff("missing"); err == nil {
+		t.Error("Expected error when getting the diff of a session that doesn't exist")
+	}
+	if diff, err := engine.DiffWithOptions(ids[0], DiffOptions{IgnoreWhitespace: true}); err != nil ||
+		len(diff.Files) != 0 {
+		t.Errorf("Expected an empty diff ignoring whitespace, got %+v, %v", diff, err)
+	}
+	
+	// A sibling that fails to be killed stays in the group, and keeping can be retried
+	wrapper, err := engine.mgr.Get(ids[0])
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", ids[0], err)
+	}
+	worktree, err := wrapper.instance.GetGitWorktree()
+	if err != nil {
+		t.Fatalf("Failed to get the worktree of %s: %v", ids[0], err)
+	}
+	lockWorktree := func(command string) {
+		if output, err := exec.Command("git", "-C", repo, "worktree", command,
+			worktree.GetWorktreePath()).CombinedOutput(); err != nil {
+			t.Fatalf("git worktree %s failed: %s", command, output)
+		}
+	}
+	lockWorktree("lock")
+	if err := engine.Keep(ids[1]); err == nil {
+		t.Fatal("Expected error when a sibling's worktree can't be removed")
+	}
+	if _, err := engine.Get(ids[0]); err != nil {
+		t.Errorf("Expected %s to stay after failing to kill it: %v", ids[0], err)
+	}
+	lockWorktree("unlock")
+	
+	// Keeping one kills the other and dissolves the group
+	if err := engine.Keep(ids[1]); err != nil {
+		t.Fatalf("Failed to keep %s: %v", ids[1], err)
+	}
+	defer engine.Kill(ids[1])
+	if _, err := engine.Get(ids[0]); err == nil {
+		t.Errorf("Expected %s to be killed", ids[0])
+	}
+	if _, err := engine.Compare("fan"); err == nil {
+		t.Error("Expected the group to be gone after keeping a session")
+	}
+	if err := engine.Keep(ids[1]); err == nil {
+		t.Error("Expected error when keeping a session that isn't part of a fan-out")
+	}
 }
 
 func TestEventBus(t *testing.T) {
@@ -4,7 +4,11 @@ import (
 	"claude-squad/config"
 	"claude-squad/session"
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 )
@@ -18,6 +22,8 @@ type manager struct {
 	store     StorageInterface
 	stopCh    chan struct{}
 	wg        sync.WaitGroup
+	// idleInterval is how often the idle policy is enforced
+	idleInterval time.Duration
 }
 
 // sessionWrapper wraps a session.Instance with additional metadata
@@ -26,7 +32,12 @@ type sessionWrapper struct {
 	id         string
 	lastStdout string
 	lastDiff   *DiffStats
-	stopCh     chan struct{}
+	// stopCh stops the session's watcher, if watching is true
+	stopCh   chan struct{}
+	watching bool
+	// mu serializes the watcher's checks with everything else reading or changing the instance.
+	// Take it after the manager's lock, never before.
+	mu sync.Mutex
 }
 
 // newManager creates a new session manager
@@ -37,6 +48,8 @@ func newManager(cfg *config.Config, store StorageInterface, eventBus *EventBus)
 		cfg:      cfg,
 		store:    store,
 		stopCh:   make(chan struct{}),
+
+		idleInterval: 10 * time.Second,
 	}
 }
 
@@ -59,18 +72,21 @@ func (m *manager) Start(ctx context.Context) error {
 		}
 	}
 	
+	m.wg.Add(1)
+	go m.watchIdle()
+	
 	return nil
 }
 
 // Stop shuts down the manager and all sessions
 func (m *manager) Stop() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	// Stop all session watchers
+	// Stop all watchers first, since they take the lock themselves
 	close(m.stopCh)
 	m.wg.Wait()
 	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
 	// Save current state
 	if err := m.saveAll(); err != nil {
 		return fmt.Errorf("failed to save sessions: %w", err)
@@ -85,31 +101,27 @@ func (m *manager) Create(opts SessionOpts) (string, error) {
 	defer m.mu.Unlock()
 	
 	// Check for duplicate titles (maintaining backward compatibility)
-	for _, sw := range m.sessions {
-		if sw.instance.Title == opts.Title {
-			return "", fmt.Errorf("session with title '%s' already exists", opts.Title)
-		}
+	if err := m.checkTitle(opts.Title); err != nil {
+		return "", err
 	}
 	
-	// Create new instance
-	instanceOpts := session.InstanceOptions{
-		Title:   opts.Title,
-		Path:    opts.Path,
-		Program: opts.Program,
-		AutoYes: opts.AutoYes,
+	instanceOpts, err := m.instanceOptions(opts)
+	if err != nil {
+		return "", err
 	}
 	
+	// Create new instance
 	instance, err := session.NewInstance(instanceOpts)
 	if err != nil {
 		return "", fmt.Errorf("failed to create instance: %w", err)
 	}
 	
 	// Set AutoYes from options
-	instance.AutoYes = opts.AutoYes
+	instance.AutoYes = instanceOpts.AutoYes
 	
-	// Start the instance
-	if err := instance.Start(true); err != nil {
-		return "", fmt.Errorf("failed to start instance: %w", err)
+	wrapper, err := m.startInstance(instance)
+	if err != nil {
+		return "", err
 	}
 	
 	// Send initial prompt if provided
@@ -120,29 +132,140 @@ func (m *manager) Create(opts SessionOpts) (string, error) {
 		}
 	}
 	
+	m.addSession(wrapper)
+	return wrapper.id, nil
+}
+
+// FanOut creates a session for each program, all from the same base commit, and queues the prompt for each
+func (m *manager) FanOut(opts SessionOpts, programs []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	instanceOpts, err := m.instanceOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Keep on a session of one fan-out would kill the sessions of another with the same group
+	for _, sw := range m.sessions {
+		sw.mu.Lock()
+		group := sw.instance.Group
+		sw.mu.Unlock()
+		if group == opts.Title {
+			return nil, fmt.Errorf("fan-out '%s' already exists", opts.Title)
+		}
+	}
+	instances, err := session.NewFanOut(instanceOpts, programs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instances: %w", err)
+	}
+	for _, instance := range instances {
+		if err := m.checkTitle(instance.Title); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Start all of them before adding any, so a failure leaves nothing behind
+	wrappers := make([]*sessionWrapper, 0, len(instances))
+	for _, instance := range instances {
+		instance.AutoYes = instanceOpts.AutoYes
+		wrapper, err := m.startInstance(instance)
+		if err == nil {
+			wrappers = append(wrappers, wrapper)
+			// Queue the prompt, so each program gets it once it's ready for input
+			if opts.Prompt != "" {
+				err = instance.Enqueue(opts.Prompt)
+			}
+		}
+		if err != nil {
+			for _, started := range wrappers {
+				if killErr := started.instance.Kill(); killErr != nil {
+					fmt.Printf("Warning: failed to clean up %s: %v\n", started.instance.Title, killErr)
+				}
+			}
+			return nil, err
+		}
+	}
+	
+	ids := make([]string, len(wrappers))
+	for i, wrapper := range wrappers {
+		m.addSession(wrapper)
+		ids[i] = wrapper.id
+	}
+	return ids, nil
+}
+
+// checkTitle returns an error if a session already has the title
+func (m *manager) checkTitle(title string) error {
+	for _, sw := range m.sessions {
+		if sw.instance.Title == title {
+			return fmt.Errorf("session with title '%s' already exists", title)
+		}
+	}
+	return nil
+}
+
+// instanceOptions applies the config of the session's repository to opts
+func (m *manager) instanceOptions(opts SessionOpts) (session.InstanceOptions, error) {
+	cfg, err := m.cfg.ForRepo(opts.Path)
+	if err != nil {
+		return session.InstanceOptions{}, err
+	}
+	
+	backend := opts.Backend
+	if backend == "" {
+		backend = cfg.TerminalBackend
+	}
+	program := opts.Program
+	if program == "" {
+		program = cfg.DefaultProgram
+	}
+	
+	instanceOpts := session.InstanceOptions{
+		Title:   opts.Title,
+		Path:    opts.Path,
+		Program: program,
+		AutoYes: opts.AutoYes || cfg.AutoYes,
+		Backend: backend,
+
+		HistoryLimit: cfg.HistoryLimit,
+		BaseRef:      opts.BaseRef,
+	}
+	for _, w := range opts.Windows {
+		instanceOpts.Windows = append(instanceOpts.Windows, session.Window{Name: w.Name, Command: w.Command})
+	}
+	return instanceOpts, nil
+}
+
+// startInstance starts a new instance and wraps it, without adding it to the manager
+func (m *manager) startInstance(instance *session.Instance) (*sessionWrapper, error) {
 	// Generate session ID (use title for backward compatibility)
-	sessionID := generateSessionID(opts.Title)
+	sessionID := generateSessionID(instance.Title)
+	instance.OnHook = m.hookPublisher(sessionID)
 	
-	// Create wrapper and start watching
-	wrapper := &sessionWrapper{
-		instance: instance,
-		id:       sessionID,
-		stopCh:   make(chan struct{}),
+	// Start the instance
+	if err := instance.Start(true); err != nil {
+		return nil, fmt.Errorf("failed to start instance %s: %w", instance.Title, err)
 	}
 	
-	m.sessions[sessionID] = wrapper
+	return &sessionWrapper{
+		instance: instance,
+		id:       sessionID,
+	}, nil
+}
+
+// addSession adds a started session, starts watching it and publishes its creation
+func (m *manager) addSession(wrapper *sessionWrapper) {
+	m.sessions[wrapper.id] = wrapper
+	status := convertStatus(wrapper.instance.Status)
 	
 	// Start watching the session
-	m.wg.Add(1)
-	go m.watchSession(wrapper)
+	m.watch(wrapper)
 	
 	// Publish creation event
-	m.eventBus.Publish(createEvent(sessionID, EventState, StateEvent{
+	m.eventBus.Publish(createEvent(wrapper.id, EventState, StateEvent{
 		Previous: StatusLoading,
-		Current:  convertStatus(instance.Status),
+		Current:  status,
 	}))
-	
-	return sessionID, nil
 }
 
 // Get retrieves a session by ID
@@ -165,7 +288,9 @@ func (m *manager) List() []SessionInfo {
 	
 	sessions := make([]SessionInfo, 0, len(m.sessions))
 	for _, wrapper := range m.sessions {
+		wrapper.mu.Lock()
 		sessions = append(sessions, m.wrapperToSessionInfo(wrapper))
+		wrapper.mu.Unlock()
 	}
 	
 	return sessions
@@ -178,6 +303,9 @@ func (m *manager) Pause(sessionID string) error {
 		return err
 	}
 	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
 	prevStatus := convertStatus(wrapper.instance.Status)
 	
 	if err := wrapper.instance.Pause(); err != nil {
@@ -200,12 +328,56 @@ func (m *manager) Resume(sessionID string) error {
 		return err
 	}
 	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
 	prevStatus := convertStatus(wrapper.instance.Status)
 	
 	if err := wrapper.instance.Resume(); err != nil {
 		return fmt.Errorf("failed to resume session: %w", err)
 	}
 	
+	// A session restored paused has no watcher yet, and needs one to track its status and send its queue
+	m.watch(wrapper)
+	
+	// Publish state change event
+	m.eventBus.Publish(createEvent(sessionID, EventState, StateEvent{
+		Previous: prevStatus,
+		Current:  convertStatus(wrapper.instance.Status),
+	}))
+	
+	return nil
+}
+
+// Revive restarts a crashed session in its worktree and queues its last prompt again
+func (m *manager) Revive(sessionID string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	prevStatus := convertStatus(wrapper.instance.Status)
+	
+	if err := wrapper.instance.Revive(); err != nil {
+		return fmt.Errorf("failed to revive session: %w", err)
+	}
+	
+	// Queue the prompt ahead of the others, so the watcher sends it once the program is ready for input
+	if prompt := wrapper.instance.LastPrompt; prompt != "" {
+		queued := len(wrapper.instance.QueuedPrompts())
+		if err := wrapper.instance.Enqueue(prompt); err == nil {
+			err = wrapper.instance.MoveQueued(queued, 0, prompt)
+		}
+		if err != nil {
+			// Log warning but don't fail the revive
+			fmt.Printf("Warning: failed to queue last prompt: %v\n", err)
+		}
+		m.publishQueue(wrapper, "")
+	}
+	
 	// Publish state change event
 	m.eventBus.Publish(createEvent(sessionID, EventState, StateEvent{
 		Previous: prevStatus,
@@ -225,8 +397,11 @@ func (m *manager) Kill(sessionID string) error {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 	
-	// Stop watching
-	close(wrapper.stopCh)
+	// Stop watching, waiting for a check in progress so it doesn't see the session half killed. If killing fails,
+	// the session stays without a watcher until it's killed again or resumed.
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	wrapper.stopWatching()
 	
 	// Kill the instance
 	if err := wrapper.instance.Kill(); err != nil {
@@ -245,8 +420,547 @@ func (m *manager) Kill(sessionID string) error {
 	return nil
 }
 
-// watchSession monitors a session for changes and publishes events
-func (m *manager) watchSession(wrapper *sessionWrapper) {
+// Diff returns the last diff of a session against its base commit
+func (m *manager) Diff(sessionID string) (*DiffStats, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	stats := wrapper.instance.GetDiffStats()
+	if stats != nil && stats.Error != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", stats.Error)
+	}
+	if diff := convertDiffStats(stats); diff != nil {
+		return diff, nil
+	}
+	return &DiffStats{}, nil
+}
+
+// DiffWithOptions computes a session's diff with opts instead of the configured diff options
+func (m *manager) DiffWithOptions(sessionID string, opts DiffOptions) (*DiffStats, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	stats, err := wrapper.instance.DiffWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return convertDiffStats(stats), nil
+}
+
+// Compare describes the sessions of a fan-out group side by side
+func (m *manager) Compare(group string) ([]Comparison, error) {
+	siblings := m.groupSessions(group)
+	if len(siblings) == 0 {
+		return nil, fmt.Errorf("fan-out not found: %s", group)
+	}
+	
+	comparisons := make([]Comparison, 0, len(siblings))
+	unlock := lockSessions(siblings)
+	defer unlock()
+	for _, wrapper := range siblings {
+		instance := wrapper.instance
+		comparison := Comparison{
+			ID:            wrapper.id,
+			Title:         instance.Title,
+			Program:       instance.Program,
+			Status:        convertStatus(instance.Status),
+			TimeToReadyMs: instance.TimeToReady.Milliseconds(),
+		}
+		if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+			comparison.Added = stats.Added
+			comparison.Removed = stats.Removed
+			comparison.Files = stats.Paths()
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons, nil
+}
+
+// Keep kills the other sessions of a session's fan-out group and takes the session out of the group
+func (m *manager) Keep(sessionID string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	wrapper.mu.Lock()
+	group := wrapper.instance.Group
+	wrapper.mu.Unlock()
+	if group == "" {
+		return fmt.Errorf("session %s is not part of a fan-out", sessionID)
+	}
+	
+	var errs []error
+	for _, sibling := range m.groupSessions(group) {
+		if sibling.id == sessionID {
+			continue
+		}
+		if err := m.Kill(sibling.id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sibling.id, err))
+		}
+	}
+	if len(errs) > 0 {
+		// Leave the group alone, so the remaining siblings can still be compared
+		return errors.Join(errs...)
+	}
+	
+	wrapper.mu.Lock()
+	wrapper.instance.Group = ""
+	wrapper.mu.Unlock()
+	return nil
+}
+
+// Conflicts returns what a session changed that other sessions of its repository changed too
+func (m *manager) Conflicts(sessionID string) ([]Conflict, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	others := m.sortedSessions()
+	unlock := lockSessions(others)
+	defer unlock()
+	ids := make(map[*session.Instance]string, len(others))
+	instances := make([]*session.Instance, 0, len(others))
+	for _, other := range others {
+		ids[other.instance] = other.id
+		instances = append(instances, other.instance)
+	}
+	
+	found := session.FindConflicts(instances)[wrapper.instance]
+	conflicts := make([]Conflict, 0, len(found))
+	for _, c := range found {
+		conflicts = append(conflicts, Conflict{ID: ids[c.Other], Title: c.Other.Title, Files: c.Files})
+	}
+	return conflicts, nil
+}
+
+// SimulateMerge merges the changes of two sessions in memory and reports the files that conflict
+func (m *manager) SimulateMerge(sessionA, sessionB string) (*MergeResult, error) {
+	a, err := m.Get(sessionA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := m.Get(sessionB)
+	if err != nil {
+		return nil, err
+	}
+	
+	unlock := lockSessions([]*sessionWrapper{a, b})
+	defer unlock()
+	
+	return session.SimulateMerge(a.instance, b.instance)
+}
+
+// ExportPatch returns a session's changes against its base commit as a patch
+func (m *manager) ExportPatch(sessionID string, format PatchFormat) (string, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return "", err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	return wrapper.instance.ExportPatch(format)
+}
+
+// ApplyPatch brings a source session's changes to files into a target session's worktree
+func (m *manager) ApplyPatch(targetID, sourceID string, files []string) error {
+	target, err := m.Get(targetID)
+	if err != nil {
+		return err
+	}
+	source, err := m.Get(sourceID)
+	if err != nil {
+		return err
+	}
+	
+	unlock := lockSessions([]*sessionWrapper{target, source})
+	defer unlock()
+	
+	return target.instance.ApplyPatch(source.instance, files)
+}
+
+// sortedSessions returns all sessions in the order they were created
+func (m *manager) sortedSessions() []*sessionWrapper {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	sessions := make([]*sessionWrapper, 0, len(m.sessions))
+	for _, wrapper := range m.sessions {
+		sessions = append(sessions, wrapper)
+	}
+	sortByCreation(sessions)
+	return sessions
+}
+
+// sortByCreation sorts sessions in the order they were created, by title for ties
+func sortByCreation(sessions []*sessionWrapper) {
+	sort.Slice(sessions, func(a, b int) bool {
+		if !sessions[a].instance.CreatedAt.Equal(sessions[b].instance.CreatedAt) {
+			return sessions[a].instance.CreatedAt.Before(sessions[b].instance.CreatedAt)
+		}
+		return sessions[a].instance.Title < sessions[b].instance.Title
+	})
+}
+
+// lockSessions locks each of several sessions once, in the order they were created so that two
+// callers locking overlapping sessions can't deadlock, and returns a function unlocking them
+func lockSessions(sessions []*sessionWrapper) func() {
+	sorted := make([]*sessionWrapper, 0, len(sessions))
+	for _, wrapper := range sessions {
+		if !slices.Contains(sorted, wrapper) {
+			sorted = append(sorted, wrapper)
+		}
+	}
+	sortByCreation(sorted)
+	
+	for _, wrapper := range sorted {
+		wrapper.mu.Lock()
+	}
+	return func() {
+		for _, wrapper := range sorted {
+			wrapper.mu.Unlock()
+		}
+	}
+}
+
+// groupSessions returns the sessions of a fan-out group in the order they were created
+func (m *manager) groupSessions(group string) []*sessionWrapper {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	var siblings []*sessionWrapper
+	for _, wrapper := range m.sessions {
+		wrapper.mu.Lock()
+		inGroup := wrapper.instance.Group == group
+		wrapper.mu.Unlock()
+		if inGroup {
+			siblings = append(siblings, wrapper)
+		}
+	}
+	sortByCreation(siblings)
+	return siblings
+}
+
+// Scrollback returns lines of a session's terminal history
+func (m *manager) Scrollback(sessionID string, fromLine, toLine int) (string, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return "", err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	content, err := wrapper.instance.Scrollback(fromLine, toLine)
+	if err != nil {
+		return "", fmt.Errorf("failed to read scrollback: %w", err)
+	}
+	
+	return content, nil
+}
+
+// Enqueue adds a prompt to a session's queue
+func (m *manager) Enqueue(sessionID, prompt string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.Enqueue(prompt); err != nil {
+		return err
+	}
+	
+	m.publishQueue(wrapper, "")
+	return nil
+}
+
+// Queue returns the prompts waiting to be sent to a session
+func (m *manager) Queue(sessionID string) ([]string, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	return wrapper.instance.QueuedPrompts(), nil
+}
+
+// MoveQueued moves a queued prompt to another position
+func (m *manager) MoveQueued(sessionID string, from, to int, prompt string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.MoveQueued(from, to, prompt); err != nil {
+		return err
+	}
+	
+	m.publishQueue(wrapper, "")
+	return nil
+}
+
+// CancelQueued removes a queued prompt
+func (m *manager) CancelQueued(sessionID string, index int, prompt string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.CancelQueued(index, prompt); err != nil {
+		return err
+	}
+	
+	m.publishQueue(wrapper, "")
+	return nil
+}
+
+// publishQueue publishes the current queue of a session
+func (m *manager) publishQueue(wrapper *sessionWrapper, delivered string) {
+	m.eventBus.Publish(createEvent(wrapper.id, EventQueue, QueueEvent{
+		Prompts:   wrapper.instance.QueuedPrompts(),
+		Delivered: delivered,
+	}))
+}
+
+// AddComment adds a review comment to a session
+func (m *manager) AddComment(sessionID string, comment ReviewComment) (ReviewComment, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	added, err := wrapper.instance.AddComment(comment)
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	
+	m.publishReview(wrapper)
+	return added, nil
+}
+
+// Comments returns the review comments of a session
+func (m *manager) Comments(sessionID string) ([]ReviewComment, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	return wrapper.instance.Comments(), nil
+}
+
+// DeleteComment removes a review comment from a session
+func (m *manager) DeleteComment(sessionID, commentID string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.DeleteComment(commentID); err != nil {
+		return err
+	}
+	
+	m.publishReview(wrapper)
+	return nil
+}
+
+// DiffHistory returns the snapshots of a session's diff over time
+func (m *manager) DiffHistory(sessionID string) ([]DiffSnapshot, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	return wrapper.instance.DiffHistory(), nil
+}
+
+// Review adds comments to a session and queues all of its pending comments as one prompt
+func (m *manager) Review(sessionID string, comments []ReviewComment) (string, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return "", err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	for _, comment := range comments {
+		if _, err := wrapper.instance.AddComment(comment); err != nil {
+			m.publishReview(wrapper)
+			return "", fmt.Errorf("failed to add comment on %s:%d: %w", comment.Path, comment.StartLine, err)
+		}
+	}
+	prompt, err := wrapper.instance.SendReview()
+	m.publishReview(wrapper)
+	if err != nil {
+		return "", err
+	}
+	
+	m.publishQueue(wrapper, "")
+	return prompt, nil
+}
+
+// publishReview publishes the current review comments of a session
+func (m *manager) publishReview(wrapper *sessionWrapper) {
+	m.eventBus.Publish(createEvent(wrapper.id, EventReview, ReviewEvent{
+		Comments: wrapper.instance.Comments(),
+	}))
+}
+
+// OpenWindow starts a companion window in a session
+func (m *manager) OpenWindow(sessionID, name, command string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.OpenWindow(name, command); err != nil {
+		return fmt.Errorf("failed to open window: %w", err)
+	}
+	
+	return nil
+}
+
+// CloseWindow terminates a companion window of a session
+func (m *manager) CloseWindow(sessionID, name string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if err := wrapper.instance.CloseWindow(name); err != nil {
+		return fmt.Errorf("failed to close window: %w", err)
+	}
+	
+	return nil
+}
+
+// SendKeys writes keys to a session's program, or to one of its companion windows
+func (m *manager) SendKeys(sessionID, window, keys string) error {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	if window == "" {
+		err = wrapper.instance.SendKeys(keys)
+	} else {
+		err = wrapper.instance.SendWindowKeys(window, keys)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send keys: %w", err)
+	}
+	
+	return nil
+}
+
+// Capture returns the visible screen of a session's program, or of one of its companion windows
+func (m *manager) Capture(sessionID, window string) (string, error) {
+	wrapper, err := m.Get(sessionID)
+	if err != nil {
+		return "", err
+	}
+	
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	
+	var content string
+	if window == "" {
+		content, err = wrapper.instance.Preview()
+	} else {
+		content, err = wrapper.instance.CaptureWindow(window)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to capture: %w", err)
+	}
+	
+	return content, nil
+}
+
+// hookPublisher returns a callback publishing worktree hook results as events
+func (m *manager) hookPublisher(sessionID string) func(session.HookResult) {
+	return func(result session.HookResult) {
+		event := HookEvent{
+			Hook:       result.Hook,
+			Command:    result.Command,
+			Output:     result.Output,
+			ExitCode:   result.ExitCode,
+			DurationMs: result.Duration.Milliseconds(),
+		}
+		if result.Err != nil {
+			event.Error = result.Err.Error()
+		}
+		m.eventBus.Publish(createEvent(sessionID, EventHook, event))
+	}
+}
+
+// watch starts watching a session, unless it's already watched. The caller holds the session's lock, or hasn't
+// shared the session yet.
+func (m *manager) watch(wrapper *sessionWrapper) {
+	if wrapper.watching {
+		return
+	}
+	wrapper.watching = true
+	wrapper.stopCh = make(chan struct{})
+	
+	m.wg.Add(1)
+	go m.watchSession(wrapper, wrapper.stopCh)
+}
+
+// stopWatching stops the session's watcher, if it has one. The caller holds the session's lock.
+func (w *sessionWrapper) stopWatching() {
+	if w.watching {
+		close(w.stopCh)
+		w.watching = false
+	}
+}
+
+// watchSession monitors a session for changes and publishes events until stopCh is closed
+func (m *manager) watchSession(wrapper *sessionWrapper, stopCh <-chan struct{}) {
 	defer m.wg.Done()
 	
 	ticker := time.NewTicker(500 * time.Millisecond)
@@ -254,7 +968,7 @@ func (m *manager) watchSession(wrapper *sessionWrapper) {
 	
 	for {
 		select {
-		case <-wrapper.stopCh:
+		case <-stopCh:
 			return
 		case <-m.stopCh:
 			return
@@ -264,10 +978,105 @@ func (m *manager) watchSession(wrapper *sessionWrapper) {
 	}
 }
 
+// setConfig switches the manager to a reloaded config, and applies its diff options to the existing sessions
+func (m *manager) setConfig(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	
+	for _, wrapper := range m.sessions {
+		repoCfg, err := cfg.ForRepo(wrapper.instance.Path)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			repoCfg = cfg
+		}
+		wrapper.mu.Lock()
+		wrapper.instance.SetDiffOptions(repoCfg.Diff)
+		wrapper.mu.Unlock()
+	}
+}
+
+// watchIdle enforces the idle policy until the manager stops
+func (m *manager) watchIdle() {
+	defer m.wg.Done()
+	
+	ticker := time.NewTicker(m.idleInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.enforceIdlePolicy(time.Now())
+		}
+	}
+}
+
+// enforceIdlePolicy pauses idle sessions, and the least recently active ones over the running limit
+func (m *manager) enforceIdlePolicy(now time.Time) {
+	m.mu.RLock()
+	policy := session.NewIdlePolicy(m.cfg)
+	wrappers := make([]*sessionWrapper, 0, len(m.sessions))
+	for _, wrapper := range m.sessions {
+		wrappers = append(wrappers, wrapper)
+	}
+	m.mu.RUnlock()
+	if !policy.Enabled() {
+		return
+	}
+	
+	// Pick the sessions to pause from a consistent view of them, without the watchers updating the statuses meanwhile
+	unlock := lockSessions(wrappers)
+	instances := make([]*session.Instance, 0, len(wrappers))
+	for _, wrapper := range wrappers {
+		instances = append(instances, wrapper.instance)
+	}
+	selected := policy.Select(instances, now)
+	unlock()
+	
+	// Pause them one at a time, holding off only the watcher of the session being paused
+	for _, wrapper := range wrappers {
+		reason, ok := selected[wrapper.instance]
+		if !ok {
+			continue
+		}
+		wrapper.mu.Lock()
+		prevStatus := convertStatus(wrapper.instance.Status)
+		// Paused, crashed or killed since it was picked
+		if !wrapper.instance.Started() || wrapper.instance.Paused() || wrapper.instance.Crashed() {
+			wrapper.mu.Unlock()
+			continue
+		}
+		err := policy.Pause(wrapper.instance, reason, now)
+		wrapper.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		m.eventBus.Publish(createEvent(wrapper.id, EventState, StateEvent{
+			Previous: prevStatus,
+			Current:  StatusPaused,
+			Reason:   reason,
+		}))
+	}
+}
+
 // checkSessionUpdates checks for stdout, diff, and state changes
 func (m *manager) checkSessionUpdates(wrapper *sessionWrapper) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
 	instance := wrapper.instance
 	
+	// Check whether the session's terminal died
+	prevStatus := convertStatus(instance.Status)
+	if instance.DetectCrash() {
+		m.eventBus.Publish(createEvent(wrapper.id, EventState, StateEvent{
+			Previous: prevStatus,
+			Current:  StatusCrashed,
+		}))
+	}
+	
 	// Check for stdout updates
 	if content, err := instance.Preview(); err == nil && content != wrapper.lastStdout {
 		wrapper.lastStdout = content
@@ -276,8 +1085,12 @@ func (m *manager) checkSessionUpdates(wrapper *sessionWrapper) {
 		}))
 	}
 	
-	// Check for diff updates
+	// Check for diff updates, which can move or resolve review comments
+	comments := instance.Comments()
 	if err := instance.UpdateDiffStats(); err == nil {
+		if current := instance.Comments(); !reflect.DeepEqual(current, comments) {
+			m.publishReview(wrapper)
+		}
 		currentDiff := convertDiffStats(instance.GetDiffStats())
 		if !diffStatsEqual(currentDiff, wrapper.lastDiff) {
 			wrapper.lastDiff = currentDiff
@@ -288,10 +1101,29 @@ func (m *manager) checkSessionUpdates(wrapper *sessionWrapper) {
 		}
 	}
 	
-	// Handle auto-yes functionality
-	if instance.AutoYes {
-		if updated, hasPrompt := instance.HasUpdated(); hasPrompt && !updated {
+	// Track whether the program is working or waiting for input, and handle auto-yes
+	if instance.Started() && !instance.Paused() && !instance.Crashed() {
+		prevStatus := convertStatus(instance.Status)
+		if updated, hasPrompt := instance.HasUpdated(); updated {
+			instance.SetStatus(session.Running)
+		} else if hasPrompt {
 			instance.TapEnter()
+		} else {
+			instance.SetStatus(session.Ready)
+		}
+		
+		// Send the next queued prompt once the program is waiting for input
+		if prompt, err := instance.DeliverQueued(); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		} else if prompt != "" {
+			m.publishQueue(wrapper, prompt)
+		}
+		
+		if current := convertStatus(instance.Status); current != prevStatus {
+			m.eventBus.Publish(createEvent(wrapper.id, EventState, StateEvent{
+				Previous: prevStatus,
+				Current:  current,
+			}))
 		}
 	}
 }
@@ -306,8 +1138,19 @@ func (m *manager) restoreSession(data SessionData) error {
 		Status:    convertToSessionStatus(data.Status),
 		Program:   data.Program,
 		AutoYes:   data.AutoYes,
+		Backend:   data.Backend,
 		Worktree:  data.Worktree,
 		DiffStats: data.DiffStats,
+
+		HistoryLimit: data.HistoryLimit,
+		Windows:      data.Windows,
+		LastPrompt:   data.LastPrompt,
+		PauseReason:  data.PauseReason,
+		Queue:        data.Queue,
+		Comments:     data.Comments,
+		DiffHistory:  data.DiffHistory,
+		Group:        data.Group,
+		TimeToReady:  data.TimeToReady,
 	}
 	
 	// Parse timestamps
@@ -329,19 +1172,19 @@ func (m *manager) restoreSession(data SessionData) error {
 		return fmt.Errorf("failed to restore instance: %w", err)
 	}
 	
+	instance.OnHook = m.hookPublisher(data.ID)
+	
 	// Create wrapper
 	wrapper := &sessionWrapper{
 		instance: instance,
 		id:       data.ID,
-		stopCh:   make(chan struct{}),
 	}
 	
 	m.sessions[data.ID] = wrapper
 	
 	// Start watching if not paused
 	if data.Status != StatusPaused {
-		m.wg.Add(1)
-		go m.watchSession(wrapper)
+		m.watch(wrapper)
 	}
 	
 	return nil
@@ -352,7 +1195,9 @@ func (m *manager) saveAll() error {
 	sessions := make([]SessionData, 0, len(m.sessions))
 	
 	for _, wrapper := range m.sessions {
+		wrapper.mu.Lock()
 		data := wrapper.instance.ToInstanceData()
+		wrapper.mu.Unlock()
 		sessionData := SessionData{
 			ID:        wrapper.id,
 			Title:     data.Title,
@@ -361,10 +1206,21 @@ func (m *manager) saveAll() error {
 			Status:    convertStatus(data.Status),
 			Program:   data.Program,
 			AutoYes:   data.AutoYes,
+			Backend:   data.Backend,
 			CreatedAt: data.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			UpdatedAt: data.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			Worktree:  data.Worktree,
 			DiffStats: data.DiffStats,
+
+			HistoryLimit: data.HistoryLimit,
+			Windows:      data.Windows,
+			LastPrompt:   data.LastPrompt,
+			PauseReason:  data.PauseReason,
+			Queue:        data.Queue,
+			Comments:     data.Comments,
+			DiffHistory:  data.DiffHistory,
+			Group:        data.Group,
+			TimeToReady:  data.TimeToReady,
 		}
 		sessions = append(sessions, sessionData)
 	}
@@ -383,10 +1239,28 @@ func (m *manager) wrapperToSessionInfo(wrapper *sessionWrapper) SessionInfo {
 		Status:    convertStatus(data.Status),
 		Program:   data.Program,
 		AutoYes:   data.AutoYes,
+		Backend:   data.Backend,
 		CreatedAt: data.CreatedAt,
 		UpdatedAt: data.UpdatedAt,
 		DiffStats: convertDiffStats(wrapper.instance.GetDiffStats()),
+		Windows:   windowNames(data.Windows),
+
+		PauseReason: data.PauseReason,
+		Queue:       data.Queue,
+		Group:       data.Group,
+	}
+}
+
+// windowNames returns the names of a session's companion windows
+func windowNames(windows []session.Window) []string {
+	if len(windows) == 0 {
+		return nil
+	}
+	names := make([]string, len(windows))
+	for i, w := range windows {
+		names[i] = w.Name
 	}
+	return names
 }
 
 // diffStatsEqual compares two DiffStats for equality
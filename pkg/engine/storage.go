@@ -24,10 +24,21 @@ type SessionData struct {
 	Status    Status                   `json:"status"`
 	Program   string                   `json:"program"`
 	AutoYes   bool                     `json:"auto_yes"`
+	Backend   string                   `json:"backend,omitempty"`
 	CreatedAt string                   `json:"created_at"` // ISO 8601 format
 	UpdatedAt string                   `json:"updated_at"` // ISO 8601 format
 	Worktree  session.GitWorktreeData  `json:"worktree"`
 	DiffStats session.DiffStatsData    `json:"diff_stats"`
+
+	HistoryLimit int              `json:"history_limit,omitempty"`
+	Windows      []session.Window `json:"windows,omitempty"`
+	LastPrompt   string           `json:"last_prompt,omitempty"`
+	PauseReason  string           `json:"pause_reason,omitempty"`
+	Queue        []string         `json:"queue,omitempty"`
+	Comments     []ReviewComment  `json:"comments,omitempty"`
+	DiffHistory  []DiffSnapshot   `json:"diff_history,omitempty"`
+	Group        string           `json:"group,omitempty"`
+	TimeToReady  time.Duration    `json:"time_to_ready,omitempty"`
 }
 
 // fileStorage implements StorageInterface using the existing config/state system
@@ -66,10 +77,21 @@ func (fs *fileStorage) LoadSessions() ([]SessionData, error) {
 			Status:    convertStatus(data.Status),
 			Program:   data.Program,
 			AutoYes:   data.AutoYes,
+			Backend:   data.Backend,
 			CreatedAt: data.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			UpdatedAt: data.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			Worktree:  data.Worktree,
 			DiffStats: data.DiffStats,
+
+			HistoryLimit: data.HistoryLimit,
+			Windows:      data.Windows,
+			LastPrompt:   data.LastPrompt,
+			PauseReason:  data.PauseReason,
+			Queue:        data.Queue,
+			Comments:     data.Comments,
+			DiffHistory:  data.DiffHistory,
+			Group:        data.Group,
+			TimeToReady:  data.TimeToReady,
 		}
 	}
 	
@@ -89,8 +111,19 @@ func (fs *fileStorage) SaveSessions(sessions []SessionData) error {
 			Status:    convertToSessionStatus(sessionData.Status),
 			Program:   sessionData.Program,
 			AutoYes:   sessionData.AutoYes,
+			Backend:   sessionData.Backend,
 			Worktree:  sessionData.Worktree,
 			DiffStats: sessionData.DiffStats,
+
+			HistoryLimit: sessionData.HistoryLimit,
+			Windows:      sessionData.Windows,
+			LastPrompt:   sessionData.LastPrompt,
+			PauseReason:  sessionData.PauseReason,
+			Queue:        sessionData.Queue,
+			Comments:     sessionData.Comments,
+			DiffHistory:  sessionData.DiffHistory,
+			Group:        sessionData.Group,
+			TimeToReady:  sessionData.TimeToReady,
 		}
 		
 		// Parse timestamps
@@ -120,8 +153,7 @@ func (fs *fileStorage) SaveSessions(sessions []SessionData) error {
 
 // LoadConfig loads the application configuration
 func (fs *fileStorage) LoadConfig() (*config.Config, error) {
-	cfg := config.LoadConfig()
-	return cfg, nil
+	return config.ReadConfig()
 }
 
 // SaveConfig saves the application configuration
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"claude-squad/config"
 	"claude-squad/session"
 	"claude-squad/session/git"
 	"time"
@@ -8,11 +9,27 @@ import (
 
 // SessionOpts contains options for creating a new session
 type SessionOpts struct {
-	Title   string
-	Path    string
+	Title string
+	Path  string
+	// Program is the program to run. Empty uses the configured default program.
 	Program string
+	// AutoYes accepts all prompts. It's also enabled if the config enables it.
 	AutoYes bool
 	Prompt  string
+	// Backend is the terminal backend ("tmux" or "headless"). Empty uses the configured default.
+	Backend string
+	// Windows are companion windows (ex. a shell or a test watcher) started next to the program.
+	Windows []WindowOpts
+	// BaseRef is the branch or commit the session's branch starts from. Empty uses the configured base branch.
+	BaseRef string
+}
+
+// WindowOpts describes a companion window of a session
+type WindowOpts struct {
+	// Name identifies the window within the session (letters, digits, '-' and '_').
+	Name string `json:"name"`
+	// Command runs in the session's worktree. Empty runs the user's shell.
+	Command string `json:"command,omitempty"`
 }
 
 // SessionInfo contains information about a session returned by the Engine API
@@ -24,9 +41,31 @@ type SessionInfo struct {
 	Status    Status       `json:"status"`
 	Program   string       `json:"program"`
 	AutoYes   bool         `json:"auto_yes"`
+	Backend   string       `json:"backend"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
 	DiffStats *DiffStats   `json:"diff_stats,omitempty"`
+	Windows   []string     `json:"windows,omitempty"`
+	// PauseReason is set if the session was paused automatically: "idle" or "max_running".
+	PauseReason string `json:"pause_reason,omitempty"`
+	// Queue holds the prompts waiting to be sent, next one first.
+	Queue []string `json:"queue,omitempty"`
+	// Group is shared by the sessions started together by FanOut.
+	Group string `json:"group,omitempty"`
+}
+
+// Comparison describes one session of a fan-out, to compare it with its siblings
+type Comparison struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Program string `json:"program"`
+	Status  Status `json:"status"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	// Files are the files the session changed, relative to the repository root.
+	Files []string `json:"files,omitempty"`
+	// TimeToReadyMs is how long the program took to become ready after the prompt. Zero until it has.
+	TimeToReadyMs int64 `json:"time_to_ready_ms,omitempty"`
 }
 
 // Status represents the status of a session
@@ -37,6 +76,8 @@ const (
 	StatusReady   Status = "ready"
 	StatusLoading Status = "loading"
 	StatusPaused  Status = "paused"
+	// StatusCrashed means the session's terminal died, ex. after a reboot. Its worktree is kept; see Engine.Revive.
+	StatusCrashed Status = "crashed"
 )
 
 // DiffStats contains git diff statistics
@@ -44,8 +85,59 @@ type DiffStats struct {
 	Added   int    `json:"added"`
 	Removed int    `json:"removed"`
 	Content string `json:"content"`
+	// Files is the diff parsed per file, with each file's hunks and lines
+	Files []FileDiff `json:"files,omitempty"`
 }
 
+// ReviewComment is a comment on lines a session changed; see Engine.Review
+type ReviewComment = session.ReviewComment
+
+// DiffSnapshot is the size of a session's diff at some point in time; see Engine.DiffHistory
+type DiffSnapshot = session.DiffSnapshot
+
+// Conflict describes what a session changed that another session of the same repository changed too
+type Conflict struct {
+	// ID and Title are the other session's.
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Files are the files both sessions changed, in path order.
+	Files []Overlap `json:"files"`
+}
+
+// Overlap is a file two sessions changed, with the lines of it both changed
+type Overlap = session.Overlap
+
+// LineRange is a range of lines of a file, both ends included
+type LineRange = session.LineRange
+
+// MergeResult is the outcome of simulating the merge of two sessions; see Engine.SimulateMerge
+type MergeResult = git.MergeResult
+
+// PatchFormat is the format of a patch exported by Engine.ExportPatch
+type PatchFormat = git.PatchFormat
+
+const (
+	// PatchFormatDiff is one unified diff of all of a session's changes
+	PatchFormatDiff = git.PatchFormatDiff
+	// PatchFormatMail is git format-patch output, with a mail per commit
+	PatchFormatMail = git.PatchFormatMail
+)
+
+// DiffOptions are the filters and size limits diffs are trimmed with; see config.DiffOptions
+type DiffOptions = config.DiffOptions
+
+// FileDiff is the diff of one file: its status, line counts and hunks
+type FileDiff = git.FileDiff
+
+// FileStatus is how a file changed: added, modified, deleted, renamed or binary
+type FileStatus = git.FileStatus
+
+// Hunk is a run of changed lines in a file and the context around them
+type Hunk = git.Hunk
+
+// DiffLine is a line of a hunk
+type DiffLine = git.DiffLine
+
 // EventKind represents the type of event
 type EventKind string
 
@@ -54,6 +146,11 @@ const (
 	EventStderr EventKind = "stderr"
 	EventDiff   EventKind = "diff"
 	EventState  EventKind = "state"
+	EventHook   EventKind = "hook"
+	EventQueue  EventKind = "queue"
+	EventReview EventKind = "review"
+	// EventConfigChanged is published without a session ID when the configuration changes or an edit is rejected
+	EventConfigChanged EventKind = "config_changed"
 )
 
 // Event represents a session event
@@ -68,6 +165,8 @@ type Event struct {
 type StateEvent struct {
 	Previous Status `json:"previous"`
 	Current  Status `json:"current"`
+	// Reason is set when the engine paused the session on its own: "idle" or "max_running".
+	Reason string `json:"reason,omitempty"`
 }
 
 // DiffEvent represents a diff change event
@@ -76,6 +175,36 @@ type DiffEvent struct {
 	HasChanges bool       `json:"has_changes"`
 }
 
+// HookEvent represents the result of a worktree hook command (see config.WorktreeSetup)
+type HookEvent struct {
+	Hook       string `json:"hook"` // "post_setup" or "pre_cleanup"
+	Command    string `json:"command"`
+	Output     string `json:"output"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// QueueEvent represents a change to a session's prompt queue. Delivered is set when the change is the engine sending
+// the next prompt.
+type QueueEvent struct {
+	Prompts   []string `json:"prompts"`
+	Delivered string   `json:"delivered,omitempty"`
+}
+
+// ReviewEvent represents a change to a session's review comments: one added, deleted, sent, moved along with its
+// lines or resolved
+type ReviewEvent struct {
+	Comments []ReviewComment `json:"comments"`
+}
+
+// ConfigChangedEvent represents a configuration reload. If Error is set, the edited config was invalid and the
+// previous config stays in effect.
+type ConfigChangedEvent struct {
+	Keys  []string `json:"keys,omitempty"` // Dotted keys that changed, ex. "worktree_setup.copy"
+	Error string   `json:"error,omitempty"`
+}
+
 // StdoutEvent represents stdout/stderr output
 type StdoutEvent struct {
 	Content string `json:"content"`
@@ -92,6 +221,8 @@ func convertStatus(s session.Status) Status {
 		return StatusLoading
 	case session.Paused:
 		return StatusPaused
+	case session.Crashed:
+		return StatusCrashed
 	default:
 		return StatusReady
 	}
@@ -108,6 +239,8 @@ func convertToSessionStatus(s Status) session.Status {
 		return session.Loading
 	case StatusPaused:
 		return session.Paused
+	case StatusCrashed:
+		return session.Crashed
 	default:
 		return session.Ready
 	}
@@ -122,5 +255,6 @@ func convertDiffStats(stats *git.DiffStats) *DiffStats {
 		Added:   stats.Added,
 		Removed: stats.Removed,
 		Content: stats.Content,
+		Files:   stats.Files,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,51 @@
+package agent
+
+import "strings"
+
+const ProgramClaude = "claude"
+
+const ProgramAider = "aider"
+const ProgramGemini = "gemini"
+
+// TrustScreen describes the "do you trust the files in this folder" screen some programs show on startup.
+type TrustScreen struct {
+	// SearchString is the text that identifies the screen.
+	SearchString string
+	// Response is the keystrokes that dismiss the screen.
+	Response []byte
+	// Iterations is the number of times to poll for the screen. Each poll waits 200ms.
+	Iterations int
+}
+
+// GetTrustScreen returns the trust screen for the program, or nil if the program doesn't show one.
+func GetTrustScreen(program string) *TrustScreen {
+	switch {
+	case program == ProgramClaude:
+		return &TrustScreen{
+			SearchString: "Do you trust the files in this folder?",
+			Response:     []byte{0x0D},
+			Iterations:   5,
+		}
+	case strings.HasPrefix(program, ProgramAider), strings.HasPrefix(program, ProgramGemini):
+		return &TrustScreen{
+			SearchString: "Open documentation url for more info",
+			Response:     []byte{0x44, 0x0D},
+			Iterations:   10, // Aider takes longer to start :/
+		}
+	}
+	return nil
+}
+
+// HasPrompt returns true if the screen content shows a permission prompt for the program. Only claude, aider
+// and gemini are supported.
+func HasPrompt(program string, content string) bool {
+	switch {
+	case program == ProgramClaude:
+		return strings.Contains(content, "No, and tell Claude what to do differently")
+	case strings.HasPrefix(program, ProgramAider):
+		return strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
+	case strings.HasPrefix(program, ProgramGemini):
+		return strings.Contains(content, "Yes, allow once")
+	}
+	return false
+}
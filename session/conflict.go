@@ -0,0 +1,197 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"fmt"
+	"sort"
+)
+
+// LineRange is a range of lines of a file, both ends included.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Overlap is a file that two instances of the same repository both changed.
+type Overlap struct {
+	// Path is the file's path in the base commit, relative to the repository root.
+	Path string `json:"path"`
+	// Lines are the ranges of lines of the base version of the file that both instances changed, or changed right next
+	// to each other. Those hunks are likely to conflict when the instances are merged; changes to different parts of
+	// a file usually merge cleanly. Binary files and files too large to diff have no lines to compare, and neither do
+	// instances whose branches started from different commits, since their line numbers don't match.
+	Lines []LineRange `json:"lines,omitempty"`
+}
+
+// Conflict is what an instance changed that another instance of the same repository changed too.
+type Conflict struct {
+	// Other is the other instance.
+	Other *Instance
+	// Files are the files both changed, in path order.
+	Files []Overlap
+}
+
+// Hunks returns the number of overlapping line ranges in all files.
+func (c Conflict) Hunks() int {
+	hunks := 0
+	for _, file := range c.Files {
+		hunks += len(file.Lines)
+	}
+	return hunks
+}
+
+// FindConflicts compares the last diffs of the started instances that share a repository, and returns the files each
+// instance changed that others changed too. Instances that don't overlap with any other are left out. The conflicts
+// of an instance are in the order of instances.
+func FindConflicts(instances []*Instance) map[*Instance][]Conflict {
+	type changes struct {
+		instance *Instance
+		repo     string
+		base     string
+		files    map[string][]LineRange
+	}
+	var all []changes
+	for _, instance := range instances {
+		if !instance.started || instance.gitWorktree == nil {
+			continue
+		}
+		stats := instance.GetDiffStats()
+		if stats == nil || stats.Error != nil || len(stats.Files) == 0 {
+			continue
+		}
+		files := make(map[string][]LineRange, len(stats.Files))
+		for _, file := range stats.Files {
+			path := file.Path
+			if file.Status == git.FileRenamed {
+				path = file.OldPath
+			}
+			files[path] = changedLines(file)
+		}
+		all = append(all, changes{
+			instance: instance,
+			repo:     instance.gitWorktree.GetRepoPath(),
+			base:     instance.gitWorktree.GetBaseCommitSHA(),
+			files:    files,
+		})
+	}
+
+	conflicts := make(map[*Instance][]Conflict)
+	for _, a := range all {
+		for _, b := range all {
+			if a.instance == b.instance || a.repo != b.repo {
+				continue
+			}
+			var files []Overlap
+			for path, aLines := range a.files {
+				bLines, ok := b.files[path]
+				if !ok {
+					continue
+				}
+				overlap := Overlap{Path: path}
+				if a.base == b.base {
+					overlap.Lines = overlappingLines(aLines, bLines)
+				}
+				files = append(files, overlap)
+			}
+			if len(files) == 0 {
+				continue
+			}
+			sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+			conflicts[a.instance] = append(conflicts[a.instance], Conflict{Other: b.instance, Files: files})
+		}
+	}
+	return conflicts
+}
+
+// changedLines returns the ranges of lines of the base version of a file that its hunks change. Lines added in place of
+// removed lines change those, and lines added between two lines of the base change both of them, since git can't
+// merge changes next to each other either.
+func changedLines(file git.FileDiff) []LineRange {
+	var ranges []LineRange
+	add := func(start, end int) {
+		start = max(start, 1)
+		end = max(end, start)
+		if n := len(ranges); n > 0 && start <= ranges[n-1].End+1 {
+			ranges[n-1].End = max(ranges[n-1].End, end)
+			return
+		}
+		ranges = append(ranges, LineRange{Start: start, End: end})
+	}
+	for _, hunk := range file.Hunks {
+		// A hunk that only adds lines starts after its old start line.
+		old := hunk.OldStart
+		if hunk.OldLines == 0 {
+			old++
+		}
+		replacing := false
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case git.LineContext:
+				old++
+				replacing = false
+			case git.LineRemoved:
+				add(old, old)
+				old++
+				replacing = true
+			case git.LineAdded:
+				if !replacing {
+					add(old-1, old)
+				}
+			}
+		}
+	}
+	return ranges
+}
+
+// overlappingLines returns the ranges of a and b that overlap, both sorted, merged into ranges covering both.
+func overlappingLines(a, b []LineRange) []LineRange {
+	var overlaps []LineRange
+	for _, x := range a {
+		for _, y := range b {
+			if x.Start > y.End || y.Start > x.End {
+				continue
+			}
+			overlap := LineRange{Start: min(x.Start, y.Start), End: max(x.End, y.End)}
+			if n := len(overlaps); n > 0 && overlap.Start <= overlaps[n-1].End {
+				overlaps[n-1].End = max(overlaps[n-1].End, overlap.End)
+				continue
+			}
+			overlaps = append(overlaps, overlap)
+		}
+	}
+	return overlaps
+}
+
+// SimulateMerge merges the changes of two instances of the same repository in memory and reports the files that
+// conflict. Neither worktree nor branch is touched: a running instance's uncommitted changes are merged from a
+// snapshot of its worktree, and a paused one's from its branch, where pausing committed them.
+func SimulateMerge(a, b *Instance) (*git.MergeResult, error) {
+	if a == b {
+		return nil, fmt.Errorf("cannot merge an instance with itself")
+	}
+	if !a.started || !b.started {
+		return nil, fmt.Errorf("instance is not started")
+	}
+	repo := a.gitWorktree.GetRepoPath()
+	if b.gitWorktree.GetRepoPath() != repo {
+		return nil, fmt.Errorf("%s and %s are not in the same repository", a.Title, b.Title)
+	}
+	ours, err := a.changesCommit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.Title, err)
+	}
+	theirs, err := b.changesCommit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Title, err)
+	}
+	return git.MergeTree(repo, ours, theirs)
+}
+
+// changesCommit returns a commit with all of the instance's changes: a snapshot of its worktree, or its branch if it's
+// paused, since pausing committed them.
+func (i *Instance) changesCommit() (string, error) {
+	if i.Status == Paused {
+		return "refs/heads/" + i.gitWorktree.GetBranchName(), nil
+	}
+	return i.gitWorktree.Snapshot()
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindConflicts(t *testing.T) {
+	instance := func(title, repo, diff string) *Instance {
+		return &Instance{
+			Title:       title,
+			started:     true,
+			gitWorktree: git.NewGitWorktreeFromStorage(repo, "/worktrees/"+title, title, title, "base"),
+			diffStats:   git.NewDiffStats(diff),
+		}
+	}
+	// a changes line 3 of main.go and adds a line after line 10, and renames util.go.
+	a := instance("a", "/repo", `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -2,3 +2,3 @@
+ package main
+-var x = 1
++var x = 2
+ 
+@@ -10,0 +11 @@ func main() {
++	run()
+diff --git a/util.go b/helpers.go
+similarity index 100%
+rename from util.go
+rename to helpers.go
+`)
+	// b changes line 11 of main.go, right after the line a added, and changes util.go.
+	b := instance("b", "/repo", `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -11 +11 @@
+-	old()
++	new()
+diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -20 +20 @@
+-x
++y
+`)
+	// c only changes a file the others don't, and d is in another repository.
+	c := instance("c", "/repo", `diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-a
++b
+`)
+	d := instance("d", "/other", `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3 +3 @@
+-var x = 1
++var x = 3
+`)
+
+	conflicts := FindConflicts([]*Instance{a, b, c, d, {Title: "not started"}})
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, []Conflict{{Other: b, Files: []Overlap{
+		{Path: "main.go", Lines: []LineRange{{Start: 10, End: 11}}},
+		{Path: "util.go"},
+	}}}, conflicts[a])
+	assert.Equal(t, []Conflict{{Other: a, Files: []Overlap{
+		{Path: "main.go", Lines: []LineRange{{Start: 10, End: 11}}},
+		{Path: "util.go"},
+	}}}, conflicts[b])
+	assert.Equal(t, 1, conflicts[a][0].Hunks())
+
+	// The lines of a file differ between base commits, so only the files are compared.
+	e := instance("e", "/repo", `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -11 +11 @@
+-	old()
++	other()
+`)
+	e.gitWorktree = git.NewGitWorktreeFromStorage("/repo", "/worktrees/e", "e", "e", "newer")
+	conflicts = FindConflicts([]*Instance{a, e})
+	assert.Equal(t, []Conflict{{Other: e, Files: []Overlap{{Path: "main.go"}}}}, conflicts[a])
+}
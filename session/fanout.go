@@ -0,0 +1,71 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NewFanOut creates an instance for each of programs, to compare how they handle the same task. opts.Title names the
+// group: each instance is titled after it and its program, ex. "fix-login-aider". The instances share their Group
+// and start from the same commit, opts.BaseRef resolved once, so later commits to the base branch don't skew the
+// comparison. The instances aren't started.
+func NewFanOut(opts InstanceOptions, programs []string) ([]*Instance, error) {
+	if opts.Title == "" {
+		return nil, fmt.Errorf("fan-out needs a title")
+	}
+	if len(programs) == 0 {
+		return nil, fmt.Errorf("fan-out needs at least one program")
+	}
+	for _, program := range programs {
+		if strings.TrimSpace(program) == "" {
+			return nil, fmt.Errorf("fan-out programs cannot be empty")
+		}
+	}
+
+	absPath, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	repoPath, err := git.RepoRoot(absPath)
+	if err != nil {
+		return nil, err
+	}
+	baseCommit, err := git.ResolveBase(repoPath, opts.BaseRef)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := FanOutTitles(opts.Title, programs)
+	instances := make([]*Instance, 0, len(programs))
+	for i, program := range programs {
+		siblingOpts := opts
+		siblingOpts.Title = titles[i]
+		siblingOpts.Program = program
+		siblingOpts.BaseRef = baseCommit
+		instance, err := NewInstance(siblingOpts)
+		if err != nil {
+			return nil, err
+		}
+		instance.Group = opts.Title
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// FanOutTitles returns the titles of the instances a fan-out named title creates for programs: the title followed by
+// the name of the program's executable, numbered if a program appears more than once. Programs must not be empty.
+func FanOutTitles(title string, programs []string) []string {
+	titles := make([]string, len(programs))
+	seen := make(map[string]int)
+	for i, program := range programs {
+		name := filepath.Base(strings.Fields(program)[0])
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, seen[name])
+		}
+		titles[i] = fmt.Sprintf("%s-%s", title, name)
+	}
+	return titles
+}
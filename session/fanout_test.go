@@ -0,0 +1,66 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutTitles(t *testing.T) {
+	titles := FanOutTitles("fix-login", []string{"claude", "aider --model sonnet", "/usr/local/bin/codex", "aider"})
+	assert.Equal(t, []string{"fix-login-claude", "fix-login-aider", "fix-login-codex", "fix-login-aider-2"}, titles)
+}
+
+func TestNewFanOut(t *testing.T) {
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	head, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+
+	instances, err := NewFanOut(InstanceOptions{Title: "task", Path: repo}, []string{"claude", "aider"})
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	for _, instance := range instances {
+		assert.Equal(t, "task", instance.Group)
+		// Every sibling starts from the commit the base resolved to when the fan-out was created.
+		assert.Equal(t, strings.TrimSpace(string(head)), instance.baseRef)
+	}
+	assert.Equal(t, "claude", instances[0].Program)
+	assert.Equal(t, "aider", instances[1].Program)
+
+	_, err = NewFanOut(InstanceOptions{Title: "task", Path: repo}, nil)
+	assert.Error(t, err)
+	_, err = NewFanOut(InstanceOptions{Title: "task", Path: repo}, []string{"claude", " "})
+	assert.Error(t, err)
+	_, err = NewFanOut(InstanceOptions{Title: "task", Path: repo, BaseRef: "missing"}, []string{"claude"})
+	assert.Error(t, err)
+}
+
+func TestTimeToReady(t *testing.T) {
+	instance := &Instance{Title: "timing", Status: Ready}
+	// Becoming ready before any prompt doesn't count.
+	instance.SetStatus(Running)
+	instance.SetStatus(Ready)
+	assert.Zero(t, instance.TimeToReady)
+
+	instance.firstPromptAt = time.Now().Add(-time.Minute)
+	instance.SetStatus(Running)
+	instance.SetStatus(Ready)
+	assert.GreaterOrEqual(t, instance.TimeToReady, time.Minute)
+
+	// Only the first time counts.
+	first := instance.TimeToReady
+	instance.SetStatus(Running)
+	instance.SetStatus(Ready)
+	assert.Equal(t, first, instance.TimeToReady)
+}
@@ -1,6 +1,8 @@
 package git
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -12,40 +14,234 @@ type DiffStats struct {
 	Added int
 	// Removed is the number of removed lines
 	Removed int
+	// Files are the files in the diff, parsed from Content
+	Files []FileDiff
 	// Error holds any error that occurred during diff computation
 	// This allows propagating setup errors (like missing base commit) without breaking the flow
 	Error error
 }
 
+// FileStatus is how a file changed in a diff.
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "added"
+	FileModified FileStatus = "modified"
+	FileDeleted  FileStatus = "deleted"
+	FileRenamed  FileStatus = "renamed"
+	// FileBinary is the status of a binary file changed in place. Binary files that were added, deleted or renamed
+	// keep those statuses, with Binary set.
+	FileBinary FileStatus = "binary"
+)
+
+// LineKind is the kind of a line in a hunk.
+type LineKind string
+
+const (
+	LineContext LineKind = "context"
+	LineAdded   LineKind = "added"
+	LineRemoved LineKind = "removed"
+)
+
+// FileDiff is the diff of one file.
+type FileDiff struct {
+	// Path is the path of the file, relative to the repository root. For a deleted file, it's the path it had.
+	Path string `json:"path"`
+	// OldPath is the path the file had before it was renamed. It's empty unless Status is FileRenamed.
+	OldPath string     `json:"old_path,omitempty"`
+	Status  FileStatus `json:"status"`
+	// Binary is true for binary files, which have no hunks or line counts.
+	Binary bool `json:"binary,omitempty"`
+	// Truncated is true for a file whose diff was too large to show. It has line counts, but no hunks.
+	Truncated bool   `json:"truncated,omitempty"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Hunks     []Hunk `json:"hunks,omitempty"`
+}
+
+// Hunk is a run of changed lines and the context around them.
+type Hunk struct {
+	// Header is the hunk's "@@ -1,3 +1,4 @@" line, including the section heading git adds after it.
+	Header   string     `json:"header"`
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffLine is a line of a hunk.
+type DiffLine struct {
+	Kind LineKind `json:"kind"`
+	// Content is the line without its leading '+', '-' or ' '.
+	Content string `json:"content"`
+	// OldLine and NewLine are the line's numbers in the old and new file. Added lines have no OldLine, and removed
+	// lines have no NewLine.
+	OldLine int `json:"old_line,omitempty"`
+	NewLine int `json:"new_line,omitempty"`
+}
+
 func (d *DiffStats) IsEmpty() bool {
 	return d.Added == 0 && d.Removed == 0 && d.Content == ""
 }
 
-// Diff returns the git diff between the worktree and the base branch along with statistics
-func (g *GitWorktree) Diff() *DiffStats {
-	stats := &DiffStats{}
+// Paths returns the paths of the files the diff touches, in the order they appear.
+func (d *DiffStats) Paths() []string {
+	paths := make([]string, len(d.Files))
+	for i, file := range d.Files {
+		paths[i] = file.Path
+	}
+	return paths
+}
 
-	// -N stages untracked files (intent to add), including them in the diff
-	_, err := g.runGitCommand(g.worktreePath, "add", "-N", ".")
-	if err != nil {
-		stats.Error = err
-		return stats
+// NewDiffStats parses a diff into files and counts its lines.
+func NewDiffStats(content string) *DiffStats {
+	stats := &DiffStats{Content: content, Files: ParseDiff(content)}
+	for _, file := range stats.Files {
+		stats.Added += file.Added
+		stats.Removed += file.Removed
 	}
+	return stats
+}
 
-	content, err := g.runGitCommand(g.worktreePath, "--no-pager", "diff", g.GetBaseCommitSHA())
-	if err != nil {
-		stats.Error = err
-		return stats
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// truncatedFormat is the line that takes the place of the hunks of a file too large to show, with its line counts.
+const truncatedFormat = "Diff truncated: %d lines added, %d removed\n"
+
+var truncatedRegex = regexp.MustCompile(`^Diff truncated: (\d+) lines added, (\d+) removed$`)
+
+// ParseDiff parses the output of git diff into files. Lines are classified by the position in their hunk, so
+// content that looks like a header, ex. an added line "++x" that shows up as "+++x", is counted like any other line.
+func ParseDiff(content string) []FileDiff {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+	// The lines of the current hunk that haven't been read yet, and the numbers of the next ones.
+	var oldLeft, newLeft, oldLine, newLine int
+
+	for _, line := range strings.Split(content, "\n") {
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				hunk.Lines = append(hunk.Lines, DiffLine{Kind: LineAdded, Content: line[1:], NewLine: newLine})
+				file.Added++
+				newLine++
+				newLeft--
+				continue
+			case strings.HasPrefix(line, "-"):
+				hunk.Lines = append(hunk.Lines, DiffLine{Kind: LineRemoved, Content: line[1:], OldLine: oldLine})
+				file.Removed++
+				oldLine++
+				oldLeft--
+				continue
+			case strings.HasPrefix(line, " ") || line == "":
+				// Some tools strip the space off blank context lines.
+				hunk.Lines = append(hunk.Lines, DiffLine{
+					Kind: LineContext, Content: strings.TrimPrefix(line, " "), OldLine: oldLine, NewLine: newLine,
+				})
+				oldLine++
+				newLine++
+				oldLeft--
+				newLeft--
+				continue
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+				continue
+			}
+			// The hunk is shorter than its header says. Treat the line as a header.
+			hunk = nil
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, FileDiff{Status: FileModified})
+			file = &files[len(files)-1]
+			hunk = nil
+			file.OldPath, file.Path = parseGitHeader(strings.TrimPrefix(line, "diff --git "))
+		case file == nil:
+			// Skip anything before the first file.
+		case strings.HasPrefix(line, "@@ "):
+			match := hunkHeaderRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			file.Hunks = append(file.Hunks, Hunk{
+				Header:   line,
+				OldStart: atoi(match[1], 0),
+				OldLines: atoi(match[2], 1),
+				NewStart: atoi(match[3], 0),
+				NewLines: atoi(match[4], 1),
+			})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+		case strings.HasPrefix(line, "new file mode"):
+			file.Status = FileAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.Status = FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = FileRenamed
+			file.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.Status = FileRenamed
+			file.Path = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.Binary = true
+		case strings.HasPrefix(line, "Diff truncated: "):
+			if match := truncatedRegex.FindStringSubmatch(line); match != nil {
+				file.Truncated = true
+				file.Added += atoi(match[1], 0)
+				file.Removed += atoi(match[2], 0)
+			}
+		case strings.HasPrefix(line, "+++ "):
+			// The header is ambiguous if a path contains " b/", so prefer this one.
+			if path := strings.TrimPrefix(line, "+++ "); path != "/dev/null" {
+				file.Path = strings.TrimPrefix(unquotePath(path), "b/")
+			}
+		}
 	}
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			stats.Added++
-		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-			stats.Removed++
+
+	for i := range files {
+		if files[i].Status != FileRenamed {
+			files[i].OldPath = ""
+		}
+		if files[i].Binary && files[i].Status == FileModified {
+			files[i].Status = FileBinary
 		}
 	}
-	stats.Content = content
+	return files
+}
 
-	return stats
+// parseGitHeader returns the old and new paths of a "diff --git a/<old> b/<new>" header, without the "diff --git ".
+func parseGitHeader(header string) (oldPath, newPath string) {
+	if strings.HasPrefix(header, `"`) {
+		// Git quotes paths with unusual characters.
+		if quoted, err := strconv.QuotedPrefix(header); err == nil {
+			oldPath = unquotePath(quoted)
+			newPath = unquotePath(strings.TrimSpace(header[len(quoted):]))
+		}
+	} else if i := strings.LastIndex(header, " b/"); i >= 0 {
+		oldPath, newPath = header[:i], unquotePath(header[i+1:])
+	}
+	return strings.TrimPrefix(oldPath, "a/"), strings.TrimPrefix(newPath, "b/")
+}
+
+// unquotePath undoes git's quoting of a path, if it's quoted.
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+// atoi parses a number from a hunk header, or returns fallback if it's missing.
+func atoi(s string, fallback int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
 }
@@ -0,0 +1,846 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	utildiff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines around each hunk, as git diff shows by default.
+	diffContextLines = 3
+	// binaryCheckSize is how much of a file is checked for NUL bytes to tell whether it's binary, like git does.
+	binaryCheckSize = 8000
+	// funcNameSize is the longest a function name in a hunk header gets, like git's.
+	funcNameSize = 80
+)
+
+// errNeedsGit is returned by nativeDiff for a worktree whose diff it can't compute the way git does, so git is run
+// instead.
+var errNeedsGit = errors.New("the diff needs git")
+
+// diffAttributes are the attributes that change how git reads files or prints their diff. nativeDiff applies none of
+// them. The binary macro sets diff and text.
+var diffAttributes = []string{"filter", "eol", "text", "crlf", "diff", "binary", "ident", "working-tree-encoding"}
+
+// diffFile is a side of a file's change: the file in the base commit, or in the worktree.
+type diffFile struct {
+	path string
+	hash plumbing.Hash
+	mode filemode.FileMode
+	// content is nil until it's needed.
+	content []byte
+}
+
+// fileChange is a file that differs between the base commit and the worktree. from is nil for an added file, and to
+// for a deleted one.
+type fileChange struct {
+	from, to *diffFile
+}
+
+func (c fileChange) path() string {
+	if c.to != nil {
+		return c.to.path
+	}
+	return c.from.path
+}
+
+// nativeDiff computes the diff of the worktree against the base commit in process with go-git, in the format execDiff
+// prints, limited to paths unless they're nil, and ignoring whitespace like git diff -w if ignoreWhitespace is set.
+// Like execDiff, it includes untracked files that aren't ignored and leaves the index alone. Submodules are left out.
+// It only finds exact renames, and applies no attributes or line ending conversion, so it returns errNeedsGit when
+// git could find a rename of an edited file or when attributes or core.autocrlf would change the diff.
+func (g *GitWorktree) nativeDiff(paths []string, ignoreWhitespace bool) (string, error) {
+	repo, err := git.PlainOpenWithOptions(g.worktreePath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	if converts, err := g.convertsFiles(repo); err != nil {
+		return "", err
+	} else if converts {
+		return "", errNeedsGit
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(g.GetBaseCommitSHA()))
+	if err != nil {
+		return "", fmt.Errorf("failed to read base commit: %w", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to read base tree: %w", err)
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("failed to read index: %w", err)
+	}
+
+	covered := func(path string) bool {
+		if paths == nil {
+			return true
+		}
+		for _, p := range paths {
+			if path == p || strings.HasPrefix(path, p+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	base := make(map[string]*diffFile)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read base tree: %w", err)
+		}
+		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule || !covered(name) {
+			continue
+		}
+		base[name] = &diffFile{path: name, hash: entry.Hash, mode: entry.Mode}
+	}
+
+	// The worktree's files are the ones in the index, and the untracked ones git add -N . would add.
+	tracked := make(map[string]*index.Entry)
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Submodule && covered(entry.Name) {
+			tracked[entry.Name] = entry
+		}
+	}
+	untracked, err := g.untrackedFiles(tracked, paths)
+	if err != nil {
+		return "", err
+	}
+	candidates := make(map[string]*index.Entry, len(tracked)+len(untracked))
+	for path, entry := range tracked {
+		candidates[path] = entry
+	}
+	for _, path := range untracked {
+		candidates[path] = nil
+	}
+
+	var changes []fileChange
+	for path, from := range base {
+		if _, ok := candidates[path]; !ok {
+			changes = append(changes, fileChange{from: from})
+		}
+	}
+	for path, entry := range candidates {
+		to, err := g.worktreeFile(path, entry)
+		if err != nil {
+			return "", err
+		}
+		from := base[path]
+		if from != nil && to != nil && from.hash == to.hash && from.mode == to.mode {
+			continue
+		}
+		if from != nil || to != nil {
+			changes = append(changes, fileChange{from: from, to: to})
+		}
+	}
+	changes = findRenames(changes)
+	if renames, err := g.mayRename(repo, changes); err != nil {
+		return "", err
+	} else if renames {
+		return "", errNeedsGit
+	}
+
+	var out strings.Builder
+	for _, change := range changes {
+		if change.from != nil && change.from.content == nil && change.from.hash != change.to.hashOrZero() {
+			blob, err := repo.BlobObject(change.from.hash)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", change.from.path, err)
+			}
+			reader, err := blob.Reader()
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", change.from.path, err)
+			}
+			change.from.content, err = io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", change.from.path, err)
+			}
+		}
+		if change.to != nil && change.to.content == nil && change.to.hash != change.from.hashOrZero() {
+			if change.to.content, err = g.readWorktreeFile(change.to); err != nil {
+				return "", err
+			}
+		}
+		writeFileDiff(&out, change, ignoreWhitespace)
+	}
+	return out.String(), nil
+}
+
+func (f *diffFile) hashOrZero() plumbing.Hash {
+	if f == nil {
+		return plumbing.ZeroHash
+	}
+	return f.hash
+}
+
+// worktreeFile returns the file at path in the worktree, or nil if there's none. If the file looks unchanged since
+// it was added to the index, the hash in entry is used rather than reading it.
+func (g *GitWorktree) worktreeFile(path string, entry *index.Entry) (*diffFile, error) {
+	info, err := os.Lstat(filepath.Join(g.worktreePath, filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := &diffFile{path: path}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		file.mode = filemode.Symlink
+	case info.Mode().IsRegular() && info.Mode()&0111 != 0:
+		file.mode = filemode.Executable
+	case info.Mode().IsRegular():
+		file.mode = filemode.Regular
+	default:
+		// A directory where the file was, or something git doesn't track
+		return nil, nil
+	}
+
+	if entry != nil && !entry.IntentToAdd && !entry.Hash.IsZero() && entry.Mode == file.mode &&
+		int64(entry.Size) == info.Size() && entry.ModifiedAt.Equal(info.ModTime()) {
+		file.hash = entry.Hash
+		return file, nil
+	}
+	if file.content, err = g.readWorktreeFile(file); err != nil {
+		return nil, err
+	}
+	file.hash = plumbing.ComputeHash(plumbing.BlobObject, file.content)
+	return file, nil
+}
+
+// readWorktreeFile reads a file of the worktree the way git stores it: a symlink's content is its target.
+func (g *GitWorktree) readWorktreeFile(file *diffFile) ([]byte, error) {
+	path := filepath.Join(g.worktreePath, filepath.FromSlash(file.path))
+	if file.mode == filemode.Symlink {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(filepath.ToSlash(target)), nil
+	}
+	return os.ReadFile(path)
+}
+
+// untrackedFiles returns the files of the worktree that aren't tracked or ignored, like git ls-files --others
+// --exclude-standard, limited to paths unless they're nil.
+func (g *GitWorktree) untrackedFiles(tracked map[string]*index.Entry, paths []string) ([]string, error) {
+	// relevant returns true if dir may hold files under paths.
+	relevant := func(dir string) bool {
+		if paths == nil || dir == "" {
+			return true
+		}
+		for _, p := range paths {
+			if dir == p || strings.HasPrefix(dir, p+"/") || strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	wanted := func(path string) bool {
+		if paths == nil {
+			return true
+		}
+		for _, p := range paths {
+			if path == p || strings.HasPrefix(path, p+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var files []string
+	var walk func(parts []string, patterns []gitignore.Pattern) error
+	walk = func(parts []string, patterns []gitignore.Pattern) error {
+		dir := filepath.Join(append([]string{g.worktreePath}, parts...)...)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		local, err := readIgnoreFile(filepath.Join(dir, ".gitignore"), parts)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns[:len(patterns):len(patterns)], local...)
+		matcher := gitignore.NewMatcher(patterns)
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if name == ".git" {
+				continue
+			}
+			entryParts := append(parts[:len(parts):len(parts)], name)
+			path := strings.Join(entryParts, "/")
+			if entry.IsDir() {
+				if !relevant(path) || matcher.Match(entryParts, true) {
+					continue
+				}
+				if _, err := os.Lstat(filepath.Join(dir, name, ".git")); err == nil {
+					// Another repository, which git would add as a submodule
+					continue
+				}
+				if err := walk(entryParts, patterns); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, ok := tracked[path]; ok || !wanted(path) {
+				continue
+			}
+			if !entry.Type().IsRegular() && entry.Type()&fs.ModeSymlink == 0 {
+				continue
+			}
+			if matcher.Match(entryParts, false) {
+				continue
+			}
+			files = append(files, path)
+		}
+		return nil
+	}
+
+	patterns, err := g.excludePatterns()
+	if err != nil {
+		return nil, err
+	}
+	if err := walk(nil, patterns); err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	return files, nil
+}
+
+// excludePatterns returns the ignore patterns that apply to the whole worktree: the user's excludes file and the
+// repository's info/exclude.
+func (g *GitWorktree) excludePatterns() ([]gitignore.Pattern, error) {
+	var patterns []gitignore.Pattern
+
+	excludesFile := ""
+	if cfg, err := gitconfig.LoadConfig(gitconfig.GlobalScope); err == nil {
+		excludesFile = cfg.Raw.Section("core").Option("excludesfile")
+	}
+	if strings.HasPrefix(excludesFile, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			excludesFile = filepath.Join(home, excludesFile[2:])
+		}
+	}
+	if excludesFile == "" {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				configHome = filepath.Join(home, ".config")
+			}
+		}
+		if configHome != "" {
+			excludesFile = filepath.Join(configHome, "git", "ignore")
+		}
+	}
+	for _, file := range []string{excludesFile, filepath.Join(g.repoPath, ".git", "info", "exclude")} {
+		if file == "" {
+			continue
+		}
+		filePatterns, err := readIgnoreFile(file, nil)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	return patterns, nil
+}
+
+// readIgnoreFile reads the patterns of a gitignore file that applies to the directory domain. A missing file has
+// none.
+func readIgnoreFile(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// emptyBlobHash is the hash of an empty file. Empty files aren't paired up as renames, as git doesn't.
+var emptyBlobHash = plumbing.ComputeHash(plumbing.BlobObject, nil)
+
+// findRenames pairs up deleted and added files with the same content and mode, as git does for exact renames, and
+// returns the changes sorted by path.
+func findRenames(changes []fileChange) []fileChange {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path() < changes[j].path()
+	})
+	deleted := make(map[plumbing.Hash][]int)
+	for i, change := range changes {
+		if change.to == nil && change.from.hash != emptyBlobHash {
+			deleted[change.from.hash] = append(deleted[change.from.hash], i)
+		}
+	}
+
+	renamed := make(map[int]bool)
+	for i, change := range changes {
+		if change.from != nil {
+			continue
+		}
+		for _, j := range deleted[change.to.hash] {
+			if !renamed[j] && changes[j].from.mode == change.to.mode {
+				renamed[j] = true
+				changes[i].from = changes[j].from
+				break
+			}
+		}
+	}
+
+	result := make([]fileChange, 0, len(changes)-len(renamed))
+	for i, change := range changes {
+		if !renamed[i] {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// mayRename returns true if git could pair up any of the added and deleted files in changes as a rename of an edited
+// file. Like git, it only pairs regular files whose sizes differ by less than half of the larger one's.
+func (g *GitWorktree) mayRename(repo *git.Repository, changes []fileChange) (bool, error) {
+	var added, deleted []int64
+	for _, change := range changes {
+		switch {
+		case change.from == nil && isRegular(change.to.mode):
+			size := int64(len(change.to.content))
+			if change.to.content == nil {
+				info, err := os.Lstat(filepath.Join(g.worktreePath, filepath.FromSlash(change.to.path)))
+				if err != nil {
+					return false, err
+				}
+				size = info.Size()
+			}
+			added = append(added, size)
+		case change.to == nil && isRegular(change.from.mode):
+			blob, err := repo.BlobObject(change.from.hash)
+			if err != nil {
+				return false, fmt.Errorf("failed to read %s: %w", change.from.path, err)
+			}
+			deleted = append(deleted, blob.Size)
+		}
+	}
+	for _, a := range added {
+		for _, d := range deleted {
+			larger, delta := max(a, d), max(a, d)-min(a, d)
+			if larger > 0 && delta*2 <= larger {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isRegular returns true for the modes of regular files, executable or not.
+func isRegular(mode filemode.FileMode) bool {
+	return mode == filemode.Regular || mode == filemode.Executable || mode == filemode.Deprecated
+}
+
+// convertsFiles returns true if git would convert the worktree's files before diffing them or diff them with
+// drivers: if core.autocrlf is set, or an attributes file sets any of diffAttributes.
+func (g *GitWorktree) convertsFiles(repo *git.Repository) (bool, error) {
+	attributesFile := ""
+	for _, scope := range []gitconfig.Scope{gitconfig.GlobalScope, gitconfig.SystemScope} {
+		if cfg, err := gitconfig.LoadConfig(scope); err == nil {
+			core := cfg.Raw.Section("core")
+			if autocrlf := core.Option("autocrlf"); autocrlf != "" && autocrlf != "false" {
+				return true, nil
+			}
+			if attributesFile == "" {
+				attributesFile = core.Option("attributesfile")
+			}
+		}
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return false, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	if autocrlf := cfg.Raw.Section("core").Option("autocrlf"); autocrlf != "" && autocrlf != "false" {
+		return true, nil
+	}
+
+	if strings.HasPrefix(attributesFile, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			attributesFile = filepath.Join(home, attributesFile[2:])
+		}
+	}
+	if attributesFile == "" {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				configHome = filepath.Join(home, ".config")
+			}
+		}
+		if configHome != "" {
+			attributesFile = filepath.Join(configHome, "git", "attributes")
+		}
+	}
+	files := []string{attributesFile, filepath.Join(g.repoPath, ".git", "info", "attributes"),
+		filepath.Join(g.worktreePath, ".gitattributes")}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return false, fmt.Errorf("failed to read index: %w", err)
+	}
+	for _, entry := range idx.Entries {
+		if entry.Name != ".gitattributes" && strings.HasSuffix(entry.Name, "/.gitattributes") {
+			files = append(files, filepath.Join(g.worktreePath, filepath.FromSlash(entry.Name)))
+		}
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if sets, err := setsDiffAttributes(file); err != nil || sets {
+			return sets, err
+		}
+	}
+	return false, nil
+}
+
+// setsDiffAttributes returns true if the attributes file at path sets, unsets or defines a macro with any of
+// diffAttributes. A missing file sets none.
+func setsDiffAttributes(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			name, _, _ := strings.Cut(strings.TrimLeft(field, "-!"), "=")
+			for _, attribute := range diffAttributes {
+				if name == attribute {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// writeFileDiff writes the diff of a file the way git diff does, with -w if ignoreWhitespace is set.
+func writeFileDiff(out *strings.Builder, change fileChange, ignoreWhitespace bool) {
+	from, to := change.from, change.to
+	if ignoreWhitespace && from != nil && to != nil && from.path == to.path && from.mode == to.mode &&
+		!isBinary(from.content) && !isBinary(to.content) &&
+		whitespaceKeys(splitLines(string(from.content))) == whitespaceKeys(splitLines(string(to.content))) {
+		// git diff -w leaves out files whose only changes are in whitespace
+		return
+	}
+	fromPath, toPath := change.path(), change.path()
+	if from != nil {
+		fromPath = from.path
+	}
+	fmt.Fprintf(out, "diff --git %s %s\n", quotePath("a/"+fromPath), quotePath("b/"+toPath))
+
+	switch {
+	case from == nil:
+		fmt.Fprintf(out, "new file mode %s\n", modeString(to.mode))
+	case to == nil:
+		fmt.Fprintf(out, "deleted file mode %s\n", modeString(from.mode))
+	default:
+		if from.mode != to.mode {
+			fmt.Fprintf(out, "old mode %s\nnew mode %s\n", modeString(from.mode), modeString(to.mode))
+		}
+		if from.path != to.path {
+			fmt.Fprintf(out, "similarity index 100%%\nrename from %s\nrename to %s\n", quotePath(from.path),
+				quotePath(to.path))
+		}
+	}
+	if from.hashOrZero() == to.hashOrZero() {
+		// Only the mode or the path changed
+		return
+	}
+
+	indexLine := fmt.Sprintf("index %s..%s", from.hashOrZero(), to.hashOrZero())
+	if from != nil && to != nil && from.mode == to.mode {
+		indexLine += " " + modeString(to.mode)
+	}
+	out.WriteString(indexLine + "\n")
+
+	oldName, newName := "/dev/null", "/dev/null"
+	var oldContent, newContent []byte
+	if from != nil {
+		oldName, oldContent = quotePath("a/"+from.path), from.content
+	}
+	if to != nil {
+		newName, newContent = quotePath("b/"+to.path), to.content
+	}
+	if isBinary(oldContent) || isBinary(newContent) {
+		fmt.Fprintf(out, "Binary files %s and %s differ\n", oldName, newName)
+		return
+	}
+	if len(oldContent) == 0 && len(newContent) == 0 {
+		return
+	}
+	var hunks strings.Builder
+	writeHunks(&hunks, string(oldContent), string(newContent), ignoreWhitespace)
+	if hunks.Len() == 0 {
+		// Only whitespace changed, along with the mode
+		return
+	}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+	out.WriteString(hunks.String())
+}
+
+// diffOp is a line of a diff: ' ' if it's in both files, '-' if it was removed and '+' if it was added. The line
+// keeps its newline, unless it's the last line of a file that doesn't end with one.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// writeHunks writes the hunks turning oldContent into newContent, with diffContextLines of context around each.
+// If ignoreWhitespace is set, lines that only differ in whitespace are unchanged, and shown as they are in newContent.
+func writeHunks(out *strings.Builder, oldContent, newContent string, ignoreWhitespace bool) {
+	oldLines := splitLines(oldContent)
+	var ops []diffOp
+	if ignoreWhitespace {
+		ops = whitespaceOps(oldLines, splitLines(newContent))
+	} else {
+		for _, d := range utildiff.Do(oldContent, newContent) {
+			kind := byte(' ')
+			switch d.Type {
+			case diffmatchpatch.DiffDelete:
+				kind = '-'
+			case diffmatchpatch.DiffInsert:
+				kind = '+'
+			}
+			for _, line := range splitLines(d.Text) {
+				ops = append(ops, diffOp{kind, line})
+			}
+		}
+	}
+
+	// oldLine[i] and newLine[i] are the number of lines of each file before ops[i].
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	// Like git, the function name of a hunk is the closest line before it that starts with a letter, _ or $, searched
+	// back to the previous hunk, and kept from the previous hunk if there's none.
+	funcName, funcSearchedTo := "", -1
+	for i := 0; i < len(ops); {
+		first := i
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// Take in the changes that are close enough for their context to meet.
+		last := first
+		for j := first + 1; j < len(ops) && j-last-1 <= 2*diffContextLines; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		start := max(first-diffContextLines, i)
+		end := min(last+diffContextLines, len(ops)-1)
+
+		for l := oldLine[start] - 1; l > funcSearchedTo && l >= 0; l-- {
+			if name, ok := funcNameOf(oldLines[l]); ok {
+				funcName = name
+				break
+			}
+		}
+		funcSearchedTo = oldLine[start] - 1
+
+		oldCount, newCount := oldLine[end+1]-oldLine[start], newLine[end+1]-newLine[start]
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		if funcName != "" {
+			header += " " + funcName
+		}
+		out.WriteString(header + "\n")
+		for _, op := range ops[start : end+1] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end + 1
+	}
+}
+
+// whitespaceOps diffs lines ignoring their whitespace. Unchanged lines are taken from newLines.
+func whitespaceOps(oldLines, newLines []string) []diffOp {
+	var ops []diffOp
+	i, j := 0, 0
+	for _, d := range utildiff.Do(whitespaceKeys(oldLines), whitespaceKeys(newLines)) {
+		for n := strings.Count(d.Text, "\n"); n > 0; n-- {
+			switch d.Type {
+			case diffmatchpatch.DiffDelete:
+				ops = append(ops, diffOp{'-', oldLines[i]})
+				i++
+			case diffmatchpatch.DiffInsert:
+				ops = append(ops, diffOp{'+', newLines[j]})
+				j++
+			default:
+				ops = append(ops, diffOp{' ', newLines[j]})
+				i++
+				j++
+			}
+		}
+	}
+	return ops
+}
+
+// whitespaceKeys returns lines with the whitespace git diff -w ignores removed, one per line.
+func whitespaceKeys(lines []string) string {
+	var keys strings.Builder
+	for _, line := range lines {
+		keys.WriteString(strings.Map(func(r rune) rune {
+			if strings.ContainsRune(" \t\n\v\f\r", r) {
+				return -1
+			}
+			return r
+		}, line))
+		keys.WriteByte('\n')
+	}
+	return keys.String()
+}
+
+// hunkRange formats the start and length of a hunk's lines in a file, where before is the number of lines before it.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprint(before + 1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// funcNameOf returns the function name git shows for a line, if it could start a function.
+func funcNameOf(line string) (string, bool) {
+	if line == "" {
+		return "", false
+	}
+	c := line[0]
+	if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$') {
+		return "", false
+	}
+	if len(line) > funcNameSize {
+		line = line[:funcNameSize]
+	}
+	return strings.TrimRight(line, " \t\n\v\f\r"), true
+}
+
+// splitLines splits text into lines, keeping their newlines.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binaryCheckSize)], 0) >= 0
+}
+
+func modeString(mode filemode.FileMode) string {
+	return fmt.Sprintf("%06o", uint32(mode))
+}
+
+// quotePath quotes a path the way git does if it has special characters, ex. "a/caf\303\251".
+func quotePath(path string) string {
+	quote := false
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		return path
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, `\%03o`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newDiffTestRepo creates a repository with files committed, and returns a worktree for it based on that commit.
+func newDiffTestRepo(t testing.TB, files map[string]string) (*GitWorktree, func(path, content string)) {
+	repo := t.TempDir()
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repo, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(repo, path), []byte(content), 0644))
+	}
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+	for path, content := range files {
+		write(path, content)
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "-m", "init")
+	head := strings.TrimSpace(git("rev-parse", "HEAD"))
+	return NewGitWorktreeFromStorage(repo, repo, "test", "main", head), write
+}
+
+func TestNativeDiffMatchesGit(t *testing.T) {
+	var long strings.Builder
+	for i := 0; i < 40; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&long, "func f%d() {\n", i)
+		} else {
+			fmt.Fprintf(&long, "\tline %d\n", i)
+		}
+	}
+	worktree, write := newDiffTestRepo(t, map[string]string{
+		".gitignore":   "*.log\nbuild/\n",
+		"long.go":      long.String(),
+		"delete.txt":   "going away, and too unlike the new files to be renamed\n",
+		"rename.txt":   "moving\nsomewhere\n",
+		"script.sh":    "echo hi\n",
+		"no-eol.txt":   "a\nb",
+		"café.txt":     "accent\n",
+		"image.bin":    "\x00\x01\x02",
+		"dir/keep.txt": "same\n",
+		"empty.txt":    "",
+		"spaces.txt":   "a b\nc\n",
+		"spaces.sh":    "x\n",
+	})
+	root := worktree.GetWorktreePath()
+
+	// Changes far apart make separate hunks, each named after the function before it. Without -w, the reindented
+	// line is changed too.
+	changed := strings.Replace(long.String(), "line 2\n", "line two\n", 1)
+	changed = strings.Replace(changed, "\tline 3\n", "    line 3\n", 1)
+	write("long.go", strings.Replace(changed, "line 35\n", "line 35\n\tadded\n", 1))
+	require.NoError(t, os.Remove(filepath.Join(root, "delete.txt")))
+	require.NoError(t, os.Rename(filepath.Join(root, "rename.txt"), filepath.Join(root, "dir/renamed.txt")))
+	require.NoError(t, os.Chmod(filepath.Join(root, "script.sh"), 0755))
+	write("no-eol.txt", "a\nc")
+	write("café.txt", "accent\nmore\n")
+	write("image.bin", "\x00\x03")
+	write("new/file.txt", "untracked\n")
+	write("new/empty.txt", "")
+	write("debug.log", "ignored\n")
+	write("build/out.txt", "ignored\n")
+	// Only whitespace changes, which -w leaves out, unless the mode changed too
+	write("spaces.txt", "a  b\n\tc\n")
+	write("spaces.sh", "x \n")
+	require.NoError(t, os.Chmod(filepath.Join(root, "spaces.sh"), 0755))
+	require.NoError(t, os.Symlink("dir/keep.txt", filepath.Join(root, "link")))
+
+	for _, ignoreWhitespace := range []bool{false, true} {
+		for _, paths := range [][]string{nil, {"long.go", "new", "no-eol.txt"}, {"missing"}} {
+			expected, err := worktree.execDiff(paths, ignoreWhitespace)
+			require.NoError(t, err)
+			actual, err := worktree.nativeDiff(paths, ignoreWhitespace)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual, "paths %v, ignoring whitespace %v", paths, ignoreWhitespace)
+		}
+	}
+}
+
+func TestNativeDiffFallsBackToGit(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+
+	t.Run("rename of an edited file", func(t *testing.T) {
+		worktree, write := newDiffTestRepo(t, map[string]string{"old.txt": content.String()})
+		require.NoError(t, os.Remove(filepath.Join(worktree.GetWorktreePath(), "old.txt")))
+		write("new.txt", content.String()+"extra\n")
+
+		_, err := worktree.nativeDiff(nil, false)
+		require.ErrorIs(t, err, errNeedsGit)
+		expected, err := worktree.execDiff(nil, false)
+		require.NoError(t, err)
+		require.Contains(t, expected, "rename from old.txt\nrename to new.txt\n")
+		actual, err := worktree.computeDiff(nil, false)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		stats := NewDiffStats(actual)
+		require.Equal(t, 1, stats.Added)
+		require.Equal(t, 0, stats.Removed)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		for _, attributes := range []string{"*.dat filter=lfs diff=lfs merge=lfs -text\n", "*.txt eol=crlf\n",
+			"[attr]nodiff -diff\n*.dat nodiff\n"} {
+			worktree, write := newDiffTestRepo(t, map[string]string{
+				".gitattributes": attributes,
+				"file.dat":       "version https://git-lfs.github.com/spec/v1\noid sha256:1\nsize 1\n",
+				"file.txt":       "text\n",
+			})
+			write("file.dat", "version https://git-lfs.github.com/spec/v1\noid sha256:2\nsize 2\n")
+			write("file.txt", "more text\n")
+
+			_, err := worktree.nativeDiff(nil, false)
+			require.ErrorIs(t, err, errNeedsGit, attributes)
+			expected, err := worktree.execDiff(nil, false)
+			require.NoError(t, err)
+			actual, err := worktree.computeDiff(nil, false)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual, attributes)
+		}
+	})
+}
+
+// BenchmarkDiff compares computing a diff in process with running git, in a repository of 1000 files with 20
+// changed and 5 added.
+func BenchmarkDiff(b *testing.B) {
+	files := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		files[fmt.Sprintf("dir%d/file%d.txt", i%20, i)] = strings.Repeat(fmt.Sprintf("line of file %d\n", i), 50)
+	}
+	worktree, write := newDiffTestRepo(b, files)
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("dir%d/file%d.txt", i%20, i*50)
+		write(path, files[path]+"changed\n")
+	}
+	for i := 0; i < 5; i++ {
+		write(fmt.Sprintf("new/file%d.txt", i), "new\n")
+	}
+
+	b.Run("exec", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := worktree.execDiff(nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("native", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := worktree.nativeDiff(nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@ package main
+ package main
+-x := 1
+---y
++x := 2
++++z
+ done
+\ No newline at end of file
+diff --git a/old.txt b/docs/new.txt
+similarity index 90%
+rename from old.txt
+rename to docs/new.txt
+index 3333333..4444444 100644
+--- a/old.txt
++++ b/docs/new.txt
+@@ -2 +2 @@
+-two
++2
+diff --git a/notes.md b/notes.md
+new file mode 100644
+index 0000000..5555555
+--- /dev/null
++++ b/notes.md
+@@ -0,0 +1,2 @@
++# Notes
++
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 6666666..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/logo.png b/logo.png
+index 7777777..8888888 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git "a/tab\there.txt" "b/tab\there.txt"
+new file mode 100644
+index 0000000..9999999
+Binary files /dev/null and "b/tab\there.txt" differ
+`
+
+func TestParseDiff(t *testing.T) {
+	stats := NewDiffStats(sampleDiff)
+
+	// "---y" and "+++z" are a removed and an added line, not file headers.
+	if stats.Added != 5 || stats.Removed != 4 {
+		t.Errorf("expected +5 -4, got +%d -%d", stats.Added, stats.Removed)
+	}
+
+	type summary struct {
+		path, oldPath  string
+		status         FileStatus
+		binary         bool
+		added, removed int
+		hunks          int
+	}
+	var got []summary
+	for _, file := range stats.Files {
+		got = append(got, summary{file.Path, file.OldPath, file.Status, file.Binary, file.Added, file.Removed,
+			len(file.Hunks)})
+	}
+	expected := []summary{
+		{"main.go", "", FileModified, false, 2, 2, 1},
+		{"docs/new.txt", "old.txt", FileRenamed, false, 1, 1, 1},
+		{"notes.md", "", FileAdded, false, 2, 0, 1},
+		{"gone.txt", "", FileDeleted, false, 0, 1, 1},
+		{"logo.png", "", FileBinary, true, 0, 0, 0},
+		{"tab\there.txt", "", FileAdded, true, 0, 0, 0},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("files = %+v\nexpected %+v", got, expected)
+	}
+
+	hunk := stats.Files[0].Hunks[0]
+	if hunk.OldStart != 1 || hunk.OldLines != 4 || hunk.NewStart != 1 || hunk.NewLines != 4 {
+		t.Errorf("unexpected hunk range %+v", hunk)
+	}
+	expectedLines := []DiffLine{
+		{Kind: LineContext, Content: "package main", OldLine: 1, NewLine: 1},
+		{Kind: LineRemoved, Content: "x := 1", OldLine: 2},
+		{Kind: LineRemoved, Content: "--y", OldLine: 3},
+		{Kind: LineAdded, Content: "x := 2", NewLine: 2},
+		{Kind: LineAdded, Content: "++z", NewLine: 3},
+		{Kind: LineContext, Content: "done", OldLine: 4, NewLine: 4},
+	}
+	if !reflect.DeepEqual(hunk.Lines, expectedLines) {
+		t.Errorf("lines = %+v\nexpected %+v", hunk.Lines, expectedLines)
+	}
+	if rename := stats.Files[1].Hunks[0]; rename.OldLines != 1 || rename.NewLines != 1 {
+		t.Errorf("expected a missing hunk count to mean 1, got %+v", rename)
+	}
+
+	if paths := stats.Paths(); !reflect.DeepEqual(paths, []string{
+		"main.go", "docs/new.txt", "notes.md", "gone.txt", "logo.png", "tab\there.txt",
+	}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+	if files := ParseDiff(""); len(files) != 0 {
+		t.Errorf("ParseDiff of an empty diff = %v, expected none", files)
+	}
+}
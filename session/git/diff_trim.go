@@ -0,0 +1,155 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// generatedAttribute marks files as generated in .gitattributes, as GitHub's linguist does.
+const generatedAttribute = "linguist-generated"
+
+// trimDiff leaves the files opts excludes out of stats, and summarizes the files over its size limits by their line
+// counts. stats is returned as it is if nothing was trimmed.
+func (g *GitWorktree) trimDiff(stats *DiffStats, opts config.DiffOptions) *DiffStats {
+	maxLines, maxBytes := opts.MaxFileLines, opts.MaxBytes
+	if maxLines == 0 {
+		maxLines = config.DefaultDiffMaxFileLines
+	}
+	if maxBytes == 0 {
+		maxBytes = config.DefaultDiffMaxBytes
+	}
+
+	var patterns []gitignore.Pattern
+	for _, pattern := range opts.Exclude {
+		patterns = append(patterns, gitignore.ParsePattern(pattern, nil))
+	}
+	excluded := gitignore.NewMatcher(patterns)
+	var attributes gitattributes.Matcher
+	if !opts.IncludeGenerated {
+		attributes = g.attributesMatcher(stats.Paths())
+	}
+
+	var out strings.Builder
+	trimmed := false
+	for _, chunk := range splitDiff(stats.Content) {
+		if chunk.path != "" {
+			parts := strings.Split(chunk.path, "/")
+			if excluded.Match(parts, false) || (attributes != nil && isGenerated(attributes, parts)) {
+				trimmed = true
+				continue
+			}
+		}
+		// Files over the limit are summarized, but smaller ones after them may still fit.
+		if chunk.added+chunk.removed > maxLines || out.Len()+len(chunk.content) > maxBytes {
+			if summary, ok := summarizeChunk(chunk); ok {
+				out.WriteString(summary)
+				trimmed = true
+				continue
+			}
+		}
+		out.WriteString(chunk.content)
+	}
+	if !trimmed {
+		return stats
+	}
+	return NewDiffStats(out.String())
+}
+
+// summarizeChunk replaces the hunks of a file's diff with its line counts. It returns false if the file has no hunks.
+func summarizeChunk(chunk diffChunk) (string, bool) {
+	if chunk.added == 0 && chunk.removed == 0 {
+		return "", false
+	}
+	var summary strings.Builder
+	for _, line := range strings.SplitAfter(chunk.content, "\n") {
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@ ") {
+			break
+		}
+		summary.WriteString(line)
+	}
+	fmt.Fprintf(&summary, truncatedFormat, chunk.added, chunk.removed)
+	return summary.String(), true
+}
+
+// attributesMatcher reads the .gitattributes files that apply to paths: the ones in the worktree's directories that
+// contain them, and the repository's info/attributes. Files that can't be read are logged and skipped.
+func (g *GitWorktree) attributesMatcher(paths []string) gitattributes.Matcher {
+	dirs := map[string]bool{"": true}
+	for _, p := range paths {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	// Deeper files take precedence, so they go later in the stack.
+	depth := func(dir string) int {
+		if dir == "" {
+			return 0
+		}
+		return strings.Count(dir, "/") + 1
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if depth(sorted[i]) != depth(sorted[j]) {
+			return depth(sorted[i]) < depth(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	var stack []gitattributes.MatchAttribute
+	read := func(file string, domain []string) {
+		attrs, err := readAttributesFile(file, domain)
+		if err != nil {
+			log.WarningLog.Printf("could not read %s: %v", file, err)
+			return
+		}
+		stack = append(stack, attrs...)
+	}
+	for _, dir := range sorted {
+		var domain []string
+		if dir != "" {
+			domain = strings.Split(dir, "/")
+		}
+		read(filepath.Join(g.worktreePath, filepath.FromSlash(dir), ".gitattributes"), domain)
+	}
+	read(filepath.Join(g.repoPath, ".git", "info", "attributes"), nil)
+	return gitattributes.NewMatcher(stack)
+}
+
+// readAttributesFile reads the attributes of a .gitattributes file that applies to the directory domain. A missing
+// file has none.
+func readAttributesFile(file string, domain []string) ([]gitattributes.MatchAttribute, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Like git, only the top level file may define macros.
+	return gitattributes.ReadAttributes(f, domain, len(domain) == 0)
+}
+
+// isGenerated reports whether the attributes mark the file at path as generated.
+func isGenerated(attributes gitattributes.Matcher, path []string) bool {
+	results, _ := attributes.Match(path, []string{generatedAttribute})
+	attr, ok := results[generatedAttribute]
+	if !ok {
+		return false
+	}
+	return attr.IsSet() || (attr.IsValueSet() && attr.Value() == "true")
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"claude-squad/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimDiff(t *testing.T) {
+	worktree, write := newDiffTestRepo(t, map[string]string{
+		".gitattributes":     "gen/** linguist-generated\n*.pb.go linguist-generated=true\n",
+		"api/.gitattributes": "keep.pb.go -linguist-generated\n",
+		"main.go":            "package main\n",
+		"vendor/lib.go":      "package lib\n",
+	})
+	defer worktree.StopWatching()
+	write("main.go", "package  main\n\nfunc main() {}\n")
+	write("vendor/lib.go", "package lib\n\nfunc Lib() {}\n")
+	write("go.lock", "locked\n")
+	write("gen/out.go", "generated\n")
+	write("api/api.pb.go", "generated\n")
+	write("api/keep.pb.go", "kept\n")
+	write("big.txt", strings.Repeat("line\n", 30))
+
+	worktree.SetDiffOptions(config.DiffOptions{Exclude: []string{"vendor/", "*.lock"}, MaxFileLines: 20})
+	stats := worktree.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"api/keep.pb.go", "big.txt", "main.go"}, stats.Paths())
+	// The file over the limit keeps its counts, even after the content is parsed again.
+	for _, files := range [][]FileDiff{stats.Files, ParseDiff(stats.Content)} {
+		big := files[1]
+		assert.True(t, big.Truncated)
+		assert.Equal(t, FileAdded, big.Status)
+		assert.Equal(t, 30, big.Added)
+		assert.Empty(t, big.Hunks)
+	}
+	assert.Equal(t, 1+30+3, stats.Added)
+	assert.Equal(t, 1, stats.Removed)
+
+	t.Run("with other options", func(t *testing.T) {
+		stats := worktree.DiffWithOptions(config.DiffOptions{IgnoreWhitespace: true, IncludeGenerated: true})
+		require.NoError(t, stats.Error)
+		assert.Equal(t, []string{"api/api.pb.go", "api/keep.pb.go", "big.txt", "gen/out.go", "go.lock", "main.go",
+			"vendor/lib.go"}, stats.Paths())
+		// Only the reindented line is left out.
+		assert.Equal(t, 2, stats.Files[5].Added)
+		assert.Equal(t, 0, stats.Files[5].Removed)
+		assert.False(t, stats.Files[2].Truncated)
+
+		// The configured options are left as they were.
+		assert.Equal(t, []string{"api/keep.pb.go", "big.txt", "main.go"}, worktree.Diff().Paths())
+	})
+
+	t.Run("over the size limit", func(t *testing.T) {
+		full := worktree.DiffWithOptions(config.DiffOptions{IncludeGenerated: true})
+		worktree.SetDiffOptions(config.DiffOptions{IncludeGenerated: true, MaxBytes: 400})
+		stats := worktree.Diff()
+		require.NoError(t, stats.Error)
+		assert.Equal(t, full.Paths(), stats.Paths())
+		assert.Less(t, len(stats.Content), len(full.Content))
+		assert.Equal(t, full.Added, stats.Added)
+		assert.Equal(t, full.Removed, stats.Removed)
+		truncated := 0
+		for _, file := range stats.Files {
+			if file.Truncated {
+				truncated++
+			}
+		}
+		assert.Positive(t, truncated)
+	})
+}
@@ -0,0 +1,334 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// diffDebounce is how long the worktree must be quiet before its diff is computed again, so a program writing many
+	// files in a row causes one diff rather than one per file.
+	diffDebounce = 300 * time.Millisecond
+	// diffMaxStaleness is how long changes wait for the worktree to be quiet before they're diffed anyway.
+	diffMaxStaleness = 2 * time.Second
+	// maxIncrementalDiffPaths is the most changed paths diffed on their own. Past it, the whole worktree is diffed.
+	maxIncrementalDiffPaths = 50
+)
+
+// Diff returns the changes in the worktree since the base commit, untracked files included, trimmed by the diff
+// options of the repository's config (see SetDiffOptions). The worktree is watched for changes after the first call,
+// so later calls only run git once files changed, and then only on those files. The index of the worktree isn't
+// changed.
+func (g *GitWorktree) Diff() *DiffStats {
+	g.diffMu.Lock()
+	defer g.diffMu.Unlock()
+
+	g.loadDiffOptions()
+	stats := g.rawDiff()
+	if stats.Error != nil {
+		return stats
+	}
+	if stats != g.trimmedFrom || g.trimmedDiff == nil {
+		g.trimmedDiff = g.trimDiff(stats, g.diffOptions)
+		g.trimmedFrom = stats
+	}
+	return g.trimmedDiff
+}
+
+// DiffWithOptions is like Diff, but uses opts instead of the configured options. It doesn't change what Diff returns.
+func (g *GitWorktree) DiffWithOptions(opts config.DiffOptions) *DiffStats {
+	g.diffMu.Lock()
+	defer g.diffMu.Unlock()
+
+	g.loadDiffOptions()
+	var stats *DiffStats
+	if opts.IgnoreWhitespace == g.diffOptions.IgnoreWhitespace {
+		stats = g.rawDiff()
+	} else {
+		content, err := g.computeDiff(nil, opts.IgnoreWhitespace)
+		if err != nil {
+			return &DiffStats{Error: err}
+		}
+		stats = NewDiffStats(content)
+	}
+	if stats.Error != nil {
+		return stats
+	}
+	return g.trimDiff(stats, opts)
+}
+
+// SetDiffOptions changes the options Diff trims diffs with, ex. after the config is reloaded. Until it's called, Diff
+// uses the options in the config of the worktree's repository.
+func (g *GitWorktree) SetDiffOptions(opts config.DiffOptions) {
+	g.diffMu.Lock()
+	defer g.diffMu.Unlock()
+
+	if g.diffOptionsSet && opts.IgnoreWhitespace != g.diffOptions.IgnoreWhitespace {
+		// Every file's diff changes
+		g.lastDiff = nil
+	}
+	g.diffOptions = opts
+	g.diffOptionsSet = true
+	g.trimmedDiff = nil
+}
+
+// DiffOptions returns the options Diff trims diffs with.
+func (g *GitWorktree) DiffOptions() config.DiffOptions {
+	g.diffMu.Lock()
+	defer g.diffMu.Unlock()
+
+	g.loadDiffOptions()
+	return g.diffOptions
+}
+
+// loadDiffOptions reads the diff options from the config of the repository, unless they were set. diffMu must be held.
+func (g *GitWorktree) loadDiffOptions() {
+	if !g.diffOptionsSet {
+		g.diffOptions = config.LoadRepoConfig(g.repoPath).Diff
+		g.diffOptionsSet = true
+	}
+}
+
+// rawDiff returns the whole diff of the worktree, computing it again if files changed since the last one. diffMu must
+// be held.
+func (g *GitWorktree) rawDiff() *DiffStats {
+	if g.watcher == nil && !g.watchFailed {
+		w, err := newWatcher(g.worktreePath)
+		if err != nil {
+			log.WarningLog.Printf("could not watch %s for changes, diffing it every time: %v", g.worktreePath, err)
+			g.watchFailed = true
+		}
+		g.watcher = w
+	}
+	if g.watcher == nil {
+		return g.fullDiff()
+	}
+
+	if g.lastDiff != nil && !g.watcher.changes.due(time.Now()) {
+		return g.lastDiff
+	}
+
+	paths, all := g.watcher.changes.take()
+	var stats *DiffStats
+	if g.lastDiff == nil || all || len(paths) > maxIncrementalDiffPaths {
+		stats = g.fullDiff()
+	} else {
+		stats = g.diffPaths(g.lastDiff, paths)
+	}
+	if stats.Error != nil {
+		// Start over with the whole worktree next time
+		g.watcher.changes.markAll()
+		return stats
+	}
+	g.lastDiff = stats
+	return stats
+}
+
+// StopWatching stops watching the worktree for changes, ex. before it's removed. The next diff starts watching again.
+func (g *GitWorktree) StopWatching() {
+	g.diffMu.Lock()
+	defer g.diffMu.Unlock()
+
+	if g.watcher != nil {
+		if err := g.watcher.Close(); err != nil {
+			log.WarningLog.Printf("could not stop watching %s: %v", g.worktreePath, err)
+		}
+	}
+	g.watcher = nil
+	g.watchFailed = false
+	g.lastDiff = nil
+	g.trimmedDiff = nil
+	g.trimmedFrom = nil
+}
+
+// fullDiff diffs the whole worktree.
+func (g *GitWorktree) fullDiff() *DiffStats {
+	content, err := g.computeDiff(nil, g.diffOptions.IgnoreWhitespace)
+	if err != nil {
+		return &DiffStats{Error: err}
+	}
+	return NewDiffStats(content)
+}
+
+// diffPaths diffs the changed paths alone, and puts their diff in place of the one they had in prev.
+func (g *GitWorktree) diffPaths(prev *DiffStats, paths []string) *DiffStats {
+	covered := func(path string) bool {
+		for _, changed := range paths {
+			if path == changed || strings.HasPrefix(path, changed+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	for _, file := range prev.Files {
+		if file.Status == FileRenamed && (covered(file.Path) || covered(file.OldPath)) {
+			// git only finds a rename when it diffs both paths together
+			return g.fullDiff()
+		}
+	}
+
+	content, err := g.computeDiff(paths, g.diffOptions.IgnoreWhitespace)
+	if err != nil {
+		return &DiffStats{Error: err}
+	}
+
+	// Keep the diff of the files that didn't change, in the path order git uses. Anything git printed that isn't
+	// about a file, like a warning, is left out.
+	var chunks []diffChunk
+	for _, chunk := range splitDiff(prev.Content) {
+		if chunk.path != "" && !covered(chunk.path) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	for _, chunk := range splitDiff(content) {
+		if chunk.path != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i].path < chunks[j].path
+	})
+
+	var merged strings.Builder
+	for _, chunk := range chunks {
+		merged.WriteString(chunk.content)
+	}
+	return NewDiffStats(merged.String())
+}
+
+// computeDiff diffs the worktree against the base commit, limited to paths unless they're nil, and ignoring
+// whitespace if ignoreWhitespace is set. It's computed in process, and by running git if that fails.
+func (g *GitWorktree) computeDiff(paths []string, ignoreWhitespace bool) (string, error) {
+	content, err := g.nativeDiff(paths, ignoreWhitespace)
+	if err == nil {
+		return content, nil
+	}
+	if errors.Is(err, errNeedsGit) {
+		return g.execDiff(paths, ignoreWhitespace)
+	}
+	log.WarningLog.Printf("could not diff %s in process, running git instead: %v", g.worktreePath, err)
+	return g.execDiff(paths, ignoreWhitespace)
+}
+
+// execDiff runs git diff against the base commit, limited to paths unless they're nil, with -w if ignoreWhitespace is
+// set. Hashes are printed in full, since git abbreviates them to a length that depends on the repository's objects.
+// Untracked files are added to a copy of the index with intent to add, so they're in the diff but the worktree's
+// index is left as it is.
+func (g *GitWorktree) execDiff(paths []string, ignoreWhitespace bool) (string, error) {
+	tempIndex, err := g.copyIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempIndex)
+	env := []string{"GIT_INDEX_FILE=" + tempIndex}
+	diffArgs := []string{"--no-pager", "diff", "--full-index"}
+	if ignoreWhitespace {
+		diffArgs = append(diffArgs, "-w")
+	}
+	diffArgs = append(diffArgs, g.GetBaseCommitSHA())
+
+	// -N stages untracked files (intent to add), including them in the diff
+	if paths == nil {
+		if _, err := g.runGitCommandEnv(g.worktreePath, env, "add", "-N", "."); err != nil {
+			return "", err
+		}
+		return g.runGitCommandEnv(g.worktreePath, env, diffArgs...)
+	}
+
+	// Naming a deleted or ignored file to git add is an error, so only add the untracked files among paths.
+	untracked, err := g.runGitCommandEnv(g.worktreePath, env,
+		append([]string{"--literal-pathspecs", "ls-files", "-z", "--others", "--exclude-standard", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if untracked = strings.TrimSuffix(untracked, "\x00"); untracked != "" {
+		if _, err := g.runGitCommandEnv(g.worktreePath, env,
+			append([]string{"--literal-pathspecs", "add", "-N", "--"}, strings.Split(untracked, "\x00")...)...); err != nil {
+			return "", err
+		}
+	}
+	diffArgs = append(append([]string{"--literal-pathspecs"}, diffArgs...), "--")
+	return g.runGitCommandEnv(g.worktreePath, env, append(diffArgs, paths...)...)
+}
+
+// copyIndex copies the worktree's index to a temporary file, so git commands can stage files without touching the
+// worktree's index. The caller removes the file.
+func (g *GitWorktree) copyIndex() (string, error) {
+	indexPath, err := g.runGitCommand(g.worktreePath, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	indexPath = strings.TrimSpace(indexPath)
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(g.worktreePath, indexPath)
+	}
+
+	tempIndex, err := os.CreateTemp("", "claudesquad-index-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	tempIndex.Close()
+	index, err := os.ReadFile(indexPath)
+	switch {
+	case os.IsNotExist(err):
+		// git treats a missing index file as an empty index
+		os.Remove(tempIndex.Name())
+	case err != nil:
+		os.Remove(tempIndex.Name())
+		return "", fmt.Errorf("failed to read index: %w", err)
+	default:
+		if err := os.WriteFile(tempIndex.Name(), index, 0600); err != nil {
+			os.Remove(tempIndex.Name())
+			return "", fmt.Errorf("failed to copy index: %w", err)
+		}
+		// git only rechecks the contents of files changed in the same second as the index was written, so the
+		// copy keeps its time. Otherwise an edit that keeps the size of a file right after staging goes unseen.
+		if info, err := os.Stat(indexPath); err == nil {
+			if err := os.Chtimes(tempIndex.Name(), info.ModTime(), info.ModTime()); err != nil {
+				os.Remove(tempIndex.Name())
+				return "", fmt.Errorf("failed to copy index: %w", err)
+			}
+		}
+	}
+	return tempIndex.Name(), nil
+}
+
+// diffChunk is the part of a diff about one file.
+type diffChunk struct {
+	path    string
+	content string
+	// added and removed are the file's line counts.
+	added, removed int
+}
+
+// splitDiff splits a diff into the parts about each file.
+func splitDiff(content string) []diffChunk {
+	var chunks []diffChunk
+	var current strings.Builder
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		chunk := diffChunk{content: current.String()}
+		if files := ParseDiff(chunk.content); len(files) > 0 {
+			chunk.path, chunk.added, chunk.removed = files[0].Path, files[0].Added, files[0].Removed
+		}
+		chunks = append(chunks, chunk)
+		current.Reset()
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return chunks
+}
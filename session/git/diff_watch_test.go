@@ -0,0 +1,97 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchedDiff(t *testing.T) {
+	repo := t.TempDir()
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repo, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(repo, path), []byte(content), 0644))
+	}
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+	write(".gitignore", "build/\n")
+	write("a.txt", "a\n")
+	write("dir/b.txt", "b\n")
+	git("init", "-q")
+	git("add", ".")
+	git("-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "-m", "init")
+	write("build/out", "ignored\n")
+	head := strings.TrimSpace(git("rev-parse", "HEAD"))
+
+	worktree := NewGitWorktreeFromStorage(repo, repo, "test", "main", head)
+	defer worktree.StopWatching()
+	require.NoError(t, worktree.Diff().Error)
+	assert.True(t, worktree.Diff().IsEmpty())
+
+	// waitForPaths waits until the diff has changes to paths, and checks it's the diff git prints for the worktree.
+	waitForPaths := func(paths ...string) {
+		require.Eventually(t, func() bool {
+			stats := worktree.Diff()
+			require.NoError(t, stats.Error)
+			return assert.ObjectsAreEqual(paths, stats.Paths())
+		}, 5*time.Second, 50*time.Millisecond)
+		expected, err := worktree.execDiff(nil, false)
+		require.NoError(t, err)
+		assert.Equal(t, expected, worktree.Diff().Content)
+	}
+
+	write("a.txt", "a\nchanged\n")
+	write("dir/c.txt", "new\n")
+	write("build/more", "ignored\n")
+	waitForPaths("a.txt", "dir/c.txt")
+	// The untracked file is in the diff, but not added to the index.
+	assert.Contains(t, git("status", "--porcelain"), "?? dir/c.txt")
+
+	// Files under a new directory are watched too.
+	write("a.txt", "a\n")
+	write("new/d.txt", "d\n")
+	waitForPaths("dir/c.txt", "new/d.txt")
+
+	require.NoError(t, os.RemoveAll(filepath.Join(repo, "new")))
+	require.NoError(t, os.Remove(filepath.Join(repo, "dir/b.txt")))
+	waitForPaths("dir/b.txt", "dir/c.txt")
+
+	// Ignoring a file takes it out of the diff, though only the .gitignore changed.
+	write("dir/.gitignore", "c.txt\n")
+	waitForPaths("dir/.gitignore", "dir/b.txt")
+}
+
+func TestChangeSetDue(t *testing.T) {
+	start := time.Now()
+	changes := &changeSet{paths: make(map[string]bool)}
+	assert.False(t, changes.due(start))
+
+	changes.add("a.txt")
+	assert.False(t, changes.due(time.Now()))
+	assert.True(t, changes.due(time.Now().Add(diffDebounce)))
+
+	// Changes that keep coming are diffed once the first is diffMaxStaleness old.
+	changes.last = start.Add(diffMaxStaleness)
+	assert.False(t, changes.due(start.Add(diffMaxStaleness-time.Millisecond)))
+	assert.True(t, changes.due(start.Add(diffMaxStaleness+diffDebounce/2)))
+
+	paths, all := changes.take()
+	assert.Equal(t, []string{"a.txt"}, paths)
+	assert.False(t, all)
+	assert.False(t, changes.due(time.Now().Add(time.Hour)))
+
+	changes.add("dir/.gitignore")
+	_, all = changes.take()
+	assert.True(t, all)
+}
@@ -0,0 +1,92 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// MergeResult is the outcome of merging two commits without touching any worktree.
+type MergeResult struct {
+	// Clean is true if the commits merge without conflicts.
+	Clean bool `json:"clean"`
+	// Conflicts are the files that don't merge cleanly, relative to the repository root.
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// snapshotEnv is the identity snapshot commits are made with, so they don't depend on the user's git config.
+var snapshotEnv = []string{
+	"GIT_AUTHOR_NAME=claude-squad",
+	"GIT_AUTHOR_EMAIL=claude-squad@localhost",
+	"GIT_COMMITTER_NAME=claude-squad",
+	"GIT_COMMITTER_EMAIL=claude-squad@localhost",
+}
+
+// Snapshot returns a commit of everything in the worktree, uncommitted changes and untracked files included, on top
+// of its HEAD. The worktree, its index and its branch are left as they are; the commit isn't on any branch, so git
+// prunes it eventually. If nothing is uncommitted, it's HEAD.
+func (g *GitWorktree) Snapshot() (string, error) {
+	head, err := g.runGitCommand(g.worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	head = strings.TrimSpace(head)
+	dirty, err := g.IsDirty()
+	if err != nil {
+		return "", err
+	}
+	if !dirty {
+		return head, nil
+	}
+
+	tempIndex, err := g.copyIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempIndex)
+	env := append([]string{"GIT_INDEX_FILE=" + tempIndex}, snapshotEnv...)
+	if _, err := g.runGitCommandEnv(g.worktreePath, env, "add", "-A"); err != nil {
+		return "", fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	tree, err := g.runGitCommandEnv(g.worktreePath, env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write snapshot tree: %w", err)
+	}
+	commit, err := g.runGitCommandEnv(g.worktreePath, env, "commit-tree", strings.TrimSpace(tree), "-p", head, "-m",
+		fmt.Sprintf("Uncommitted changes of %s", g.sessionName))
+	if err != nil {
+		return "", fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// MergeTree merges the commits ours and theirs of the repository at repoPath in memory, without touching any worktree
+// or index, and reports the files that conflict. It needs git 2.38 or later.
+func MergeTree(repoPath, ours, theirs string) (*MergeResult, error) {
+	output, err := exec.Command("git", "-C", repoPath, "merge-tree", "--write-tree", "--name-only", "--no-messages",
+		"-z", ours, theirs).Output()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return &MergeResult{Clean: true}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(output) > 0:
+		// Exit code 1 with output means conflicts: the merged tree is followed by the files with conflicts, and an
+		// empty entry. Without output, it's an error like a missing commit.
+		result := &MergeResult{}
+		entries := strings.Split(string(output), "\x00")
+		for _, entry := range entries[1:] {
+			if entry == "" {
+				break
+			}
+			result.Conflicts = append(result.Conflicts, entry)
+		}
+		return result, nil
+	case errors.As(err, &exitErr):
+		return nil, fmt.Errorf("git merge-tree failed (it needs git 2.38 or later): %s (%w)",
+			strings.TrimSpace(string(exitErr.Stderr)), err)
+	default:
+		return nil, fmt.Errorf("git merge-tree failed: %w", err)
+	}
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTree(t *testing.T) {
+	main, _ := newDiffTestRepo(t, map[string]string{
+		"shared.go": "package shared\n\nfunc A() {}\n\nfunc B() {}\n",
+		"other.go":  "package other\n",
+	})
+	repo := main.GetRepoPath()
+	worktree := func(name string) (*GitWorktree, func(path, content string)) {
+		path := filepath.Join(t.TempDir(), name)
+		output, err := exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", name, path,
+			main.GetBaseCommitSHA()).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return NewGitWorktreeFromStorage(repo, path, name, name, main.GetBaseCommitSHA()),
+			func(file, content string) {
+				require.NoError(t, os.WriteFile(filepath.Join(path, file), []byte(content), 0644))
+			}
+	}
+	a, writeA := worktree("a")
+	b, writeB := worktree("b")
+
+	// Nothing uncommitted: the snapshot is HEAD.
+	snapshot, err := a.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, main.GetBaseCommitSHA(), snapshot)
+
+	merge := func() *MergeResult {
+		ours, err := a.Snapshot()
+		require.NoError(t, err)
+		theirs, err := b.Snapshot()
+		require.NoError(t, err)
+		result, err := MergeTree(repo, ours, theirs)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Changes to different functions of a file, and an untracked file, merge cleanly.
+	writeA("shared.go", "package shared\n\nfunc A() { a() }\n\nfunc B() {}\n")
+	writeA("new.go", "package shared\n")
+	writeB("shared.go", "package shared\n\nfunc A() {}\n\nfunc B() { b() }\n")
+	assert.Equal(t, &MergeResult{Clean: true}, merge())
+
+	writeB("shared.go", "package shared\n\nfunc A() { b() }\n\nfunc B() {}\n")
+	writeB("new.go", "package other\n")
+	assert.Equal(t, &MergeResult{Conflicts: []string{"new.go", "shared.go"}}, merge())
+
+	// The worktrees are left as they were.
+	dirty, err := a.IsDirty()
+	require.NoError(t, err)
+	assert.True(t, dirty)
+	status, err := a.runGitCommand(a.GetWorktreePath(), "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, " M shared.go\n?? new.go\n", status)
+
+	_, err = MergeTree(repo, "missing", "b")
+	assert.Error(t, err)
+}
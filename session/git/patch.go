@@ -0,0 +1,60 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PatchFormat is the format of an exported patch.
+type PatchFormat string
+
+const (
+	// PatchFormatDiff is one unified diff of all changes, as git diff prints it.
+	PatchFormatDiff PatchFormat = "diff"
+	// PatchFormatMail is git format-patch output: one mail per commit, which git am applies as commits.
+	PatchFormatMail PatchFormat = "format-patch"
+)
+
+// patchPrefixes are the path prefixes git apply and git am expect, whatever diff.noprefix or diff.mnemonicPrefix say.
+var patchPrefixes = []string{"--src-prefix=a/", "--dst-prefix=b/"}
+
+// Patch returns the changes between the commits from and to of the repository at repoPath as a unified diff that
+// git apply takes, binary files included. If paths isn't empty, only those files are in it. Renames are left as a
+// deletion and an addition, so a renamed file can be picked by either of its paths.
+func Patch(repoPath, from, to string, paths []string) (string, error) {
+	args := append([]string{"--literal-pathspecs", "diff", "--binary", "--no-color", "--no-ext-diff", "--no-textconv",
+		"--no-renames"}, patchPrefixes...)
+	args = append(args, from, to, "--")
+	return gitStdout(repoPath, "", append(args, paths...)...)
+}
+
+// FormatPatch returns the commits after from up to to of the repository at repoPath in git format-patch format.
+func FormatPatch(repoPath, from, to string) (string, error) {
+	args := append([]string{"format-patch", "--stdout", "--binary", "--no-color"}, patchPrefixes...)
+	return gitStdout(repoPath, "", append(args, from+".."+to)...)
+}
+
+// ApplyPatch applies a patch made by Patch to the worktree at worktreePath. Either every file applies, or nothing is
+// changed.
+func ApplyPatch(worktreePath, patch string) error {
+	if _, err := gitStdout(worktreePath, patch, "apply", "--whitespace=nowarn", "-"); err != nil {
+		return fmt.Errorf("patch does not apply: %w", err)
+	}
+	return nil
+}
+
+// gitStdout runs git in dir with stdin and returns its output. Unlike runGit, warnings on stderr are left out of the
+// output, so it's safe for patches.
+func gitStdout(dir, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %s (%w)", strings.Join(args, " "), strings.TrimSpace(stderr.String()),
+			err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatch(t *testing.T) {
+	main, _ := newDiffTestRepo(t, map[string]string{
+		"helper.go": "package main\n\nfunc helper() {}\n",
+		"main.go":   "package main\n\nfunc main() {}\n",
+	})
+	repo, base := main.GetRepoPath(), main.GetBaseCommitSHA()
+	worktree := func(name string) (*GitWorktree, func(path, content string)) {
+		path := filepath.Join(t.TempDir(), name)
+		output, err := exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", name, path, base).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return NewGitWorktreeFromStorage(repo, path, name, name, base), func(file, content string) {
+			require.NoError(t, os.WriteFile(filepath.Join(path, file), []byte(content), 0644))
+		}
+	}
+	source, writeSource := worktree("source")
+	target, writeTarget := worktree("target")
+
+	// The source commits one change and leaves the others uncommitted.
+	writeSource("helper.go", "package main\n\nfunc helper() int { return 1 }\n")
+	output, err := exec.Command("git", "-C", source.GetWorktreePath(), "-c", "user.email=test@example.com", "-c",
+		"user.name=test", "commit", "-q", "-am", "better helper").CombinedOutput()
+	require.NoError(t, err, string(output))
+	writeSource("main.go", "package main\n\nfunc main() { helper() }\n")
+	writeSource("image.bin", "\x00\x01\x02")
+	snapshot, err := source.Snapshot()
+	require.NoError(t, err)
+
+	patch, err := Patch(repo, base, snapshot, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"helper.go", "image.bin", "main.go"}, NewDiffStats(patch).Paths())
+	assert.Contains(t, patch, "GIT binary patch")
+
+	mail, err := FormatPatch(repo, base, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(mail, "\nSubject: "))
+	assert.Contains(t, mail, "Subject: [PATCH 1/2] better helper")
+	assert.Contains(t, mail, "Subject: [PATCH 2/2] Uncommitted changes of source")
+
+	// Only the helper is taken, on top of the target's own change.
+	writeTarget("main.go", "package main\n\nfunc main() { println() }\n")
+	helper, err := Patch(repo, base, snapshot, []string{"helper.go", "image.bin"})
+	require.NoError(t, err)
+	require.NoError(t, ApplyPatch(target.GetWorktreePath(), helper))
+	stats := target.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"helper.go", "image.bin", "main.go"}, stats.Paths())
+	target.StopWatching()
+
+	// A patch that doesn't apply changes nothing.
+	full, err := Patch(repo, base, snapshot, nil)
+	require.NoError(t, err)
+	writeTarget("helper.go", "package main\n")
+	assert.Error(t, ApplyPatch(target.GetWorktreePath(), full))
+	content, err := os.ReadFile(filepath.Join(target.GetWorktreePath(), "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() { println() }\n", string(content))
+
+	// The patch takes a renamed file by its old path, and applies whatever prefixes the repository's config asks for.
+	for _, option := range []string{"diff.noprefix", "diff.mnemonicPrefix", "diff.renames"} {
+		output, err = exec.Command("git", "-C", repo, "config", option, "true").CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	require.NoError(t, os.Rename(filepath.Join(source.GetWorktreePath(), "helper.go"),
+		filepath.Join(source.GetWorktreePath(), "util.go")))
+	snapshot, err = source.Snapshot()
+	require.NoError(t, err)
+	renamed, err := Patch(repo, base, snapshot, []string{"helper.go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"helper.go"}, NewDiffStats(renamed).Paths())
+	assert.Contains(t, renamed, "diff --git a/helper.go b/helper.go\ndeleted file mode")
+	mail, err = FormatPatch(repo, base, snapshot)
+	require.NoError(t, err)
+	assert.Contains(t, mail, "diff --git a/helper.go b/helper.go")
+}
@@ -0,0 +1,221 @@
+package git
+
+import (
+	"claude-squad/config"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree is a worktree registered with a repository.
+type Worktree struct {
+	// Path is the path of the worktree.
+	Path string
+	// Branch is the branch checked out in the worktree, or empty if its HEAD is detached.
+	Branch string
+	// Main is true for the repository's main worktree.
+	Main bool
+	// Prunable is true if the worktree's directory is gone, so `git worktree prune` would remove it.
+	Prunable bool
+}
+
+// runGit runs git in dir and returns its output.
+func runGit(dir string, args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s (%w)", strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return string(output), nil
+}
+
+// ListWorktrees returns the worktrees registered with the repository at repoPath.
+func ListWorktrees(repoPath string) ([]Worktree, error) {
+	output, err := runGit(repoPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []Worktree
+	for _, block := range strings.Split(strings.TrimSpace(output), "\n\n") {
+		var wt Worktree
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				wt.Path = value
+			case "branch":
+				wt.Branch = strings.TrimPrefix(value, "refs/heads/")
+			case "prunable":
+				wt.Prunable = true
+			}
+		}
+		if wt.Path == "" {
+			continue
+		}
+		// The main worktree is always listed first.
+		wt.Main = len(worktrees) == 0
+		worktrees = append(worktrees, wt)
+	}
+	return worktrees, nil
+}
+
+// ListBranches returns the local branches of the repository at repoPath whose names start with prefix.
+func ListBranches(repoPath, prefix string) ([]string, error) {
+	output, err := runGit(repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for _, branch := range strings.Split(strings.TrimSpace(output), "\n") {
+		if branch != "" && strings.HasPrefix(branch, prefix) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+// createdKey is the key of a branch's config section that marks the branches claude-squad created, so they can be
+// told apart from the user's own. Deleting a branch with git branch -D removes its section too.
+const createdKey = "claudesquad"
+
+// MarkCreated records in the config of the repository at repoPath that claude-squad created branch.
+func MarkCreated(repoPath, branch string) error {
+	if _, err := runGit(repoPath, "config", "branch."+branch+"."+createdKey, "true"); err != nil {
+		return fmt.Errorf("failed to mark branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// ListCreatedBranches returns the local branches of the repository at repoPath that claude-squad created, as
+// recorded by MarkCreated.
+func ListCreatedBranches(repoPath string) ([]string, error) {
+	output, err := exec.Command("git", "-C", repoPath, "config", "--get-regexp",
+		`^branch\..*\.`+createdKey+`$`).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// No branch is marked
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), "."+createdKey)
+		// A section can outlive its branch if the branch was removed without git branch.
+		if value == "true" && BranchExists(repoPath, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+// BranchExists returns true if the repository at repoPath has a local branch with the given name.
+func BranchExists(repoPath, branch string) bool {
+	_, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// DeleteBranch force deletes a local branch of the repository at repoPath.
+func DeleteBranch(repoPath, branch string) error {
+	if _, err := runGit(repoPath, "branch", "-D", branch); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// RemoveWorktree force removes the worktree at worktreePath from the repository at repoPath, discarding any
+// uncommitted changes. The worktree's branch is kept.
+func RemoveWorktree(repoPath, worktreePath string) error {
+	if _, err := runGit(repoPath, "worktree", "remove", "-f", worktreePath); err != nil {
+		return fmt.Errorf("failed to remove worktree %s: %w", worktreePath, err)
+	}
+	return nil
+}
+
+// PruneWorktrees removes the registrations of worktrees whose directories are gone from the repository at repoPath.
+func PruneWorktrees(repoPath string) error {
+	if _, err := runGit(repoPath, "worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// WorktreeRepo returns the path of the repository that the worktree at worktreePath belongs to. It reads the
+// worktree's .git file, so it works even if the repository no longer knows about the worktree.
+func WorktreeRepo(worktreePath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(worktreePath, ".git"))
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git worktree: %w", worktreePath, err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s is not a git worktree: unexpected .git file", worktreePath)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(worktreePath, gitDir)
+	}
+	// The git dir of a worktree is <repo>/.git/worktrees/<name>.
+	commonDir := filepath.Dir(filepath.Dir(gitDir))
+	if filepath.Base(commonDir) != ".git" {
+		return "", fmt.Errorf("%s is not a git worktree: unexpected git dir %s", worktreePath, gitDir)
+	}
+	return filepath.Dir(commonDir), nil
+}
+
+// RepoRoot returns the root of the repository containing path. If path is in a linked worktree, it returns the root of
+// the repository the worktree belongs to.
+func RepoRoot(path string) (string, error) {
+	output, err := runGit(path, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository: %w", path, err)
+	}
+	commonDir := strings.TrimSpace(output)
+	if filepath.Base(commonDir) != ".git" {
+		return "", fmt.Errorf("%s is in a bare repository", path)
+	}
+	return filepath.Dir(commonDir), nil
+}
+
+// IsWorktreeDirty returns true if the worktree at worktreePath has uncommitted changes or untracked files.
+func IsWorktreeDirty(worktreePath string) (bool, error) {
+	output, err := runGit(worktreePath, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// ResolveBase returns the commit a new branch in the repository at repoPath starts from: ref if it's set, otherwise
+// the repository's configured base branch, otherwise HEAD.
+func ResolveBase(repoPath, ref string) (string, error) {
+	if ref == "" {
+		ref = config.LoadRepoConfig(repoPath).BaseBranch
+	}
+	if ref != "" {
+		if strings.HasPrefix(ref, "-") {
+			return "", fmt.Errorf("invalid base branch %s", ref)
+		}
+		output, err := runGit(repoPath, "rev-parse", "--verify", ref+"^{commit}")
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve base branch %s: %w", ref, err)
+		}
+		return strings.TrimSpace(output), nil
+	}
+
+	output, err := runGit(repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
+			strings.Contains(err.Error(), "fatal: not a valid object name") ||
+			strings.Contains(err.Error(), "fatal: HEAD: not a valid object name") {
+			return "", fmt.Errorf("this appears to be a brand new repository: please create an initial commit before creating an instance")
+		}
+		return "", fmt.Errorf("failed to get HEAD commit hash: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
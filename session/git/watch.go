@@ -0,0 +1,126 @@
+package git
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watcher tracks the paths of a worktree that changed, so its diff is only computed again when there's something
+// new. It uses inotify on Linux, and compares file sizes and modification times every second elsewhere.
+type watcher struct {
+	changes *changeSet
+	closer  io.Closer
+}
+
+// newWatcher starts watching the worktree at root. The .git directory and ignored directories aren't watched.
+func newWatcher(root string) (*watcher, error) {
+	ignored, err := ignoredDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	changes := &changeSet{paths: make(map[string]bool)}
+	closer, err := watchTree(root, ignored, changes)
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{changes: changes, closer: closer}, nil
+}
+
+// Close stops watching.
+func (w *watcher) Close() error {
+	return w.closer.Close()
+}
+
+// changeSet collects the paths that changed, relative to the worktree and slash separated.
+type changeSet struct {
+	mu    sync.Mutex
+	paths map[string]bool
+	// all is true if the watcher lost track of what changed, so everything should be diffed.
+	all bool
+	// first is when the first change since the last take was seen, and last when the last change was seen.
+	first, last time.Time
+}
+
+func (c *changeSet) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths[path] = true
+	// A .gitignore can add files to the diff or take them out anywhere below it
+	if path == ".gitignore" || strings.HasSuffix(path, "/.gitignore") {
+		c.all = true
+	}
+	c.seen(time.Now())
+}
+
+func (c *changeSet) markAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all = true
+	c.seen(time.Now())
+}
+
+// seen records a change at now. mu must be held.
+func (c *changeSet) seen(now time.Time) {
+	if c.first.IsZero() {
+		c.first = now
+	}
+	c.last = now
+}
+
+// due returns true if something changed and it's time to diff it: the worktree was quiet for diffDebounce, or the
+// first change is diffMaxStaleness old, so a program that never stops writing doesn't hold the diff back forever.
+func (c *changeSet) due(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.paths) == 0 && !c.all {
+		return false
+	}
+	return now.Sub(c.last) >= diffDebounce || now.Sub(c.first) >= diffMaxStaleness
+}
+
+// take returns the paths that changed, sorted, and whether everything should be diffed, and starts over.
+func (c *changeSet) take() ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	paths := make([]string, 0, len(c.paths))
+	for path := range c.paths {
+		paths = append(paths, path)
+	}
+	all := c.all
+	c.paths = make(map[string]bool)
+	c.all = false
+	c.first = time.Time{}
+	sort.Strings(paths)
+	return paths, all
+}
+
+// ignoredDirs returns the directories of the worktree at root that git ignores, ex. node_modules, relative to root.
+// Files in them can't change the diff, so they aren't watched.
+func ignoredDirs(root string) (map[string]bool, error) {
+	output, err := exec.Command("git", "-C", root, "ls-files", "-z", "--others", "--ignored", "--exclude-standard",
+		"--directory").Output()
+	if err != nil {
+		return nil, err
+	}
+	ignored := map[string]bool{".git": true}
+	for _, path := range strings.Split(string(output), "\x00") {
+		if strings.HasSuffix(path, "/") {
+			ignored[strings.TrimSuffix(path, "/")] = true
+		}
+	}
+	return ignored, nil
+}
+
+// isIgnored returns true if git ignores the directory at path, relative to root. It's used for directories created
+// after the watch started.
+func isIgnored(root, path string) bool {
+	if filepath.Base(path) == ".git" {
+		return true
+	}
+	return exec.Command("git", "-C", root, "check-ignore", "-q", "--", path).Run() == nil
+}
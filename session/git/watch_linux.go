@@ -0,0 +1,158 @@
+//go:build linux
+
+package git
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask is the events that can change a diff: files written, created, deleted, moved or chmoded.
+const inotifyMask = unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM |
+	unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// inotifyWatcher watches each directory of a worktree with inotify.
+type inotifyWatcher struct {
+	root string
+	fd   int
+	// file reads fd, so Close interrupts a read in progress.
+	file    *os.File
+	changes *changeSet
+
+	// dirs maps watch descriptors to the directory they watch, relative to root. After the watcher starts, it and
+	// ignored are only used by the goroutine reading events.
+	dirs map[int]string
+	// ignored are the directories that aren't watched, relative to root.
+	ignored map[string]bool
+	done    chan struct{}
+}
+
+// watchTree watches root and the directories under it, except ignored ones, and records changes in changes.
+func watchTree(root string, ignored map[string]bool, changes *changeSet) (io.Closer, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &inotifyWatcher{
+		root:    root,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changes: changes,
+		dirs:    make(map[int]string),
+		ignored: ignored,
+		done:    make(chan struct{}),
+	}
+	if err := w.addTree(""); err != nil {
+		w.file.Close()
+		// Most likely out of watches (fs.inotify.max_user_watches) in a large repository
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+// addTree watches the directory dir, relative to root, and the directories under it.
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(filepath.Join(w.root, dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed while we walked
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+		if w.ignored[rel] {
+			return filepath.SkipDir
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+				return nil
+			}
+			return err
+		}
+		w.dirs[wd] = rel
+		return nil
+	})
+}
+
+// read records the events inotify reports until the watcher is closed.
+func (w *inotifyWatcher) read() {
+	defer close(w.done)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			// Closed
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+			w.handle(event, name)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(event *unix.InotifyEvent, name string) {
+	if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+		w.changes.markAll()
+		return
+	}
+
+	dir, ok := w.dirs[int(event.Wd)]
+	if event.Mask&unix.IN_IGNORED != 0 {
+		// The directory was removed, which its parent reports
+		delete(w.dirs, int(event.Wd))
+	}
+	if !ok || name == "" {
+		return
+	}
+
+	path := name
+	if dir != "" {
+		path = dir + "/" + name
+	}
+	if path == ".git" || strings.HasPrefix(path, ".git/") {
+		return
+	}
+	if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		if isIgnored(w.root, path) {
+			w.ignored[path] = true
+			return
+		}
+		if err := w.addTree(path); err != nil {
+			// Out of watches: we can't tell what changes under it anymore
+			w.changes.markAll()
+		}
+	}
+	w.changes.add(path)
+}
+
+// Close stops watching and waits for the events being read to be recorded.
+func (w *inotifyWatcher) Close() error {
+	err := w.file.Close()
+	<-w.done
+	return err
+}
@@ -0,0 +1,118 @@
+//go:build !linux
+
+package git
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// scanInterval is how often the files of a worktree are checked for changes where inotify isn't available.
+const scanInterval = time.Second
+
+// fileState is what a scan remembers about a file to tell whether it changed.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// scanWatcher finds changed files by comparing their size, modification time and mode every scanInterval. It's
+// much cheaper than running git, which reads every file.
+type scanWatcher struct {
+	root    string
+	ignored map[string]bool
+	changes *changeSet
+	files   map[string]fileState
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// watchTree watches root and the files under it, except those in ignored directories, and records changes in changes.
+func watchTree(root string, ignored map[string]bool, changes *changeSet) (io.Closer, error) {
+	w := &scanWatcher{root: root, ignored: ignored, changes: changes, stop: make(chan struct{})}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.update()
+			}
+		}
+	}()
+	return w, nil
+}
+
+// scan returns the state of each file in the worktree, by path relative to root.
+func (w *scanWatcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Removed while we walked
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && (w.ignored[rel] || filepath.Base(rel) == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == ".git" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[rel] = fileState{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+		return nil
+	})
+	return files, err
+}
+
+// update scans the worktree again and records the files that changed, appeared or disappeared.
+func (w *scanWatcher) update() {
+	files, err := w.scan()
+	if err != nil {
+		w.changes.markAll()
+		return
+	}
+	for path, state := range files {
+		if old, ok := w.files[path]; !ok || old != state {
+			w.changes.add(path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			w.changes.add(path)
+		}
+	}
+	w.files = files
+}
+
+// Close stops scanning.
+func (w *scanWatcher) Close() error {
+	close(w.stop)
+	w.wg.Wait()
+	return nil
+}
@@ -4,11 +4,15 @@ import (
 	"claude-squad/config"
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-func getWorktreeDirectory() (string, error) {
+// DefaultWorktreeDirectory returns the directory worktrees are created in if worktree_root isn't set.
+func DefaultWorktreeDirectory() (string, error) {
 	configDir, err := config.GetConfigDir()
 	if err != nil {
 		return "", err
@@ -29,6 +33,24 @@ type GitWorktree struct {
 	branchName string
 	// Base commit hash for the worktree
 	baseCommitSHA string
+	// baseRef is the branch or commit a new worktree starts from, overriding the configured base branch
+	baseRef string
+
+	// diffMu guards the fields below, which let Diff skip git while nothing in the worktree changes.
+	diffMu sync.Mutex
+	// watcher tracks the files changed since the last diff. It's nil until the first diff, or if the worktree can't
+	// be watched.
+	watcher *watcher
+	// watchFailed is true if the worktree can't be watched, so every diff runs git.
+	watchFailed bool
+	// lastDiff is the last diff computed.
+	lastDiff *DiffStats
+	// diffOptions trim the diffs Diff returns. They're read from the config on the first diff, unless diffOptionsSet.
+	diffOptions    config.DiffOptions
+	diffOptionsSet bool
+	// trimmedDiff is trimmedFrom, the last diff computed, trimmed with diffOptions.
+	trimmedDiff *DiffStats
+	trimmedFrom *DiffStats
 }
 
 func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string) *GitWorktree {
@@ -41,12 +63,9 @@ func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName
 	}
 }
 
-// NewGitWorktree creates a new GitWorktree instance
-func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
-	cfg := config.LoadConfig()
-	sanitizedName := sanitizeBranchName(sessionName)
-	branchName := fmt.Sprintf("%s%s", cfg.BranchPrefix, sanitizedName)
-
+// NewGitWorktree creates a new GitWorktree instance. baseRef is the branch or commit its branch starts from; empty
+// uses the configured base branch, or HEAD if there is none.
+func NewGitWorktree(repoPath string, sessionName string, baseRef string) (tree *GitWorktree, branchname string, err error) {
 	// Convert repoPath to absolute path
 	absPath, err := filepath.Abs(repoPath)
 	if err != nil {
@@ -60,22 +79,91 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 		return nil, "", err
 	}
 
-	worktreeDir, err := getWorktreeDirectory()
+	cfg := config.LoadRepoConfig(repoPath)
+	sanitizedName := sanitizeBranchName(sessionName)
+	branchName := fmt.Sprintf("%s%s", cfg.BranchPrefix, sanitizedName)
+
+	worktreePath, err := newWorktreePath(cfg, repoPath, sanitizedName, branchName)
 	if err != nil {
 		return nil, "", err
 	}
 
-	worktreePath := filepath.Join(worktreeDir, sanitizedName)
-	worktreePath = worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano())
-
 	return &GitWorktree{
 		repoPath:     repoPath,
 		sessionName:  sessionName,
 		branchName:   branchName,
 		worktreePath: worktreePath,
+		baseRef:      baseRef,
 	}, branchName, nil
 }
 
+// newWorktreePath returns the path for a new worktree of the repository, following the worktree_root and
+// worktree_name templates. If the path is taken, a numeric suffix is added.
+func newWorktreePath(cfg *config.Config, repoPath, title, branchName string) (string, error) {
+	vars := map[string]string{
+		"repo":   filepath.Base(repoPath),
+		"title":  pathComponent(title),
+		"branch": pathComponent(branchName),
+		"id":     fmt.Sprintf("%x", time.Now().UnixNano()),
+	}
+
+	root := cfg.WorktreeRoot
+	if root == "" {
+		dir, err := DefaultWorktreeDirectory()
+		if err != nil {
+			return "", err
+		}
+		root = dir
+	} else {
+		root = expandTemplate(root, vars)
+		if root == "~" || strings.HasPrefix(root, "~/") {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to expand worktree_root %s: %w", cfg.WorktreeRoot, err)
+			}
+			root = filepath.Join(homeDir, strings.TrimPrefix(root, "~"))
+		}
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(repoPath, root)
+		}
+		root = filepath.Clean(root)
+		if rel, err := filepath.Rel(repoPath, root); err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("worktree_root %s is inside the repository %s", root, repoPath)
+		}
+	}
+
+	template := cfg.WorktreeName
+	if template == "" {
+		template = config.DefaultWorktreeName
+	}
+	name := expandTemplate(template, vars)
+	if strings.Trim(name, ".") == "" {
+		return "", fmt.Errorf("worktree_name %q expands to the invalid name %q", template, name)
+	}
+
+	path := filepath.Join(root, name)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path, nil
+		}
+		path = filepath.Join(root, fmt.Sprintf("%s-%d", name, n))
+	}
+}
+
+// expandTemplate replaces the {name} placeholders in template with their values.
+func expandTemplate(template string, vars map[string]string) string {
+	for name, value := range vars {
+		template = strings.ReplaceAll(template, "{"+name+"}", value)
+	}
+	return template
+}
+
+// pathComponent makes s safe to use as a single path component.
+func pathComponent(s string) string {
+	return strings.ReplaceAll(sanitizeBranchName(s), "/", "-")
+}
+
 // GetWorktreePath returns the path to the worktree
 func (g *GitWorktree) GetWorktreePath() string {
 	return g.worktreePath
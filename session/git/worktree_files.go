@@ -0,0 +1,104 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CopyFromRepo copies the files matching patterns from the main repository into the worktree. Patterns are globs
+// relative to the repository root; matching directories are copied recursively. If symlink is true, symlinks to the
+// files in the repository are created instead of copies. Destinations that already exist in the worktree, such as
+// tracked files, are skipped so checked out content is never clobbered. It returns the worktree-relative paths that
+// were created.
+func (g *GitWorktree) CopyFromRepo(patterns []string, symlink bool) ([]string, error) {
+	var created []string
+	for _, pattern := range patterns {
+		if filepath.IsAbs(pattern) {
+			return created, fmt.Errorf("invalid pattern %q: must be relative to the repository root", pattern)
+		}
+		matches, err := filepath.Glob(filepath.Join(g.repoPath, pattern))
+		if err != nil {
+			return created, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		for _, src := range matches {
+			rel, err := filepath.Rel(g.repoPath, src)
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return created, fmt.Errorf("invalid pattern %q: %s is not inside the repository", pattern, src)
+			}
+			// The worktree has its own git metadata.
+			if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+				continue
+			}
+			dst := filepath.Join(g.worktreePath, rel)
+			if _, err := os.Lstat(dst); err == nil {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return created, fmt.Errorf("failed to create directory for %s: %w", rel, err)
+			}
+			if symlink {
+				err = os.Symlink(src, dst)
+			} else {
+				err = copyPath(src, dst)
+			}
+			if err != nil {
+				return created, fmt.Errorf("failed to copy %s into worktree: %w", rel, err)
+			}
+			created = append(created, rel)
+		}
+	}
+	return created, nil
+}
+
+// copyPath copies the file, symlink or directory at src to dst, preserving permissions.
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
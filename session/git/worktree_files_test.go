@@ -0,0 +1,58 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFromRepo(t *testing.T) {
+	repo := t.TempDir()
+	worktree := t.TempDir()
+	write := func(root, path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0600))
+	}
+	write(repo, ".env", "SECRET=1")
+	write(repo, "config/local.json", "{}")
+	write(repo, "config/dev.json", "{}")
+	write(repo, "node_modules/pkg/index.js", "module.exports = 1")
+	write(repo, "README.md", "repo")
+	write(worktree, "README.md", "worktree")
+
+	g := NewGitWorktreeFromStorage(repo, worktree, "session", "branch", "")
+
+	created, err := g.CopyFromRepo([]string{".env", "config/*.json", "README.md", "missing"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{".env", filepath.Join("config", "dev.json"), filepath.Join("config", "local.json")}, created)
+
+	data, err := os.ReadFile(filepath.Join(worktree, ".env"))
+	require.NoError(t, err)
+	require.Equal(t, "SECRET=1", string(data))
+	info, err := os.Stat(filepath.Join(worktree, ".env"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Existing files in the worktree are left untouched.
+	data, err = os.ReadFile(filepath.Join(worktree, "README.md"))
+	require.NoError(t, err)
+	require.Equal(t, "worktree", string(data))
+
+	created, err = g.CopyFromRepo([]string{"node_modules"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"node_modules"}, created)
+	target, err := os.Readlink(filepath.Join(worktree, "node_modules"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(repo, "node_modules"), target)
+
+	_, err = g.CopyFromRepo([]string{"../outside"}, false)
+	require.NoError(t, err, "patterns without matches are ignored")
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(repo), "outside"), nil, 0644))
+	t.Cleanup(func() { os.Remove(filepath.Join(filepath.Dir(repo), "outside")) })
+	_, err = g.CopyFromRepo([]string{"../outside"}, false)
+	require.Error(t, err)
+	_, err = g.CopyFromRepo([]string{"/etc/passwd"}, false)
+	require.Error(t, err)
+}
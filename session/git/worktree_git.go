@@ -3,14 +3,23 @@ package git
 import (
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
 
 // runGitCommand executes a git command and returns any error
 func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error) {
+	return g.runGitCommandEnv(path, nil, args...)
+}
+
+// runGitCommandEnv executes a git command with env added to the environment, ex. to use another index file.
+func (g *GitWorktree) runGitCommandEnv(path string, env []string, args ...string) (string, error) {
 	baseArgs := []string{"-C", path}
 	cmd := exec.Command("git", append(baseArgs, args...)...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
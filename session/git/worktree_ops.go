@@ -2,9 +2,9 @@ package git
 
 import (
 	"claude-squad/log"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -32,9 +32,8 @@ func (g *GitWorktree) Setup() error {
 
 // SetupFromExistingBranch creates a worktree from an existing branch
 func (g *GitWorktree) SetupFromExistingBranch() error {
-	// Ensure worktrees directory exists
-	worktreesDir := filepath.Join(g.repoPath, "worktrees")
-	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
+	// Ensure the directory the worktree goes in exists
+	if err := os.MkdirAll(filepath.Dir(g.worktreePath), 0755); err != nil {
 		return fmt.Errorf("failed to create worktrees directory: %w", err)
 	}
 
@@ -49,11 +48,11 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 	return nil
 }
 
-// SetupNewWorktree creates a new worktree from HEAD
+// SetupNewWorktree creates a new worktree from the base ref it was created with, the configured base branch, or HEAD if
+// there is neither
 func (g *GitWorktree) SetupNewWorktree() error {
-	// Ensure worktrees directory exists
-	worktreesDir := filepath.Join(g.repoPath, "worktrees")
-	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
+	// Ensure the directory the worktree goes in exists
+	if err := os.MkdirAll(filepath.Dir(g.worktreePath), 0755); err != nil {
 		return fmt.Errorf("failed to create worktrees directory: %w", err)
 	}
 
@@ -71,24 +70,26 @@ func (g *GitWorktree) SetupNewWorktree() error {
 		return fmt.Errorf("failed to cleanup existing branch: %w", err)
 	}
 
-	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	baseCommit, err := ResolveBase(g.repoPath, g.baseRef)
 	if err != nil {
-		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
-			strings.Contains(err.Error(), "fatal: not a valid object name") ||
-			strings.Contains(err.Error(), "fatal: HEAD: not a valid object name") {
-			return fmt.Errorf("this appears to be a brand new repository: please create an initial commit before creating an instance")
-		}
-		return fmt.Errorf("failed to get HEAD commit hash: %w", err)
+		return err
 	}
-	headCommit := strings.TrimSpace(string(output))
-	g.baseCommitSHA = headCommit
+	return g.addWorktree(baseCommit)
+}
+
+// addWorktree creates the worktree on a new branch starting at baseCommit.
+func (g *GitWorktree) addWorktree(baseCommit string) error {
+	g.baseCommitSHA = baseCommit
 
-	// Create a new worktree from the HEAD commit
+	// Create a new worktree from the base commit
 	// Otherwise, we'll inherit uncommitted changes from the previous worktree.
 	// This way, we can start the worktree with a clean slate.
-	// TODO: we might want to give an option to use main/master instead of the current branch.
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
-		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
+	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, baseCommit); err != nil {
+		return fmt.Errorf("failed to create worktree from commit %s: %w", baseCommit, err)
+	}
+	// The branch is ours to delete once nothing uses it, unlike the existing branches sessions can be started on
+	if err := MarkCreated(g.repoPath, g.branchName); err != nil {
+		log.WarningLog.Print(err)
 	}
 
 	return nil
@@ -96,6 +97,7 @@ func (g *GitWorktree) SetupNewWorktree() error {
 
 // Cleanup removes the worktree and associated branch
 func (g *GitWorktree) Cleanup() error {
+	g.StopWatching()
 	var errs []error
 
 	// Check if worktree path exists before attempting removal
@@ -141,6 +143,7 @@ func (g *GitWorktree) Cleanup() error {
 
 // Remove removes the worktree but keeps the branch
 func (g *GitWorktree) Remove() error {
+	g.StopWatching()
 	// Remove the worktree using git command
 	if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath); err != nil {
 		return fmt.Errorf("failed to remove worktree: %w", err)
@@ -157,70 +160,53 @@ func (g *GitWorktree) Prune() error {
 	return nil
 }
 
-// CleanupWorktrees removes all worktrees and their associated branches
-func CleanupWorktrees() error {
-	worktreesDir, err := getWorktreeDirectory()
+// CleanupWorktrees removes the worktrees in the default worktree directory and at the given paths, along with their
+// branches. Each worktree is removed from the repository it belongs to, not the one in the current directory.
+func CleanupWorktrees(paths ...string) error {
+	worktreesDir, err := DefaultWorktreeDirectory()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree directory: %w", err)
 	}
 
 	entries, err := os.ReadDir(worktreesDir)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read worktree directory: %w", err)
 	}
-
-	// Get a list of all branches associated with worktrees
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to list worktrees: %w", err)
-	}
-
-	// Parse the output to extract branch names
-	worktreeBranches := make(map[string]string)
-	currentWorktree := ""
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "worktree ") {
-			currentWorktree = strings.TrimPrefix(line, "worktree ")
-		} else if strings.HasPrefix(line, "branch ") {
-			branchPath := strings.TrimPrefix(line, "branch ")
-			// Extract branch name from refs/heads/branch-name
-			branchName := strings.TrimPrefix(branchPath, "refs/heads/")
-			if currentWorktree != "" {
-				worktreeBranches[currentWorktree] = branchName
-			}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			paths = append(paths, filepath.Join(worktreesDir, entry.Name()))
 		}
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			worktreePath := filepath.Join(worktreesDir, entry.Name())
-
-			// Delete the branch associated with this worktree if found
-			for path, branch := range worktreeBranches {
-				if strings.Contains(path, entry.Name()) {
-					// Delete the branch
-					deleteCmd := exec.Command("git", "branch", "-D", branch)
-					if err := deleteCmd.Run(); err != nil {
-						// Log the error but continue with other worktrees
-						log.ErrorLog.Printf("failed to delete branch %s: %v", branch, err)
-					}
-					break
+	var errs []error
+	repos := make(map[string]bool)
+	for _, path := range paths {
+		if repo, err := WorktreeRepo(path); err == nil {
+			repos[repo] = true
+			branch, branchErr := runGit(path, "symbolic-ref", "--quiet", "--short", "HEAD")
+			if err := RemoveWorktree(repo, path); err != nil {
+				// Log the error but continue with other worktrees
+				log.ErrorLog.Print(err)
+			}
+			if branchErr == nil {
+				if err := DeleteBranch(repo, strings.TrimSpace(branch)); err != nil {
+					log.ErrorLog.Print(err)
 				}
 			}
+		}
 
-			// Remove the worktree directory
-			os.RemoveAll(worktreePath)
+		// Remove whatever is left of the worktree directory
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove worktree directory %s: %w", path, err))
 		}
 	}
 
 	// You have to prune the cleaned up worktrees.
-	cmd = exec.Command("git", "worktree", "prune")
-	_, err = cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to prune worktrees: %w", err)
+	for repo := range repos {
+		if err := PruneWorktrees(repo); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
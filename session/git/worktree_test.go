@@ -0,0 +1,141 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+
+	os.Exit(m.Run())
+}
+
+func TestNewWorktreePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	parent := t.TempDir()
+	repo := filepath.Join(parent, "myrepo")
+	require.NoError(t, os.Mkdir(repo, 0755))
+
+	t.Run("default location and name", func(t *testing.T) {
+		path, err := newWorktreePath(&config.Config{}, repo, "fix bug", "me/fix-bug")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, ".claude-squad", "worktrees"), filepath.Dir(path))
+		assert.Regexp(t, `^fix-bug_[0-9a-f]+$`, filepath.Base(path))
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		cfg := &config.Config{WorktreeRoot: "../{repo}-worktrees", WorktreeName: "{repo}-{title}"}
+		path, err := newWorktreePath(cfg, repo, "fix bug", "me/fix-bug")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(parent, "myrepo-worktrees", "myrepo-fix-bug"), path)
+
+		cfg = &config.Config{WorktreeRoot: "~/wt", WorktreeName: "{branch}"}
+		path, err = newWorktreePath(cfg, repo, "fix bug", "me/fix-bug")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, "wt", "me-fix-bug"), path)
+	})
+
+	t.Run("taken paths get a suffix", func(t *testing.T) {
+		root := t.TempDir()
+		cfg := &config.Config{WorktreeRoot: root, WorktreeName: "{title}"}
+		require.NoError(t, os.Mkdir(filepath.Join(root, "task"), 0755))
+		require.NoError(t, os.Mkdir(filepath.Join(root, "task-2"), 0755))
+		path, err := newWorktreePath(cfg, repo, "task", "me/task")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "task-3"), path)
+	})
+
+	t.Run("root inside the repository is rejected", func(t *testing.T) {
+		for _, root := range []string{".", "worktrees", repo + "/sub"} {
+			_, err := newWorktreePath(&config.Config{WorktreeRoot: root}, repo, "task", "me/task")
+			assert.ErrorContains(t, err, "inside the repository", root)
+		}
+		_, err := newWorktreePath(&config.Config{WorktreeRoot: "../..worktrees"}, repo, "task", "me/task")
+		assert.NoError(t, err)
+	})
+}
+
+func TestSetupCreatesNothingInRepo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	worktreePath := filepath.Join(t.TempDir(), "nested", "task")
+	g := NewGitWorktreeFromStorage(repo, worktreePath, "task", "test/task", "")
+	require.NoError(t, g.Setup())
+	defer g.Cleanup()
+
+	assert.DirExists(t, worktreePath)
+	entries, err := os.ReadDir(repo)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{".git"}, names, "unexpected files in the repository: %s", strings.Join(names, ", "))
+}
+
+func TestCleanupWorktreesUsesEachWorktreesRepo(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	// One worktree in the default directory, one elsewhere.
+	worktreesDir, err := DefaultWorktreeDirectory()
+	require.NoError(t, err)
+	inDefault := NewGitWorktreeFromStorage(repo, filepath.Join(worktreesDir, "a"), "a", "test/a", "")
+	require.NoError(t, inDefault.Setup())
+	elsewhere := NewGitWorktreeFromStorage(repo, filepath.Join(t.TempDir(), "b"), "b", "test/b", "")
+	require.NoError(t, elsewhere.Setup())
+	// Branches created for worktrees are marked as claude-squad's.
+	created, err := ListCreatedBranches(repo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/a", "test/b"}, created)
+
+	// Run from a directory that isn't the repository.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	require.NoError(t, CleanupWorktrees(elsewhere.GetWorktreePath()))
+
+	assert.NoDirExists(t, inDefault.GetWorktreePath())
+	assert.NoDirExists(t, elsewhere.GetWorktreePath())
+	branches, err := ListBranches(repo, "test/")
+	require.NoError(t, err)
+	assert.Empty(t, branches)
+	created, err = ListCreatedBranches(repo)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+	worktrees, err := ListWorktrees(repo)
+	require.NoError(t, err)
+	assert.Len(t, worktrees, 1)
+}
@@ -0,0 +1,509 @@
+package headless
+
+import (
+	"claude-squad/log"
+	"claude-squad/session/agent"
+	"claude-squad/session/recording"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/hinshun/vt10x"
+	"golang.org/x/term"
+)
+
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// HeadlessSession runs a program directly under a PTY without tmux. The program's output is fed into an in-process
+// virtual terminal emulator, which is what Capture renders. Unlike tmux, the program does not outlive this process.
+type HeadlessSession struct {
+	// Initialized by NewHeadlessSession
+	name    string
+	program string
+
+	mu sync.Mutex
+
+	// Initialized by Start
+	//
+	// cmd is the program running under the PTY.
+	cmd *exec.Cmd
+	// ptmx is the PTY master. Writes go to the program's stdin and reads come from its stdout/stderr.
+	ptmx *os.File
+	// vt is the virtual terminal the PTY output is parsed into.
+	vt vt10x.Terminal
+	// width and height are the dimensions of the PTY while detached.
+	width, height int
+	// generation is incremented every time output is read from the PTY. seenGeneration is the generation
+	// observed by the last call to HasUpdated.
+	generation     uint64
+	seenGeneration uint64
+	// exited is closed once the program exits.
+	exited chan struct{}
+	// recorder receives the PTY output if recording is enabled.
+	recorder *recording.Recorder
+	// windows are the companion programs started by OpenWindow, keyed by window name. Each runs under its own PTY.
+	windows map[string]*HeadlessSession
+
+	// Initialized by Attach
+	// Deinitilaized by Detach
+	//
+	// attachCh is closed at the very end of detaching. Used to signal callers.
+	attachCh chan struct{}
+	// out mirrors the PTY output to the attached terminal.
+	out io.Writer
+	// ctx and cancel terminate the window size goroutine on Detach.
+	ctx    context.Context
+	cancel func()
+	wg     *sync.WaitGroup
+}
+
+// NewHeadlessSession creates a new HeadlessSession with the given name and program.
+func NewHeadlessSession(name string, program string) *HeadlessSession {
+	return &HeadlessSession{
+		name:    name,
+		program: program,
+		width:   defaultWidth,
+		height:  defaultHeight,
+	}
+}
+
+// Start runs the program in workDir under a new PTY.
+func (h *HeadlessSession) Start(workDir string) error {
+	if h.Alive() {
+		return fmt.Errorf("headless session already running: %s", h.name)
+	}
+
+	shell := userShell()
+	// Run the program through the shell so programs with arguments (ex. aider --model ...) work the same as tmux.
+	cmd := exec.Command(shell, "-c", h.program)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(h.height), Cols: uint16(h.width)})
+	if err != nil {
+		return fmt.Errorf("error starting headless session: %w", err)
+	}
+
+	h.mu.Lock()
+	h.cmd = cmd
+	h.ptmx = ptmx
+	// The PTY is the writer so the emulator can answer terminal queries like cursor position requests.
+	h.vt = vt10x.New(vt10x.WithSize(h.width, h.height), vt10x.WithWriter(ptmx))
+	h.exited = make(chan struct{})
+	h.mu.Unlock()
+
+	go h.readLoop(ptmx, h.vt)
+	go func(exited chan struct{}) {
+		_ = cmd.Wait()
+		close(exited)
+	}(h.exited)
+
+	if trust := agent.GetTrustScreen(h.program); trust != nil {
+		// Deal with "do you trust the files" screen by sending the response keystrokes.
+		for i := 0; i < trust.Iterations; i++ {
+			time.Sleep(200 * time.Millisecond)
+			content, err := h.Capture()
+			if err != nil {
+				log.ErrorLog.Printf("could not check 'do you trust the files screen': %v", err)
+			}
+			if strings.Contains(content, trust.SearchString) {
+				if err := h.SendKeys(string(trust.Response)); err != nil {
+					log.ErrorLog.Printf("could not tap enter on trust screen: %v", err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// userShell returns the user's shell.
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
+
+// readLoop parses the PTY output into the virtual terminal until the PTY is closed.
+func (h *HeadlessSession) readLoop(ptmx *os.File, vt vt10x.Terminal) {
+	buf := make([]byte, 32*1024)
+	// pending holds the bytes of a multi-byte rune split across reads.
+	var pending []byte
+	for {
+		nr, err := ptmx.Read(buf)
+		if nr > 0 {
+			data := append(pending, buf[:nr]...)
+			written, _ := vt.Write(data)
+			pending = append([]byte(nil), data[written:]...)
+
+			h.mu.Lock()
+			h.generation++
+			out, recorder := h.out, h.recorder
+			h.mu.Unlock()
+			if out != nil {
+				_, _ = out.Write(buf[:nr])
+			}
+			if recorder != nil {
+				if _, err := recorder.Write(buf[:nr]); err != nil {
+					log.ErrorLog.Printf("error recording headless session output: %v", err)
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Restore reconnects to an existing session. Headless sessions die with the process that started them, so
+// there is nothing to reconnect to after a restart.
+func (h *HeadlessSession) Restore() error {
+	if !h.Alive() {
+		return fmt.Errorf("headless session %s is not running: headless sessions do not survive restarts", h.name)
+	}
+	return nil
+}
+
+// SendKeys writes keys to the program's stdin.
+func (h *HeadlessSession) SendKeys(keys string) error {
+	h.mu.Lock()
+	ptmx := h.ptmx
+	h.mu.Unlock()
+	if ptmx == nil {
+		return fmt.Errorf("headless session %s is not running", h.name)
+	}
+	_, err := ptmx.Write([]byte(keys))
+	return err
+}
+
+// TapEnter sends an enter keystroke to the program.
+func (h *HeadlessSession) TapEnter() error {
+	if err := h.SendKeys("\r"); err != nil {
+		return fmt.Errorf("error sending enter keystroke to PTY: %w", err)
+	}
+	return nil
+}
+
+// Capture renders the visible screen of the virtual terminal, including ANSI color codes.
+func (h *HeadlessSession) Capture() (string, error) {
+	h.mu.Lock()
+	vt := h.vt
+	h.mu.Unlock()
+	if vt == nil {
+		return "", fmt.Errorf("headless session %s is not running", h.name)
+	}
+	return renderScreen(vt), nil
+}
+
+// Scrollback returns lines from through to of the screen, inclusive. A negative to returns through the last line.
+// The virtual terminal doesn't keep lines that scroll off the top, so only the visible screen is available.
+func (h *HeadlessSession) Scrollback(from, to int) (string, error) {
+	content, err := h.Capture()
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if to < 0 || to >= len(lines) {
+		to = len(lines) - 1
+	}
+	if from > to {
+		return "", nil
+	}
+	return strings.Join(lines[from:to+1], "\n") + "\n", nil
+}
+
+// HasUpdated checks if the program printed anything since the last call. It also returns true if the screen
+// has a prompt for aider, claude or gemini.
+func (h *HeadlessSession) HasUpdated() (updated bool, hasPrompt bool) {
+	content, err := h.Capture()
+	if err != nil {
+		log.ErrorLog.Printf("error capturing screen content in status monitor: %v", err)
+		return false, false
+	}
+	hasPrompt = agent.HasPrompt(h.program, content)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.generation != h.seenGeneration {
+		h.seenGeneration = h.generation
+		return true, hasPrompt
+	}
+	return false, hasPrompt
+}
+
+// Resize sets the dimensions of the PTY and the virtual terminal.
+func (h *HeadlessSession) Resize(width, height int) error {
+	h.mu.Lock()
+	h.width = width
+	h.height = height
+	h.mu.Unlock()
+	return h.resize(width, height)
+}
+
+func (h *HeadlessSession) resize(width, height int) error {
+	h.mu.Lock()
+	ptmx, vt := h.ptmx, h.vt
+	h.mu.Unlock()
+	if ptmx == nil {
+		return nil
+	}
+	vt.Resize(width, height)
+	return pty.Setsize(ptmx, &pty.Winsize{Rows: uint16(height), Cols: uint16(width)})
+}
+
+// Attach mirrors the program's output to stdout and forwards stdin to the program until ctrl-q is pressed.
+func (h *HeadlessSession) Attach() (chan struct{}, error) {
+	if !h.Alive() {
+		return nil, fmt.Errorf("headless session %s is not running", h.name)
+	}
+
+	h.attachCh = make(chan struct{})
+	h.wg = &sync.WaitGroup{}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+
+	// Redraw the current screen, then stream new output as it arrives.
+	content, _ := h.Capture()
+	fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J"+strings.ReplaceAll(content, "\n", "\r\n"))
+	h.mu.Lock()
+	h.out = os.Stdout
+	h.mu.Unlock()
+
+	go func() {
+		buf := make([]byte, 32)
+		for {
+			nr, err := os.Stdin.Read(buf)
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				continue
+			}
+
+			// Check for Ctrl+q (ASCII 17)
+			if nr == 1 && buf[0] == 17 {
+				h.Detach()
+				return
+			}
+
+			if err := h.SendKeys(string(buf[:nr])); err != nil {
+				log.ErrorLog.Printf("error forwarding input to headless session: %v", err)
+			}
+		}
+	}()
+
+	h.monitorWindowSize()
+	return h.attachCh, nil
+}
+
+// monitorWindowSize resizes the PTY to the attached terminal, polling for size changes.
+func (h *HeadlessSession) monitorWindowSize() {
+	lastCols, lastRows := 0, 0
+	doUpdate := func() {
+		cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
+		if err != nil || (cols == lastCols && rows == lastRows) {
+			return
+		}
+		lastCols, lastRows = cols, rows
+		if err := h.resize(cols, rows); err != nil {
+			log.ErrorLog.Printf("failed to update window size: %v", err)
+		}
+	}
+	doUpdate()
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.ctx.Done():
+				return
+			case <-ticker.C:
+				doUpdate()
+			}
+		}
+	}()
+}
+
+// Detach stops mirroring output and restores the detached window size.
+func (h *HeadlessSession) Detach() {
+	defer func() {
+		close(h.attachCh)
+		h.attachCh = nil
+		h.cancel = nil
+		h.ctx = nil
+		h.wg = nil
+	}()
+
+	h.mu.Lock()
+	h.out = nil
+	width, height := h.width, h.height
+	h.mu.Unlock()
+
+	h.cancel()
+	h.wg.Wait()
+
+	if err := h.resize(width, height); err != nil {
+		log.ErrorLog.Printf("failed to restore window size: %v", err)
+	}
+}
+
+// Record tees the PTY output into the asciicast recording at path.
+func (h *HeadlessSession) Record(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.recorder != nil {
+		return nil
+	}
+	recorder, err := recording.Open(path, h.width, h.height, h.name)
+	if err != nil {
+		return err
+	}
+	h.recorder = recorder
+	return nil
+}
+
+// OpenWindow starts command in workDir as a companion of the program. An empty command starts the user's shell.
+func (h *HeadlessSession) OpenWindow(name, workDir, command string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.windows[name]; ok {
+		return fmt.Errorf("window %s already exists", name)
+	}
+	if command == "" {
+		command = userShell()
+	}
+	window := NewHeadlessSession(h.name+":"+name, command)
+	window.width, window.height = h.width, h.height
+	if err := window.Start(workDir); err != nil {
+		return fmt.Errorf("error starting window %s: %w", name, err)
+	}
+	if h.windows == nil {
+		h.windows = make(map[string]*HeadlessSession)
+	}
+	h.windows[name] = window
+	return nil
+}
+
+// window returns the companion window with the given name.
+func (h *HeadlessSession) window(name string) (*HeadlessSession, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	window, ok := h.windows[name]
+	if !ok {
+		return nil, fmt.Errorf("window %s does not exist", name)
+	}
+	return window, nil
+}
+
+// CloseWindow kills the companion window with the given name.
+func (h *HeadlessSession) CloseWindow(name string) error {
+	window, err := h.window(name)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	delete(h.windows, name)
+	h.mu.Unlock()
+	return window.Close()
+}
+
+// SendWindowKeys writes keys to the stdin of the companion window with the given name.
+func (h *HeadlessSession) SendWindowKeys(name, keys string) error {
+	window, err := h.window(name)
+	if err != nil {
+		return err
+	}
+	return window.SendKeys(keys)
+}
+
+// CaptureWindow renders the screen of the companion window with the given name.
+func (h *HeadlessSession) CaptureWindow(name string) (string, error) {
+	window, err := h.window(name)
+	if err != nil {
+		return "", err
+	}
+	return window.Capture()
+}
+
+// AttachWindow attaches to the companion window with the given name.
+func (h *HeadlessSession) AttachWindow(name string) (chan struct{}, error) {
+	window, err := h.window(name)
+	if err != nil {
+		return nil, err
+	}
+	return window.Attach()
+}
+
+// WindowAlive returns true if the command of the companion window with the given name is still running.
+func (h *HeadlessSession) WindowAlive(name string) bool {
+	window, err := h.window(name)
+	return err == nil && window.Alive()
+}
+
+// Alive returns true if the program is still running.
+func (h *HeadlessSession) Alive() bool {
+	h.mu.Lock()
+	exited := h.exited
+	h.mu.Unlock()
+	if exited == nil {
+		return false
+	}
+	select {
+	case <-exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close kills the program and its companion windows, and closes the PTY.
+func (h *HeadlessSession) Close() error {
+	h.mu.Lock()
+	cmd, ptmx, exited, recorder, windows := h.cmd, h.ptmx, h.exited, h.recorder, h.windows
+	h.cmd = nil
+	h.ptmx = nil
+	h.recorder = nil
+	h.windows = nil
+	h.mu.Unlock()
+
+	var errs []error
+	for name, window := range windows {
+		if err := window.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing window %s: %w", name, err))
+		}
+	}
+	if cmd != nil && cmd.Process != nil {
+		select {
+		case <-exited:
+		default:
+			if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				errs = append(errs, fmt.Errorf("error killing program: %w", err))
+			}
+			<-exited
+		}
+	}
+	if ptmx != nil {
+		if err := ptmx.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing PTY: %w", err))
+		}
+	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing recording: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package headless
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderScreen(t *testing.T) {
+	vt := vt10x.New(vt10x.WithSize(20, 3))
+	_, err := vt.Write([]byte("plain\r\n\x1b[31mred\x1b[0m text"))
+	require.NoError(t, err)
+
+	lines := strings.Split(renderScreen(vt), "\n")
+	require.Equal(t, "plain", lines[0])
+	require.Equal(t, "\x1b[0;38;5;1mred\x1b[0m text", lines[1])
+	require.Equal(t, "", lines[2])
+}
+
+func TestHeadlessSession(t *testing.T) {
+	session := NewHeadlessSession("test-session", "echo hello && cat")
+	require.NoError(t, session.Start(t.TempDir()))
+	defer session.Close()
+
+	require.Eventually(t, func() bool {
+		content, err := session.Capture()
+		return err == nil && strings.Contains(content, "hello")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	updated, hasPrompt := session.HasUpdated()
+	require.True(t, updated)
+	require.False(t, hasPrompt)
+
+	require.NoError(t, session.SendKeys("ping"))
+	require.NoError(t, session.TapEnter())
+	require.Eventually(t, func() bool {
+		content, err := session.Capture()
+		return err == nil && strings.Contains(content, "ping")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	scrollback, err := session.Scrollback(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", scrollback)
+
+	require.NoError(t, session.Resize(40, 10))
+	require.True(t, session.Alive())
+	require.Error(t, session.Start(t.TempDir()))
+
+	require.NoError(t, session.Close())
+	require.False(t, session.Alive())
+	require.Error(t, session.Restore())
+}
+
+func TestHeadlessSessionWindows(t *testing.T) {
+	session := NewHeadlessSession("test-session", "cat")
+	require.NoError(t, session.Start(t.TempDir()))
+	defer session.Close()
+
+	require.NoError(t, session.OpenWindow("echo", t.TempDir(), "cat"))
+	require.Error(t, session.OpenWindow("echo", t.TempDir(), "cat"))
+
+	require.NoError(t, session.SendWindowKeys("echo", "from window\r"))
+	require.Eventually(t, func() bool {
+		content, err := session.CaptureWindow("echo")
+		return err == nil && strings.Contains(content, "from window")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	content, err := session.Capture()
+	require.NoError(t, err)
+	require.NotContains(t, content, "from window")
+
+	require.NoError(t, session.CloseWindow("echo"))
+	_, err = session.CaptureWindow("echo")
+	require.Error(t, err)
+	require.False(t, session.WindowAlive("echo"))
+
+	// A window whose command exited is still there, but not alive.
+	require.NoError(t, session.OpenWindow("done", t.TempDir(), "true"))
+	require.Eventually(t, func() bool { return !session.WindowAlive("done") }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,94 @@
+package headless
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Glyph attribute bits used by vt10x. They're unexported upstream.
+const (
+	attrReverse = 1 << iota
+	attrUnderline
+	attrBold
+	attrGfx
+	attrItalic
+)
+
+// renderScreen renders the virtual terminal as lines of text with SGR escape sequences, similar to
+// `tmux capture-pane -p -e`. Trailing blanks on each line are dropped.
+func renderScreen(vt vt10x.View) string {
+	vt.Lock()
+	defer vt.Unlock()
+
+	cols, rows := vt.Size()
+	var b strings.Builder
+	for y := 0; y < rows; y++ {
+		// Find the last non-blank cell so we don't pad lines with spaces.
+		end := cols
+		for end > 0 {
+			g := vt.Cell(end-1, y)
+			if (g.Char != ' ' && g.Char != 0) || g.BG != vt10x.DefaultBG {
+				break
+			}
+			end--
+		}
+
+		prev := vt10x.Glyph{FG: vt10x.DefaultFG, BG: vt10x.DefaultBG}
+		for x := 0; x < end; x++ {
+			g := vt.Cell(x, y)
+			if g.FG != prev.FG || g.BG != prev.BG || g.Mode != prev.Mode {
+				b.WriteString(sgr(g))
+				prev = g
+			}
+			if g.Char == 0 {
+				b.WriteRune(' ')
+			} else {
+				b.WriteRune(g.Char)
+			}
+		}
+		if prev.FG != vt10x.DefaultFG || prev.BG != vt10x.DefaultBG || prev.Mode != 0 {
+			b.WriteString("\x1b[0m")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// sgr returns the escape sequence that resets the attributes and then applies the glyph's attributes.
+func sgr(g vt10x.Glyph) string {
+	params := []string{"0"}
+	if g.Mode&attrBold != 0 {
+		params = append(params, "1")
+	}
+	if g.Mode&attrItalic != 0 {
+		params = append(params, "3")
+	}
+	if g.Mode&attrUnderline != 0 {
+		params = append(params, "4")
+	}
+	if g.Mode&attrReverse != 0 {
+		params = append(params, "7")
+	}
+	if p := colorParam(g.FG, 38); p != "" {
+		params = append(params, p)
+	}
+	if p := colorParam(g.BG, 48); p != "" {
+		params = append(params, p)
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// colorParam returns the SGR parameter for a color. base is 38 for foreground and 48 for background.
+func colorParam(c vt10x.Color, base int) string {
+	switch {
+	case c == vt10x.DefaultFG || c == vt10x.DefaultBG:
+		return ""
+	case c < 256:
+		return fmt.Sprintf("%d;5;%d", base, c)
+	case c < 1<<24:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, (c>>16)&0xff, (c>>8)&0xff, c&0xff)
+	}
+	return ""
+}
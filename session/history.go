@@ -0,0 +1,85 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"slices"
+	"time"
+)
+
+const (
+	// diffHistoryInterval is how far apart snapshots taken between turns are kept. A snapshot that would be closer
+	// than that to the one before the last replaces the last, so a program editing a file many times in a few seconds
+	// takes few snapshots.
+	diffHistoryInterval = 10 * time.Second
+	// maxDiffHistory is how many snapshots an instance keeps. The oldest are dropped first.
+	maxDiffHistory = 500
+	// maxTurnDiffBytes is how large the diffs kept with turn snapshots get in total, since they're saved in
+	// state.json. The diffs of the oldest turns are dropped first, keeping their line counts.
+	maxTurnDiffBytes = 8 << 20
+)
+
+// DiffSnapshot is the size of an instance's diff at some point in time.
+type DiffSnapshot struct {
+	Time    time.Time `json:"time"`
+	Added   int       `json:"added"`
+	Removed int       `json:"removed"`
+	Files   int       `json:"files"`
+	// Turn is true if the snapshot was taken when the program finished a turn, going from running to ready.
+	Turn bool `json:"turn,omitempty"`
+	// Content is the whole diff at the end of a turn. It's only kept with diff.keep_turn_diffs.
+	Content string `json:"content,omitempty"`
+}
+
+// DiffHistory returns the snapshots of the instance's diff, oldest first.
+func (i *Instance) DiffHistory() []DiffSnapshot {
+	i.historyMu.Lock()
+	defer i.historyMu.Unlock()
+	return slices.Clone(i.history)
+}
+
+// recordDiff adds a snapshot of stats to the history. Snapshots that don't change the diff since the last one are
+// skipped, and ones between turns close to the last snapshot replace it.
+func (i *Instance) recordDiff(stats *git.DiffStats, turn bool, now time.Time) {
+	snapshot := DiffSnapshot{
+		Time:    now,
+		Added:   stats.Added,
+		Removed: stats.Removed,
+		Files:   len(stats.Files),
+		Turn:    turn,
+	}
+	if turn && i.gitWorktree != nil && i.gitWorktree.DiffOptions().KeepTurnDiffs {
+		snapshot.Content = stats.Content
+	}
+
+	i.historyMu.Lock()
+	defer i.historyMu.Unlock()
+	if n := len(i.history); n > 0 {
+		last := i.history[n-1]
+		same := last.Added == snapshot.Added && last.Removed == snapshot.Removed && last.Files == snapshot.Files
+		// A turn ending is guessed from the program being quiet, so it can end again without changing anything.
+		if same && (!turn || last.Turn && last.Content == snapshot.Content) {
+			return
+		}
+		// Compared with the snapshot before, so steady changes still take a snapshot every interval.
+		if !turn && n > 1 && !last.Turn && now.Sub(i.history[n-2].Time) < diffHistoryInterval {
+			i.history[n-1] = snapshot
+			return
+		}
+	}
+	i.appendSnapshot(snapshot)
+}
+
+// appendSnapshot adds a snapshot to the history, dropping the oldest snapshots and turn diffs past the limits.
+func (i *Instance) appendSnapshot(snapshot DiffSnapshot) {
+	i.history = append(i.history, snapshot)
+	if len(i.history) > maxDiffHistory {
+		i.history = slices.Delete(i.history, 0, len(i.history)-maxDiffHistory)
+	}
+	size := 0
+	for j := len(i.history) - 1; j >= 0; j-- {
+		size += len(i.history[j].Content)
+		if size > maxTurnDiffBytes {
+			i.history[j].Content = ""
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffHistory(t *testing.T) {
+	worktree := git.NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "history", "history", "")
+	worktree.SetDiffOptions(config.DiffOptions{KeepTurnDiffs: true})
+	instance := &Instance{Title: "history", gitWorktree: worktree}
+	diff := func(added int) *git.DiffStats {
+		return &git.DiffStats{Added: added, Removed: 1, Content: "diff", Files: make([]git.FileDiff, 1)}
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	instance.recordDiff(diff(1), false, at(0))
+	instance.recordDiff(diff(2), false, at(5))
+	// Close to the one before, so it replaces the last snapshot.
+	instance.recordDiff(diff(3), false, at(8))
+	// The same counts are skipped.
+	instance.recordDiff(diff(3), false, at(20))
+	instance.recordDiff(diff(4), false, at(25))
+	// Turns are always kept, with their diff.
+	instance.recordDiff(diff(4), true, at(26))
+	// A turn that changes nothing is skipped.
+	instance.recordDiff(diff(4), true, at(27))
+	instance.recordDiff(diff(5), false, at(27))
+
+	history := instance.DiffHistory()
+	require.Len(t, history, 5)
+	var added []int
+	for _, snapshot := range history {
+		added = append(added, snapshot.Added)
+	}
+	assert.Equal(t, []int{1, 3, 4, 4, 5}, added)
+	assert.Equal(t, at(8), history[1].Time)
+	assert.True(t, history[3].Turn)
+	assert.Equal(t, "diff", history[3].Content)
+	assert.Empty(t, history[4].Content)
+	assert.Equal(t, 1, history[4].Files)
+
+	// The diff isn't kept without diff.keep_turn_diffs.
+	worktree.SetDiffOptions(config.DiffOptions{})
+	instance.recordDiff(diff(6), true, at(60))
+	assert.Empty(t, instance.DiffHistory()[5].Content)
+
+	for i := range maxDiffHistory {
+		instance.recordDiff(diff(i+10), false, at(100+i*int(diffHistoryInterval.Seconds())))
+	}
+	history = instance.DiffHistory()
+	assert.Len(t, history, maxDiffHistory)
+	assert.Equal(t, maxDiffHistory+9, history[len(history)-1].Added)
+
+	// The oldest turn diffs are dropped past maxTurnDiffBytes.
+	worktree.SetDiffOptions(config.DiffOptions{KeepTurnDiffs: true})
+	large := func(added int) *git.DiffStats {
+		return &git.DiffStats{Added: added, Content: strings.Repeat("x", maxTurnDiffBytes/2)}
+	}
+	for j := range 3 {
+		instance.recordDiff(large(j+1000), true, at(10000+j))
+	}
+	history = instance.DiffHistory()
+	require.Len(t, history, maxDiffHistory)
+	n := len(history)
+	assert.Empty(t, history[n-3].Content)
+	assert.NotEmpty(t, history[n-2].Content)
+	assert.NotEmpty(t, history[n-1].Content)
+
+	// The history survives a round trip through storage.
+	restored := &Instance{history: instance.ToInstanceData().DiffHistory}
+	assert.Equal(t, history, restored.DiffHistory())
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// HookPostSetup runs in a new worktree before the program starts.
+	HookPostSetup = "post_setup"
+	// HookPreCleanup runs in a worktree before it's removed.
+	HookPreCleanup = "pre_cleanup"
+)
+
+// hookErrorLines is the number of trailing output lines included in a hook failure error.
+const hookErrorLines = 20
+
+// hookTimeout is how long a hook command can run before it's killed, so a hung command can't block starting or
+// tearing down the instance forever.
+var hookTimeout = 10 * time.Minute
+
+// HookResult is the outcome of one worktree hook command.
+type HookResult struct {
+	// Hook is HookPostSetup or HookPreCleanup.
+	Hook string
+	// Command is the shell command that was run.
+	Command string
+	// Output is the combined stdout and stderr of the command.
+	Output string
+	// ExitCode is the exit code of the command, or -1 if it couldn't be run.
+	ExitCode int
+	// Duration is how long the command ran.
+	Duration time.Duration
+	// Err is non-nil if the command failed.
+	Err error
+}
+
+// prepareWorktree copies files from the main repository into a freshly set up worktree and runs the post_setup
+// hooks. It fails on the first error so the program never starts in a half prepared worktree.
+func (i *Instance) prepareWorktree() error {
+	setup := config.LoadRepoConfig(i.gitWorktree.GetRepoPath()).WorktreeSetup
+
+	if _, err := i.gitWorktree.CopyFromRepo(setup.Copy, false); err != nil {
+		return fmt.Errorf("failed to copy files into worktree: %w", err)
+	}
+	if _, err := i.gitWorktree.CopyFromRepo(setup.Symlink, true); err != nil {
+		return fmt.Errorf("failed to symlink files into worktree: %w", err)
+	}
+
+	for _, command := range setup.PostSetup {
+		if result := i.runHook(HookPostSetup, command); result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// runPreCleanup runs the pre_cleanup hooks if the worktree still exists. Teardown shouldn't be blocked by a
+// failing hook, so failures are only logged and reported through OnHook.
+func (i *Instance) runPreCleanup() {
+	if i.gitWorktree == nil {
+		return
+	}
+	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err != nil {
+		return
+	}
+	for _, command := range config.LoadRepoConfig(i.gitWorktree.GetRepoPath()).WorktreeSetup.PreCleanup {
+		if result := i.runHook(HookPreCleanup, command); result.Err != nil {
+			log.ErrorLog.Print(result.Err)
+		}
+	}
+}
+
+// runHook runs a hook command in the worktree and reports the result to OnHook.
+func (i *Instance) runHook(hook, command string) HookResult {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// Don't wait on processes the command started in the background that still hold its output open.
+	cmd.WaitDelay = time.Second
+	cmd.Dir = i.gitWorktree.GetWorktreePath()
+	cmd.Env = append(os.Environ(),
+		"CLAUDE_SQUAD_SESSION="+i.Title,
+		"CLAUDE_SQUAD_REPO="+i.gitWorktree.GetRepoPath(),
+		"CLAUDE_SQUAD_WORKTREE="+i.gitWorktree.GetWorktreePath(),
+		"CLAUDE_SQUAD_BRANCH="+i.gitWorktree.GetBranchName(),
+	)
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	result := HookResult{
+		Hook:     hook,
+		Command:  command,
+		Output:   string(output),
+		Duration: time.Since(start),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		result.ExitCode = -1
+		result.Err = fmt.Errorf("%s hook %q timed out after %s%s", hook, command, hookTimeout, outputTail(result.Output))
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Err = fmt.Errorf("%s hook %q failed with exit code %d%s", hook, command, result.ExitCode,
+			outputTail(result.Output))
+	default:
+		result.ExitCode = -1
+		result.Err = fmt.Errorf("%s hook %q could not be run: %w", hook, command, err)
+	}
+
+	log.InfoLog.Printf("%s hook %q for %s finished in %s (exit code %d)", hook, command, i.Title,
+		result.Duration.Round(time.Millisecond), result.ExitCode)
+	if i.OnHook != nil {
+		i.OnHook(result)
+	}
+	return result
+}
+
+// outputTail formats the last lines of a hook's output for an error message.
+func outputTail(output string) string {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return ""
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) > hookErrorLines {
+		lines = lines[len(lines)-hookErrorLines:]
+	}
+	return ":\n" + strings.Join(lines, "\n")
+}
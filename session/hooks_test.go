@@ -0,0 +1,38 @@
+package session
+
+import (
+	"claude-squad/log"
+	"claude-squad/session/git"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHook(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	worktree := git.NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "hooks", "hooks", "")
+	var results []HookResult
+	instance := &Instance{Title: "hooks", gitWorktree: worktree, OnHook: func(result HookResult) {
+		results = append(results, result)
+	}}
+
+	result := instance.runHook(HookPostSetup, `echo "$CLAUDE_SQUAD_SESSION"; exit 3`)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "hooks\n", result.Output)
+	require.Error(t, result.Err)
+	assert.Contains(t, result.Err.Error(), "exit code 3")
+
+	// A hung command is killed, even with a background process holding its output open.
+	defer func(timeout time.Duration) { hookTimeout = timeout }(hookTimeout)
+	hookTimeout = 100 * time.Millisecond
+	start := time.Now()
+	result = instance.runHook(HookPreCleanup, "echo waiting; sleep 10 & sleep 10")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, -1, result.ExitCode)
+	require.Error(t, result.Err)
+	assert.Contains(t, result.Err.Error(), "timed out after 100ms:\nwaiting")
+	assert.Len(t, results, 2)
+}
@@ -0,0 +1,74 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// PauseReasonIdle means the instance was paused after sitting ready with no new output for too long.
+	PauseReasonIdle = "idle"
+	// PauseReasonMaxRunning means the instance was paused to keep the number of running instances under the limit.
+	PauseReasonMaxRunning = "max_running"
+)
+
+// IdlePolicy decides which instances to pause automatically.
+type IdlePolicy struct {
+	// PauseAfter is how long an instance can be ready with no new output before it's paused. Zero disables it.
+	PauseAfter time.Duration
+	// MaxRunning is the number of instances that can be unpaused at once. Zero means no limit.
+	MaxRunning int
+}
+
+// NewIdlePolicy returns the idle policy configured in cfg.
+func NewIdlePolicy(cfg *config.Config) IdlePolicy {
+	return IdlePolicy{
+		PauseAfter: time.Duration(cfg.IdlePauseMinutes) * time.Minute,
+		MaxRunning: cfg.MaxRunningSessions,
+	}
+}
+
+// Enabled returns true if the policy would ever pause anything.
+func (p IdlePolicy) Enabled() bool {
+	return p.PauseAfter > 0 || p.MaxRunning > 0
+}
+
+// Select returns the instances that should be paused at now, mapped to the reason why. Idle instances are picked
+// first. If that still leaves more than MaxRunning instances, the least recently active ones are picked too.
+func (p IdlePolicy) Select(instances []*Instance, now time.Time) map[*Instance]string {
+	selected := make(map[*Instance]string)
+	var running []*Instance
+	for _, instance := range instances {
+		if instance.inactive() {
+			continue
+		}
+		if p.PauseAfter > 0 && instance.Status == Ready && now.Sub(instance.lastActivity) >= p.PauseAfter {
+			selected[instance] = PauseReasonIdle
+			continue
+		}
+		running = append(running, instance)
+	}
+
+	if p.MaxRunning > 0 && len(running) > p.MaxRunning {
+		sort.SliceStable(running, func(a, b int) bool {
+			return running[a].lastActivity.Before(running[b].lastActivity)
+		})
+		for _, instance := range running[:len(running)-p.MaxRunning] {
+			selected[instance] = PauseReasonMaxRunning
+		}
+	}
+	return selected
+}
+
+// Pause pauses an instance picked by Select for reason. Unlike Instance.Pause, it leaves the clipboard alone. An
+// instance that fails to pause is treated as active, so it isn't picked again until it goes idle again.
+func (p IdlePolicy) Pause(instance *Instance, reason string, now time.Time) error {
+	if err := instance.pause(); err != nil {
+		instance.lastActivity = now
+		return fmt.Errorf("failed to auto-pause %s: %w", instance.Title, err)
+	}
+	instance.PauseReason = reason
+	return nil
+}
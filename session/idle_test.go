@@ -0,0 +1,49 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdlePolicySelect(t *testing.T) {
+	now := time.Now()
+	newInstance := func(title string, status Status, idle time.Duration) *Instance {
+		return &Instance{Title: title, Status: status, started: true, lastActivity: now.Add(-idle)}
+	}
+	ready := newInstance("ready", Ready, time.Hour)
+	fresh := newInstance("fresh", Ready, time.Minute)
+	busy := newInstance("busy", Running, 2*time.Hour)
+	recent := newInstance("recent", Running, time.Second)
+	paused := newInstance("paused", Paused, 3*time.Hour)
+	crashed := newInstance("crashed", Crashed, 3*time.Hour)
+	loading := &Instance{Title: "loading", Status: Loading}
+	instances := []*Instance{ready, fresh, busy, recent, paused, crashed, loading}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Empty(t, IdlePolicy{}.Select(instances, now))
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		policy := IdlePolicy{PauseAfter: 30 * time.Minute}
+		// Only ready instances count as idle, however long a running one has gone without output.
+		assert.Equal(t, map[*Instance]string{ready: PauseReasonIdle}, policy.Select(instances, now))
+	})
+
+	t.Run("max running", func(t *testing.T) {
+		policy := IdlePolicy{MaxRunning: 2}
+		assert.Equal(t, map[*Instance]string{
+			busy:  PauseReasonMaxRunning,
+			ready: PauseReasonMaxRunning,
+		}, policy.Select(instances, now))
+	})
+
+	t.Run("idle instances count towards the limit", func(t *testing.T) {
+		policy := IdlePolicy{PauseAfter: 30 * time.Minute, MaxRunning: 2}
+		assert.Equal(t, map[*Instance]string{
+			ready: PauseReasonIdle,
+			busy:  PauseReasonMaxRunning,
+		}, policy.Select(instances, now))
+	})
+}
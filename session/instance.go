@@ -1,14 +1,19 @@
 package session
 
 import (
+	"claude-squad/config"
 	"claude-squad/log"
 	"claude-squad/session/git"
-	"claude-squad/session/tmux"
+	"claude-squad/session/recording"
+	"errors"
 	"path/filepath"
+	"regexp"
 
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/atotto/clipboard"
@@ -25,8 +30,14 @@ const (
 	Loading
 	// Paused is if the instance is paused (worktree removed but branch preserved).
 	Paused
+	// Crashed is if the instance's terminal session died while it was running, ex. after a reboot. The worktree is
+	// preserved, and Revive runs the program in it again.
+	Crashed
 )
 
+// ErrSessionDead is returned when restoring an instance whose terminal session no longer exists.
+var ErrSessionDead = errors.New("terminal session is no longer running")
+
 // Instance is a running instance of claude code.
 type Instance struct {
 	// Title is the title of the instance.
@@ -51,6 +62,22 @@ type Instance struct {
 	AutoYes bool
 	// Prompt is the initial prompt to pass to the instance on startup
 	Prompt string
+	// LastPrompt is the last prompt sent with SendPrompt.
+	LastPrompt string
+	// PauseReason is set when the instance was paused automatically, to PauseReasonIdle or PauseReasonMaxRunning.
+	PauseReason string
+	// Group is shared by sibling instances started together by a fan-out. It's empty for other instances.
+	Group string
+	// TimeToReady is how long the program took to become ready after its first prompt. Zero until it has.
+	TimeToReady time.Duration
+	// Backend is the terminal backend the program runs in (BackendTmux or BackendHeadless).
+	Backend string
+	// HistoryLimit is the number of scrollback lines the terminal retains. Zero uses the backend's default.
+	HistoryLimit int
+	// Windows are the companion windows that run alongside the program in the worktree.
+	Windows []Window
+	// OnHook is called with the result of every worktree hook command. It may be nil.
+	OnHook func(HookResult)
 
 	// DiffStats stores the current git diff statistics
 	diffStats *git.DiffStats
@@ -58,10 +85,30 @@ type Instance struct {
 	// The below fields are initialized upon calling Start().
 
 	started bool
-	// tmuxSession is the tmux session for the instance.
-	tmuxSession *tmux.TmuxSession
+	// terminal is the terminal session (tmux or headless) for the instance.
+	terminal Terminal
 	// gitWorktree is the git worktree for the instance.
 	gitWorktree *git.GitWorktree
+	// baseRef is the branch or commit the branch of a new instance starts from.
+	baseRef string
+	// queue holds the prompts waiting for DeliverQueued.
+	queue   []string
+	queueMu sync.Mutex
+	// comments are the review comments on the instance's changes, guarded by reviewMu.
+	comments []ReviewComment
+	reviewMu sync.Mutex
+	// history holds the snapshots of the diff over time, guarded by historyMu.
+	history   []DiffSnapshot
+	historyMu sync.Mutex
+	// stopping is set while Pause or Kill tear the instance down, so DetectCrash doesn't take the terminal they
+	// closed for a crash.
+	stopping atomic.Bool
+	// turnEnded is set when the program finishes a turn, so the next diff update takes a turn snapshot.
+	turnEnded bool
+	// lastActivity is when the instance was last started, sent input or produced output.
+	lastActivity time.Time
+	// firstPromptAt is when the first prompt was sent, for TimeToReady.
+	firstPromptAt time.Time
 }
 
 // ToInstanceData converts an Instance to its serializable form
@@ -77,6 +124,17 @@ func (i *Instance) ToInstanceData() InstanceData {
 		UpdatedAt: time.Now(),
 		Program:   i.Program,
 		AutoYes:   i.AutoYes,
+		Backend:   i.Backend,
+
+		LastPrompt:   i.LastPrompt,
+		PauseReason:  i.PauseReason,
+		Group:        i.Group,
+		TimeToReady:  i.TimeToReady,
+		Queue:        i.QueuedPrompts(),
+		Comments:     i.Comments(),
+		DiffHistory:  i.DiffHistory(),
+		HistoryLimit: i.HistoryLimit,
+		Windows:      i.Windows,
 	}
 
 	// Only include worktree data if gitWorktree is initialized
@@ -114,6 +172,17 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		CreatedAt: data.CreatedAt,
 		UpdatedAt: data.UpdatedAt,
 		Program:   data.Program,
+		Backend:   data.Backend,
+
+		LastPrompt:   data.LastPrompt,
+		PauseReason:  data.PauseReason,
+		Group:        data.Group,
+		TimeToReady:  data.TimeToReady,
+		HistoryLimit: data.HistoryLimit,
+		Windows:      data.Windows,
+		queue:        data.Queue,
+		comments:     data.Comments,
+		history:      data.DiffHistory,
 		gitWorktree: git.NewGitWorktreeFromStorage(
 			data.Worktree.RepoPath,
 			data.Worktree.WorktreePath,
@@ -125,16 +194,25 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 			Added:   data.DiffStats.Added,
 			Removed: data.DiffStats.Removed,
 			Content: data.DiffStats.Content,
+			Files:   git.ParseDiff(data.DiffStats.Content),
 		},
 	}
 
-	if instance.Paused() {
+	if instance.Paused() || instance.Crashed() {
+		terminal, err := newTerminal(instance.Backend, instance.Title, instance.Program, instance.HistoryLimit)
+		if err != nil {
+			return nil, err
+		}
 		instance.started = true
-		instance.tmuxSession = tmux.NewTmuxSession(instance.Title, instance.Program)
-	} else {
-		if err := instance.Start(false); err != nil {
+		instance.terminal = terminal
+	} else if err := instance.Start(false); err != nil {
+		if instance.terminal == nil {
 			return nil, err
 		}
+		// Keep the session and its worktree around, so one dead session doesn't stop the others from loading.
+		log.WarningLog.Printf("marking session %s crashed: %v", instance.Title, err)
+		instance.started = true
+		instance.SetStatus(Crashed)
 	}
 
 	return instance, nil
@@ -150,8 +228,27 @@ type InstanceOptions struct {
 	Program string
 	// If AutoYes is true, then
 	AutoYes bool
+	// Backend is the terminal backend to run the program in. Empty means tmux if it's installed.
+	Backend string
+	// HistoryLimit is the number of scrollback lines to retain. Zero uses the backend's default.
+	HistoryLimit int
+	// Windows are companion windows to open when the instance starts.
+	Windows []Window
+	// BaseRef is the branch or commit the instance's branch starts from. Empty uses the configured base branch.
+	BaseRef string
+}
+
+// Window is a companion program, like a shell or a test watcher, that runs next to the instance's program in
+// the same worktree.
+type Window struct {
+	// Name identifies the window within the instance.
+	Name string `json:"name"`
+	// Command is the command to run. Empty runs the user's shell.
+	Command string `json:"command,omitempty"`
 }
 
+var windowNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 func NewInstance(opts InstanceOptions) (*Instance, error) {
 	t := time.Now()
 
@@ -171,6 +268,11 @@ func NewInstance(opts InstanceOptions) (*Instance, error) {
 		CreatedAt: t,
 		UpdatedAt: t,
 		AutoYes:   false,
+		Backend:   opts.Backend,
+
+		HistoryLimit: opts.HistoryLimit,
+		Windows:      opts.Windows,
+		baseRef:      opts.BaseRef,
 	}, nil
 }
 
@@ -182,6 +284,12 @@ func (i *Instance) RepoName() (string, error) {
 }
 
 func (i *Instance) SetStatus(status Status) {
+	if status == Ready && i.Status != Ready && i.TimeToReady == 0 && !i.firstPromptAt.IsZero() {
+		i.TimeToReady = time.Since(i.firstPromptAt)
+	}
+	if status == Ready && i.Status == Running {
+		i.turnEnded = true
+	}
 	i.Status = status
 }
 
@@ -191,17 +299,23 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 		return fmt.Errorf("instance title cannot be empty")
 	}
 
-	tmuxSession := tmux.NewTmuxSession(i.Title, i.Program)
-	i.tmuxSession = tmuxSession
+	i.Backend = ResolveBackend(i.Backend)
+	terminal, err := newTerminal(i.Backend, i.Title, i.Program, i.HistoryLimit)
+	if err != nil {
+		return err
+	}
+	i.terminal = terminal
+
+	if !firstTimeSetup {
+		return i.restore()
+	}
 
-	if firstTimeSetup {
-		gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title)
-		if err != nil {
-			return fmt.Errorf("failed to create git worktree: %w", err)
-		}
-		i.gitWorktree = gitWorktree
-		i.Branch = branchName
+	gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title, i.baseRef)
+	if err != nil {
+		return fmt.Errorf("failed to create git worktree: %w", err)
 	}
+	i.gitWorktree = gitWorktree
+	i.Branch = branchName
 
 	// Setup error handler to cleanup resources on any error
 	var setupErr error
@@ -215,53 +329,127 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 		}
 	}()
 
-	if !firstTimeSetup {
-		// Reuse existing session
-		if err := tmuxSession.Restore(); err != nil {
-			setupErr = fmt.Errorf("failed to restore existing session: %w", err)
-			return setupErr
-		}
-	} else {
-		// Setup git worktree first
-		if err := i.gitWorktree.Setup(); err != nil {
-			setupErr = fmt.Errorf("failed to setup git worktree: %w", err)
-			return setupErr
+	// Setup git worktree first
+	if err := i.gitWorktree.Setup(); err != nil {
+		setupErr = fmt.Errorf("failed to setup git worktree: %w", err)
+		return setupErr
+	}
+
+	if err := i.prepareWorktree(); err != nil {
+		if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 		}
+		setupErr = fmt.Errorf("failed to prepare git worktree: %w", err)
+		return setupErr
+	}
 
-		// Create new session
-		if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
-			// Cleanup git worktree if tmux session creation fails
-			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
-				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
-			}
-			setupErr = fmt.Errorf("failed to start new session: %w", err)
-			return setupErr
+	// Create new session
+	if err := i.terminal.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		// Cleanup git worktree if terminal session creation fails
+		if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 		}
+		setupErr = fmt.Errorf("failed to start new session: %w", err)
+		return setupErr
+	}
+
+	if err := i.openWindows(); err != nil {
+		setupErr = err
+		return setupErr
+	}
+
+	i.startRecording(true)
+	i.lastActivity = time.Now()
+	i.SetStatus(Running)
+
+	return nil
+}
+
+// restore reconnects to the terminal session of an instance loaded from storage. If that fails, the worktree and
+// branch are left alone, since they hold the session's work.
+func (i *Instance) restore() error {
+	if !i.terminal.Alive() {
+		return ErrSessionDead
+	}
+	if err := i.terminal.Restore(); err != nil {
+		return fmt.Errorf("failed to restore existing session: %w", err)
 	}
+	i.started = true
 
+	i.startRecording(false)
+	i.lastActivity = time.Now()
 	i.SetStatus(Running)
+	return nil
+}
+
+// Revive starts a fresh terminal session running the program in the existing worktree of a crashed instance. The
+// program starts from scratch: it doesn't remember what it was doing before the crash.
+func (i *Instance) Revive() error {
+	if !i.started {
+		return fmt.Errorf("cannot revive instance that has not been started")
+	}
+	if i.Status != Crashed {
+		return fmt.Errorf("can only revive crashed instances")
+	}
+	worktreePath := i.gitWorktree.GetWorktreePath()
+	if _, err := os.Stat(worktreePath); err != nil {
+		return fmt.Errorf("cannot revive: worktree %s is gone: %w", worktreePath, err)
+	}
+
+	// Release whatever is left of the dead session. It's already gone, so errors are expected.
+	_ = i.terminal.Close()
+	terminal, err := newTerminal(i.Backend, i.Title, i.Program, i.HistoryLimit)
+	if err != nil {
+		return err
+	}
+	i.terminal = terminal
+	if err := i.terminal.Start(worktreePath); err != nil {
+		return fmt.Errorf("failed to start new session: %w", err)
+	}
 
+	// The session is usable without its companion windows, so don't fail the revive over them.
+	if err := i.openWindows(); err != nil {
+		log.WarningLog.Print(err)
+	}
+
+	i.startRecording(false)
+	i.lastActivity = time.Now()
+	i.SetStatus(Running)
 	return nil
 }
 
+// DetectCrash marks a running instance crashed if its terminal session died, and reports whether it did.
+func (i *Instance) DetectCrash() bool {
+	// stopping is checked after Alive, since it's set before the terminal is closed.
+	if i.inactive() || i.terminal.Alive() || i.stopping.Load() {
+		return false
+	}
+	log.WarningLog.Printf("session %s is no longer running", i.Title)
+	i.SetStatus(Crashed)
+	return true
+}
+
 // Kill terminates the instance and cleans up all resources
 func (i *Instance) Kill() error {
 	if !i.started {
 		// If instance was never started, just return success
 		return nil
 	}
+	i.stopping.Store(true)
 
 	var errs []error
 
 	// Always try to cleanup both resources, even if one fails
-	// Clean up tmux session first since it's using the git worktree
-	if i.tmuxSession != nil {
-		if err := i.tmuxSession.Close(); err != nil {
-			errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
+	// Clean up terminal session first since it's using the git worktree
+	if i.terminal != nil {
+		// A crashed session is already gone, so closing it only releases what's left.
+		if err := i.terminal.Close(); err != nil && i.Status != Crashed {
+			errs = append(errs, fmt.Errorf("failed to close terminal session: %w", err))
 		}
 	}
 
 	// Then clean up git worktree
+	i.runPreCleanup()
 	if i.gitWorktree != nil {
 		if err := i.gitWorktree.Cleanup(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to cleanup git worktree: %w", err))
@@ -296,25 +484,44 @@ func (i *Instance) Close() error {
 }
 
 func (i *Instance) Preview() (string, error) {
-	if !i.started || i.Status == Paused {
+	if i.inactive() {
 		return "", nil
 	}
-	return i.tmuxSession.CapturePaneContent()
+	return i.terminal.Capture()
+}
+
+// Scrollback returns lines from through to, inclusive, of the session's history and screen. Line 0 is the oldest
+// retained line and a negative to means through the last line.
+func (i *Instance) Scrollback(from, to int) (string, error) {
+	if i.inactive() {
+		return "", fmt.Errorf("cannot read scrollback of instance that is not running")
+	}
+	if from < 0 {
+		return "", fmt.Errorf("invalid scrollback range: from line %d is negative", from)
+	}
+	if to >= 0 && to < from {
+		return "", fmt.Errorf("invalid scrollback range: to line %d is before from line %d", to, from)
+	}
+	return i.terminal.Scrollback(from, to)
 }
 
 func (i *Instance) HasUpdated() (updated bool, hasPrompt bool) {
-	if !i.started {
+	if i.inactive() {
 		return false, false
 	}
-	return i.tmuxSession.HasUpdated()
+	updated, hasPrompt = i.terminal.HasUpdated()
+	if updated {
+		i.lastActivity = time.Now()
+	}
+	return updated, hasPrompt
 }
 
-// TapEnter sends an enter key press to the tmux session if AutoYes is enabled.
+// TapEnter sends an enter key press to the terminal session if AutoYes is enabled.
 func (i *Instance) TapEnter() {
 	if !i.started || !i.AutoYes {
 		return
 	}
-	if err := i.tmuxSession.TapEnter(); err != nil {
+	if err := i.terminal.TapEnter(); err != nil {
 		log.ErrorLog.Printf("error tapping enter: %v", err)
 	}
 }
@@ -323,15 +530,15 @@ func (i *Instance) Attach() (chan struct{}, error) {
 	if !i.started {
 		return nil, fmt.Errorf("cannot attach instance that has not been started")
 	}
-	return i.tmuxSession.Attach()
+	return i.terminal.Attach()
 }
 
 func (i *Instance) SetPreviewSize(width, height int) error {
-	if !i.started || i.Status == Paused {
+	if i.inactive() {
 		return fmt.Errorf("cannot set preview size for instance that has not been started or " +
 			"is paused")
 	}
-	return i.tmuxSession.SetDetachedSize(width, height)
+	return i.terminal.Resize(width, height)
 }
 
 // GetGitWorktree returns the git worktree for the instance
@@ -360,19 +567,42 @@ func (i *Instance) Paused() bool {
 	return i.Status == Paused
 }
 
-// TmuxAlive returns true if the tmux session is alive. This is a sanity check before attaching.
+// Crashed returns true if the instance's terminal session died. See Revive.
+func (i *Instance) Crashed() bool {
+	return i.Status == Crashed
+}
+
+// inactive returns true if the instance has no terminal session to talk to.
+func (i *Instance) inactive() bool {
+	return !i.started || i.Status == Paused || i.Status == Crashed
+}
+
+// TmuxAlive returns true if the terminal session is alive. This is a sanity check before attaching.
 func (i *Instance) TmuxAlive() bool {
-	return i.tmuxSession.DoesSessionExist()
+	return i.terminal.Alive()
 }
 
-// Pause stops the tmux session and removes the worktree, preserving the branch
+// Pause stops the terminal session and removes the worktree, preserving the branch. The branch name is copied to the
+// clipboard so it can be checked out.
 func (i *Instance) Pause() error {
+	if err := i.pause(); err != nil {
+		return err
+	}
+	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
+	return nil
+}
+
+// pause is Pause without touching the clipboard, for pausing in the background.
+func (i *Instance) pause() error {
 	if !i.started {
 		return fmt.Errorf("cannot pause instance that has not been started")
 	}
 	if i.Status == Paused {
 		return fmt.Errorf("instance is already paused")
 	}
+	i.stopping.Store(true)
+	defer i.stopping.Store(false)
+	i.PauseReason = ""
 
 	var errs []error
 
@@ -391,16 +621,18 @@ func (i *Instance) Pause() error {
 		}
 	}
 
-	// Close tmux session first since it's using the git worktree
-	if err := i.tmuxSession.Close(); err != nil {
-		errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
+	// Close terminal session first since it's using the git worktree. A crashed session is already gone.
+	if err := i.terminal.Close(); err != nil && i.Status != Crashed {
+		errs = append(errs, fmt.Errorf("failed to close terminal session: %w", err))
 		log.ErrorLog.Print(err)
-		// Return early if we can't close tmux to avoid corrupted state
+		// Return early if we can't close the terminal to avoid corrupted state
 		return i.combineErrors(errs)
 	}
 
 	// Check if worktree exists before trying to remove it
 	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err == nil {
+		i.runPreCleanup()
+
 		// Remove worktree but keep branch
 		if err := i.gitWorktree.Remove(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove git worktree: %w", err))
@@ -422,11 +654,10 @@ func (i *Instance) Pause() error {
 	}
 
 	i.SetStatus(Paused)
-	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
 	return nil
 }
 
-// Resume recreates the worktree and restarts the tmux session
+// Resume recreates the worktree and restarts the terminal session
 func (i *Instance) Resume() error {
 	if !i.started {
 		return fmt.Errorf("cannot resume instance that has not been started")
@@ -449,10 +680,19 @@ func (i *Instance) Resume() error {
 		return fmt.Errorf("failed to setup git worktree: %w", err)
 	}
 
-	// Create new tmux session
-	if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+	if err := i.prepareWorktree(); err != nil {
+		log.ErrorLog.Print(err)
+		// Remove the worktree but keep the branch, which holds the paused session's work.
+		if cleanupErr := i.gitWorktree.Remove(); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+		}
+		return fmt.Errorf("failed to prepare git worktree: %w", err)
+	}
+
+	// Create new terminal session
+	if err := i.terminal.Start(i.gitWorktree.GetWorktreePath()); err != nil {
 		log.ErrorLog.Print(err)
-		// Cleanup git worktree if tmux session creation fails
+		// Cleanup git worktree if terminal session creation fails
 		if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
 			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 			log.ErrorLog.Print(err)
@@ -460,10 +700,143 @@ func (i *Instance) Resume() error {
 		return fmt.Errorf("failed to start new session: %w", err)
 	}
 
+	// The session is usable without its companion windows, so don't fail the resume over them.
+	if err := i.openWindows(); err != nil {
+		log.WarningLog.Print(err)
+	}
+
+	i.PauseReason = ""
+	i.startRecording(false)
+	i.lastActivity = time.Now()
 	i.SetStatus(Running)
 	return nil
 }
 
+// openWindows starts the instance's companion windows in a freshly started terminal.
+func (i *Instance) openWindows() error {
+	var errs []error
+	for _, w := range i.Windows {
+		if err := i.terminal.OpenWindow(w.Name, i.gitWorktree.GetWorktreePath(), w.Command); err != nil {
+			errs = append(errs, fmt.Errorf("failed to open window %s: %w", w.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OpenWindow starts command in a new companion window named name. An empty command runs the user's shell.
+func (i *Instance) OpenWindow(name, command string) error {
+	if i.inactive() {
+		return fmt.Errorf("cannot open window in instance that is not running")
+	}
+	if !windowNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid window name %q: use letters, digits, '-' and '_'", name)
+	}
+	if i.liveWindow(name) >= 0 {
+		return fmt.Errorf("window %s already exists", name)
+	}
+	if err := i.terminal.OpenWindow(name, i.gitWorktree.GetWorktreePath(), command); err != nil {
+		return err
+	}
+	i.Windows = append(i.Windows, Window{Name: name, Command: command})
+	return nil
+}
+
+// CloseWindow terminates the companion window named name.
+func (i *Instance) CloseWindow(name string) error {
+	idx, err := i.runningWindow(name)
+	if err != nil {
+		return err
+	}
+	if err := i.terminal.CloseWindow(name); err != nil {
+		return err
+	}
+	i.Windows = append(i.Windows[:idx], i.Windows[idx+1:]...)
+	return nil
+}
+
+// SendWindowKeys writes keys to the companion window named name.
+func (i *Instance) SendWindowKeys(name, keys string) error {
+	if _, err := i.runningWindow(name); err != nil {
+		return err
+	}
+	return i.terminal.SendWindowKeys(name, keys)
+}
+
+// CaptureWindow returns the visible screen of the companion window named name.
+func (i *Instance) CaptureWindow(name string) (string, error) {
+	if _, err := i.runningWindow(name); err != nil {
+		return "", err
+	}
+	return i.terminal.CaptureWindow(name)
+}
+
+// AttachWindow attaches to the companion window named name.
+func (i *Instance) AttachWindow(name string) (chan struct{}, error) {
+	if _, err := i.runningWindow(name); err != nil {
+		return nil, err
+	}
+	return i.terminal.AttachWindow(name)
+}
+
+// HasWindow returns true if the instance has a companion window named name whose command is still running.
+func (i *Instance) HasWindow(name string) bool {
+	return i.liveWindow(name) >= 0
+}
+
+// runningWindow returns the index of the companion window named name, checking that the instance is running.
+func (i *Instance) runningWindow(name string) (int, error) {
+	if i.inactive() {
+		return -1, fmt.Errorf("instance is not running")
+	}
+	idx := i.liveWindow(name)
+	if idx < 0 {
+		return -1, fmt.Errorf("window %s does not exist", name)
+	}
+	return idx, nil
+}
+
+// liveWindow returns the index of the companion window named name, or -1 if there's none. A window whose command
+// exited, ex. a shell the user quit, is forgotten, so it can be opened again. The windows of an instance that isn't
+// running are kept, to be opened again when it is.
+func (i *Instance) liveWindow(name string) int {
+	idx := i.windowIndex(name)
+	if idx < 0 || i.inactive() || i.terminal.WindowAlive(name) {
+		return idx
+	}
+	// Release whatever is left of the window. It's already gone, so errors are expected.
+	_ = i.terminal.CloseWindow(name)
+	i.Windows = append(i.Windows[:idx], i.Windows[idx+1:]...)
+	return -1
+}
+
+func (i *Instance) windowIndex(name string) int {
+	for idx, w := range i.Windows {
+		if w.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// startRecording records the terminal output to the instance's asciicast recording. A fresh instance starts a new
+// recording; otherwise, the output is added to the recording so far. A session that can't be recorded still runs, so
+// errors are only logged.
+func (i *Instance) startRecording(fresh bool) {
+	path, err := recording.Path(i.Title, i.CreatedAt)
+	if err != nil {
+		log.WarningLog.Printf("could not get recording path for %s: %v", i.Title, err)
+		return
+	}
+	if fresh {
+		if err := recording.Remove(i.Title, i.CreatedAt); err != nil {
+			log.WarningLog.Printf("could not remove old recording of %s: %v", i.Title, err)
+		}
+	}
+	if err := i.terminal.Record(path); err != nil {
+		log.WarningLog.Printf("could not record session %s: %v", i.Title, err)
+	}
+}
+
 // UpdateDiffStats updates the git diff statistics for this instance
 func (i *Instance) UpdateDiffStats() error {
 	if !i.started {
@@ -486,7 +859,16 @@ func (i *Instance) UpdateDiffStats() error {
 		return fmt.Errorf("failed to get diff stats: %w", stats.Error)
 	}
 
-	i.diffStats = stats
+	changed := stats != i.diffStats
+	if changed {
+		// The files changed, maybe under the comments
+		i.diffStats = stats
+		i.updateComments()
+	}
+	if changed || i.turnEnded {
+		i.recordDiff(stats, i.turnEnded, time.Now())
+		i.turnEnded = false
+	}
 	return nil
 }
 
@@ -495,23 +877,61 @@ func (i *Instance) GetDiffStats() *git.DiffStats {
 	return i.diffStats
 }
 
-// SendPrompt sends a prompt to the tmux session
+// DiffWithOptions computes the instance's diff with opts instead of the configured diff options. The worktree of a
+// paused instance is gone, so it has no diff to compute.
+func (i *Instance) DiffWithOptions(opts config.DiffOptions) (*git.DiffStats, error) {
+	if !i.started || i.Status == Paused {
+		return nil, fmt.Errorf("instance is not running")
+	}
+	stats := i.gitWorktree.DiffWithOptions(opts)
+	if stats.Error != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", stats.Error)
+	}
+	return stats, nil
+}
+
+// SetDiffOptions changes the options the instance's diffs are trimmed with, ex. after the config is reloaded. The
+// next UpdateDiffStats applies them.
+func (i *Instance) SetDiffOptions(opts config.DiffOptions) {
+	if i.gitWorktree != nil {
+		i.gitWorktree.SetDiffOptions(opts)
+	}
+}
+
+// SendKeys writes keys to the program without pressing enter.
+func (i *Instance) SendKeys(keys string) error {
+	if i.inactive() {
+		return fmt.Errorf("instance is not running")
+	}
+	if err := i.terminal.SendKeys(keys); err != nil {
+		return err
+	}
+	i.lastActivity = time.Now()
+	return nil
+}
+
+// SendPrompt sends a prompt to the terminal session
 func (i *Instance) SendPrompt(prompt string) error {
 	if !i.started {
 		return fmt.Errorf("instance not started")
 	}
-	if i.tmuxSession == nil {
-		return fmt.Errorf("tmux session not initialized")
+	if i.terminal == nil {
+		return fmt.Errorf("terminal session not initialized")
 	}
-	if err := i.tmuxSession.SendKeys(prompt); err != nil {
-		return fmt.Errorf("error sending keys to tmux session: %w", err)
+	if err := i.terminal.SendKeys(prompt); err != nil {
+		return fmt.Errorf("error sending keys to terminal session: %w", err)
 	}
 
 	// Brief pause to prevent carriage return from being interpreted as newline
 	time.Sleep(100 * time.Millisecond)
-	if err := i.tmuxSession.TapEnter(); err != nil {
+	if err := i.terminal.TapEnter(); err != nil {
 		return fmt.Errorf("error tapping enter: %w", err)
 	}
+	i.LastPrompt = prompt
+	i.lastActivity = time.Now()
+	if i.firstPromptAt.IsZero() {
+		i.firstPromptAt = i.lastActivity
+	}
 
 	return nil
 }
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"claude-squad/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deadTerminal is a terminal whose session has exited.
+type deadTerminal struct {
+	Terminal
+}
+
+func (deadTerminal) Alive() bool { return false }
+
+// windowTerminal is a terminal with companion windows, some of which exited.
+type windowTerminal struct {
+	Terminal
+	alive  map[string]bool
+	closed []string
+}
+
+func (w *windowTerminal) WindowAlive(name string) bool { return w.alive[name] }
+
+func (w *windowTerminal) CloseWindow(name string) error {
+	w.closed = append(w.closed, name)
+	return nil
+}
+
+func TestDetectCrash(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	instance := &Instance{Title: "crash", Status: Running, started: true, terminal: deadTerminal{}}
+
+	// Pause and Kill close the terminal themselves, which isn't a crash.
+	instance.stopping.Store(true)
+	assert.False(t, instance.DetectCrash())
+	assert.Equal(t, Running, instance.Status)
+
+	instance.stopping.Store(false)
+	assert.True(t, instance.DetectCrash())
+	assert.Equal(t, Crashed, instance.Status)
+	assert.False(t, instance.DetectCrash())
+}
+
+func TestWindowExited(t *testing.T) {
+	terminal := &windowTerminal{alive: map[string]bool{"test": true}}
+	instance := &Instance{Title: "windows", Status: Running, started: true, terminal: terminal,
+		Windows: []Window{{Name: "shell"}, {Name: "test", Command: "go test ./..."}}}
+
+	// The shell was quit, so it's forgotten and can be opened again.
+	assert.True(t, instance.HasWindow("test"))
+	assert.False(t, instance.HasWindow("shell"))
+	assert.Equal(t, []Window{{Name: "test", Command: "go test ./..."}}, instance.Windows)
+	assert.Equal(t, []string{"shell"}, terminal.closed)
+
+	// A paused instance keeps its windows, to open them when it's resumed.
+	instance.Status = Paused
+	terminal.alive = nil
+	assert.True(t, instance.HasWindow("test"))
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"fmt"
+	"slices"
+)
+
+// ExportPatch returns the instance's changes against its base commit, uncommitted changes and untracked files
+// included. PatchFormatDiff is one unified diff; PatchFormatMail is git format-patch output, with a mail per commit on
+// the instance's branch and one more for its uncommitted changes. Empty if nothing changed.
+func (i *Instance) ExportPatch(format git.PatchFormat) (string, error) {
+	if !i.started {
+		return "", fmt.Errorf("instance is not started")
+	}
+	commit, err := i.changesCommit()
+	if err != nil {
+		return "", err
+	}
+	base := i.gitWorktree.GetBaseCommitSHA()
+	switch format {
+	case git.PatchFormatDiff, "":
+		return git.Patch(i.gitWorktree.GetRepoPath(), base, commit, nil)
+	case git.PatchFormatMail:
+		return git.FormatPatch(i.gitWorktree.GetRepoPath(), base, commit)
+	default:
+		return "", fmt.Errorf("unknown patch format %q", format)
+	}
+}
+
+// ApplyPatch brings source's changes to files into the instance's worktree, ex. to take a helper one program wrote
+// into the instance of another. files are relative to the repository root; empty takes all of source's changes. The
+// changes are applied on top of what the instance changed already, as uncommitted changes. Either every file applies,
+// or nothing is changed.
+func (i *Instance) ApplyPatch(source *Instance, files []string) error {
+	if i == source {
+		return fmt.Errorf("cannot apply an instance's changes to itself")
+	}
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("instance is not running")
+	}
+	if !source.started {
+		return fmt.Errorf("instance %s is not started", source.Title)
+	}
+	if source.gitWorktree.GetRepoPath() != i.gitWorktree.GetRepoPath() {
+		return fmt.Errorf("%s and %s are not in the same repository", source.Title, i.Title)
+	}
+
+	commit, err := source.changesCommit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", source.Title, err)
+	}
+	patch, err := git.Patch(source.gitWorktree.GetRepoPath(), source.gitWorktree.GetBaseCommitSHA(), commit, files)
+	if err != nil {
+		return err
+	}
+	changed := git.NewDiffStats(patch).Paths()
+	for _, file := range files {
+		if !slices.Contains(changed, file) {
+			return fmt.Errorf("%s did not change %s", source.Title, file)
+		}
+	}
+	if len(changed) == 0 {
+		return fmt.Errorf("%s has no changes to apply", source.Title)
+	}
+	return git.ApplyPatch(i.gitWorktree.GetWorktreePath(), patch)
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enqueue adds a prompt to the end of the instance's queue. Queued prompts are sent one at a time by DeliverQueued,
+// each time the program is ready for input.
+func (i *Instance) Enqueue(prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("prompt cannot be empty")
+	}
+	i.queueMu.Lock()
+	defer i.queueMu.Unlock()
+	i.queue = append(i.queue, prompt)
+	return nil
+}
+
+// QueuedPrompts returns the prompts waiting to be sent, next one first.
+func (i *Instance) QueuedPrompts() []string {
+	i.queueMu.Lock()
+	defer i.queueMu.Unlock()
+	if len(i.queue) == 0 {
+		return nil
+	}
+	return append([]string(nil), i.queue...)
+}
+
+// MoveQueued moves the queued prompt at index from to index to, shifting the prompts in between. prompt is the
+// prompt expected at from, as QueuedPrompts returned it, so a prompt delivered in the meantime doesn't make it move
+// another one.
+func (i *Instance) MoveQueued(from, to int, prompt string) error {
+	i.queueMu.Lock()
+	defer i.queueMu.Unlock()
+	if err := i.checkQueued(from, prompt); err != nil {
+		return err
+	}
+	if err := i.checkQueueIndex(to); err != nil {
+		return err
+	}
+	i.queue = append(i.queue[:from], i.queue[from+1:]...)
+	i.queue = append(i.queue[:to], append([]string{prompt}, i.queue[to:]...)...)
+	return nil
+}
+
+// CancelQueued removes the queued prompt at index. Like in MoveQueued, prompt is the prompt expected there.
+func (i *Instance) CancelQueued(index int, prompt string) error {
+	i.queueMu.Lock()
+	defer i.queueMu.Unlock()
+	if err := i.checkQueued(index, prompt); err != nil {
+		return err
+	}
+	i.queue = append(i.queue[:index], i.queue[index+1:]...)
+	return nil
+}
+
+// DeliverQueued sends the next queued prompt if the instance is ready for input, and returns it. It returns an empty
+// string if there was nothing to send. A prompt that fails to send stays at the front of the queue.
+func (i *Instance) DeliverQueued() (string, error) {
+	i.queueMu.Lock()
+	if i.inactive() || i.Status != Ready || len(i.queue) == 0 {
+		i.queueMu.Unlock()
+		return "", nil
+	}
+	prompt := i.queue[0]
+	i.queue = i.queue[1:]
+	i.queueMu.Unlock()
+
+	if err := i.SendPrompt(prompt); err != nil {
+		i.queueMu.Lock()
+		i.queue = append([]string{prompt}, i.queue...)
+		i.queueMu.Unlock()
+		return "", fmt.Errorf("failed to send queued prompt: %w", err)
+	}
+	// The program is working on the prompt now. Waiting for it to go quiet again keeps the next prompt from being
+	// sent right behind this one.
+	i.SetStatus(Running)
+	return prompt, nil
+}
+
+// checkQueued returns an error unless prompt is queued at index. queueMu must be held.
+func (i *Instance) checkQueued(index int, prompt string) error {
+	if err := i.checkQueueIndex(index); err != nil {
+		return err
+	}
+	if i.queue[index] != prompt {
+		return fmt.Errorf("the queue changed, prompt %d is no longer the one expected", index)
+	}
+	return nil
+}
+
+func (i *Instance) checkQueueIndex(index int) error {
+	if index < 0 || index >= len(i.queue) {
+		return fmt.Errorf("queue index %d out of range, the queue has %d prompts", index, len(i.queue))
+	}
+	return nil
+}
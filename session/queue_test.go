@@ -0,0 +1,90 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promptTerminal is a terminal that records the keys sent to it, or fails to send them if err is set.
+type promptTerminal struct {
+	Terminal
+	sent []string
+	err  error
+}
+
+func (p *promptTerminal) SendKeys(keys string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent = append(p.sent, keys)
+	return nil
+}
+
+func (p *promptTerminal) TapEnter() error { return nil }
+
+func TestQueue(t *testing.T) {
+	instance := &Instance{Title: "queue"}
+	assert.Nil(t, instance.QueuedPrompts())
+	assert.Error(t, instance.Enqueue("  \n"))
+
+	for _, prompt := range []string{"add tests", "run the linter", "fix the lint"} {
+		require.NoError(t, instance.Enqueue(prompt))
+	}
+	require.NoError(t, instance.MoveQueued(0, 2, "add tests"))
+	assert.Equal(t, []string{"run the linter", "fix the lint", "add tests"}, instance.QueuedPrompts())
+	require.NoError(t, instance.MoveQueued(2, 1, "add tests"))
+	assert.Equal(t, []string{"run the linter", "add tests", "fix the lint"}, instance.QueuedPrompts())
+	assert.Error(t, instance.MoveQueued(0, 3, "run the linter"))
+	assert.Error(t, instance.MoveQueued(-1, 0, "run the linter"))
+	// Nothing moves if the prompt at the index isn't the expected one, ex. after the first was delivered.
+	assert.Error(t, instance.MoveQueued(1, 0, "fix the lint"))
+
+	assert.Error(t, instance.CancelQueued(1, "run the linter"))
+	require.NoError(t, instance.CancelQueued(1, "add tests"))
+	assert.Error(t, instance.CancelQueued(2, "fix the lint"))
+
+	// The queue survives a round trip through storage.
+	restored := &Instance{queue: instance.ToInstanceData().Queue}
+	assert.Equal(t, []string{"run the linter", "fix the lint"}, restored.QueuedPrompts())
+
+	// Nothing is sent to an instance that isn't running.
+	prompt, err := instance.DeliverQueued()
+	require.NoError(t, err)
+	assert.Empty(t, prompt)
+	assert.Len(t, instance.QueuedPrompts(), 2)
+}
+
+func TestDeliverQueued(t *testing.T) {
+	terminal := &promptTerminal{}
+	instance := &Instance{Title: "deliver", Status: Running, started: true, terminal: terminal}
+	require.NoError(t, instance.Enqueue("add tests"))
+	require.NoError(t, instance.Enqueue("run the linter"))
+
+	// Nothing is sent while the program is working.
+	prompt, err := instance.DeliverQueued()
+	require.NoError(t, err)
+	assert.Empty(t, prompt)
+	assert.Empty(t, terminal.sent)
+	assert.Equal(t, []string{"add tests", "run the linter"}, instance.QueuedPrompts())
+
+	// Once it's ready for input, only the next prompt is sent and the program is working again.
+	instance.SetStatus(Ready)
+	prompt, err = instance.DeliverQueued()
+	require.NoError(t, err)
+	assert.Equal(t, "add tests", prompt)
+	assert.Equal(t, []string{"add tests"}, terminal.sent)
+	assert.Equal(t, []string{"run the linter"}, instance.QueuedPrompts())
+	assert.Equal(t, Running, instance.Status)
+
+	// A prompt that fails to be sent stays at the front of the queue.
+	instance.SetStatus(Ready)
+	terminal.err = errors.New("session is gone")
+	prompt, err = instance.DeliverQueued()
+	assert.Error(t, err)
+	assert.Empty(t, prompt)
+	assert.Equal(t, []string{"run the linter"}, instance.QueuedPrompts())
+	assert.Equal(t, Ready, instance.Status)
+}
@@ -0,0 +1,293 @@
+// Package recording records terminal output in the asciicast v2 format and plays it back.
+// See https://docs.asciinema.org/manual/asciicast/v2/ for the format.
+package recording
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// FileName is the name of the recording file inside a session's directory.
+	FileName = "recording.cast"
+	// sessionsDirName is the directory, inside the config directory, holding per-session data.
+	sessionsDirName = "sessions"
+)
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// SessionDir returns the directory holding the data of the session with the given title, created at created. The
+// creation time keeps apart sessions that had the same title at different times.
+func SessionDir(title string, created time.Time) (string, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%d", dirName(title), created.Unix())), nil
+}
+
+// Path returns the path of the recording of the session with the given title, created at created.
+func Path(title string, created time.Time) (string, error) {
+	dir, err := SessionDir(title, created)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Latest returns the path of the recording of the last session created with the given title. The error satisfies
+// os.IsNotExist if there's none.
+func Latest(title string) (string, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	prefix := dirName(title) + "-"
+	latest, found := int64(0), ""
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		// A title can be the start of another, so the rest of the name must be the creation time alone.
+		created, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil || created < 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name(), FileName)
+		if _, err := os.Stat(path); err == nil && (found == "" || created > latest) {
+			latest, found = created, path
+		}
+	}
+	if found == "" {
+		return "", &os.PathError{Op: "open", Path: filepath.Join(dir, prefix+"*", FileName), Err: os.ErrNotExist}
+	}
+	return found, nil
+}
+
+// Remove deletes the data of the session with the given title, created at created.
+func Remove(title string, created time.Time) error {
+	dir, err := SessionDir(title, created)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove session data: %w", err)
+	}
+	return nil
+}
+
+// sessionsDir returns the directory holding the directories of the sessions.
+func sessionsDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, sessionsDirName), nil
+}
+
+// dirName returns title with the path separators it may contain replaced, for use in a directory name.
+func dirName(title string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, title)
+}
+
+// Recorder appends output events to an asciicast file. It is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	f     *os.File
+	start time.Time
+	// pending holds the bytes of a multi-byte rune split across writes. Event data must be valid UTF-8.
+	pending []byte
+}
+
+// Open opens the recording at path for appending, creating it with a header if it doesn't exist. When appending
+// to an existing recording, event times continue from the header's timestamp so the recording stays ordered.
+func Open(path string, width, height int, title string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	start := time.Now()
+	header, err := readHeader(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	if header != nil {
+		start = time.Unix(header.Timestamp, 0)
+	} else {
+		data, err := json.Marshal(Header{
+			Version:   2,
+			Width:     width,
+			Height:    height,
+			Timestamp: start.Unix(),
+			Title:     title,
+		})
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to marshal recording header: %w", err)
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write recording header: %w", err)
+		}
+	}
+
+	return &Recorder{f: f, start: start}, nil
+}
+
+// readHeader reads the header of an existing recording. It returns nil if the file is empty.
+func readHeader(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+	if len(line) == 0 {
+		return nil, nil
+	}
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse recording header: %w", err)
+	}
+	return &header, nil
+}
+
+// Write records p as an output event.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+	// Hold back an incomplete rune at the end until the next write.
+	end := len(data)
+	for i := 0; i < utf8.UTFMax && end-i > 0; i++ {
+		if utf8.RuneStart(data[end-i-1]) {
+			if !utf8.FullRune(data[end-i-1:]) {
+				end = end - i - 1
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[end:]...)
+	if end == 0 {
+		return len(p), nil
+	}
+
+	event, err := json.Marshal([]interface{}{
+		time.Since(r.start).Seconds(),
+		"o",
+		strings.ToValidUTF8(string(data[:end]), "�"),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal recording event: %w", err)
+	}
+	if _, err := r.f.Write(append(event, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write recording event: %w", err)
+	}
+	return len(p), nil
+}
+
+// Close closes the recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// Play writes the output events of the recording in r to w, sleeping between events to reproduce the original
+// timing. speed scales playback (2 is twice as fast). Pauses longer than idleLimit are shortened to idleLimit;
+// zero disables the limit.
+func Play(r io.Reader, w io.Writer, speed float64, idleLimit time.Duration) error {
+	if speed <= 0 {
+		return fmt.Errorf("speed must be positive, got %v", speed)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+		return fmt.Errorf("recording is empty")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("failed to parse recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version: %d", header.Version)
+	}
+
+	var last float64
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to parse recording event on line %d: %w", lineNum, err)
+		}
+		if len(event) != 3 {
+			return fmt.Errorf("invalid recording event on line %d", lineNum)
+		}
+		at, ok1 := event[0].(float64)
+		kind, ok2 := event[1].(string)
+		data, ok3 := event[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return fmt.Errorf("invalid recording event on line %d", lineNum)
+		}
+		if kind != "o" {
+			continue
+		}
+
+		delay := time.Duration((at - last) * float64(time.Second))
+		if idleLimit > 0 && delay > idleLimit {
+			delay = idleLimit
+		}
+		last = at
+		if delay > 0 {
+			time.Sleep(time.Duration(float64(delay) / speed))
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read recording: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"claude-squad/config"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndPlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session", FileName)
+
+	recorder, err := Open(path, 100, 30, "test")
+	require.NoError(t, err)
+	_, err = recorder.Write([]byte("hello "))
+	require.NoError(t, err)
+	// Split a multi-byte rune across writes.
+	snowman := []byte("☃")
+	_, err = recorder.Write(append([]byte("world "), snowman[:1]...))
+	require.NoError(t, err)
+	_, err = recorder.Write(snowman[1:])
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	// Reopening appends events without writing a second header.
+	recorder, err = Open(path, 100, 30, "test")
+	require.NoError(t, err)
+	_, err = recorder.Write([]byte("\r\nagain"))
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	var header Header
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &header))
+	require.Equal(t, Header{Version: 2, Width: 100, Height: 30, Timestamp: header.Timestamp, Title: "test"}, header)
+	events := 0
+	for scanner.Scan() {
+		var event []interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		require.Len(t, event, 3)
+		require.Equal(t, "o", event[1])
+		events++
+	}
+	require.Equal(t, 4, events)
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, Play(f, &out, 100, 0))
+	require.Equal(t, "hello world ☃\r\nagain", out.String())
+}
+
+func TestPlayErrors(t *testing.T) {
+	var out bytes.Buffer
+	require.Error(t, Play(bytes.NewBufferString(""), &out, 1, 0))
+	require.Error(t, Play(bytes.NewBufferString(`{"version":1}`), &out, 1, 0))
+	require.Error(t, Play(bytes.NewBufferString(`{"version":2}`+"\n"+`[1, "o"]`), &out, 1, 0))
+	require.Error(t, Play(bytes.NewBufferString(`{"version":2}`), &out, 0, 0))
+}
+
+func TestSessionRecordings(t *testing.T) {
+	t.Setenv(config.HomeEnvVar, t.TempDir())
+	_, err := Latest("fix/login")
+	require.True(t, os.IsNotExist(err))
+
+	// Sessions that had the same title at different times, and one whose title starts with it
+	created := time.Unix(1700000000, 0)
+	for _, session := range []struct {
+		title   string
+		created time.Time
+	}{{"fix/login", created}, {"fix/login", created.Add(time.Hour)}, {"fix/login-2", created.Add(2 * time.Hour)}} {
+		path, err := Path(session.title, session.created)
+		require.NoError(t, err)
+		recorder, err := Open(path, 80, 24, session.title)
+		require.NoError(t, err)
+		require.NoError(t, recorder.Close())
+	}
+
+	latest, err := Latest("fix/login")
+	require.NoError(t, err)
+	path, err := Path("fix/login", created.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, path, latest)
+
+	require.NoError(t, Remove("fix/login", created.Add(time.Hour)))
+	latest, err = Latest("fix/login")
+	require.NoError(t, err)
+	path, err = Path("fix/login", created)
+	require.NoError(t, err)
+	require.Equal(t, path, latest)
+}
@@ -0,0 +1,212 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReviewComment is a comment on lines an instance changed. Comments are sent to the program together, as one review
+// prompt, by SendReview.
+type ReviewComment struct {
+	ID string `json:"id"`
+	// Path is the file the comment is on, relative to the repository root.
+	Path string `json:"path"`
+	// StartLine and EndLine are the first and last lines the comment is on, numbered as in the worktree's version of
+	// the file. They follow the lines when lines are added or removed above them.
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Body      string `json:"body"`
+	// Lines are the commented lines as they were when the comment was made.
+	Lines []string `json:"lines,omitempty"`
+	// Sent is true once the comment was sent to the program.
+	Sent bool `json:"sent,omitempty"`
+	// Resolved is true once the commented lines changed or their file was removed.
+	Resolved  bool      `json:"resolved,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddComment adds a comment on lines of the instance's worktree. ID, Lines and CreatedAt are filled in; an EndLine of
+// zero comments on StartLine alone. Returns the comment as added.
+func (i *Instance) AddComment(comment ReviewComment) (ReviewComment, error) {
+	if !i.started || i.Status == Paused {
+		return ReviewComment{}, fmt.Errorf("instance is not running")
+	}
+	if strings.TrimSpace(comment.Body) == "" {
+		return ReviewComment{}, fmt.Errorf("comment cannot be empty")
+	}
+	if comment.EndLine == 0 {
+		comment.EndLine = comment.StartLine
+	}
+	if comment.StartLine < 1 || comment.EndLine < comment.StartLine {
+		return ReviewComment{}, fmt.Errorf("invalid line range %d-%d", comment.StartLine, comment.EndLine)
+	}
+	lines, err := i.worktreeLines(comment.Path)
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	if comment.EndLine > len(lines) {
+		return ReviewComment{}, fmt.Errorf("%s has %d lines, can't comment on line %d", comment.Path, len(lines),
+			comment.EndLine)
+	}
+	comment.Lines = slices.Clone(lines[comment.StartLine-1 : comment.EndLine])
+	comment.Sent = false
+	comment.Resolved = false
+	comment.CreatedAt = time.Now()
+
+	i.reviewMu.Lock()
+	defer i.reviewMu.Unlock()
+	next := 1
+	for _, c := range i.comments {
+		if id, err := strconv.Atoi(c.ID); err == nil && id >= next {
+			next = id + 1
+		}
+	}
+	comment.ID = strconv.Itoa(next)
+	i.comments = append(i.comments, comment)
+	return comment, nil
+}
+
+// Comments returns the instance's comments, in the order they were added.
+func (i *Instance) Comments() []ReviewComment {
+	i.reviewMu.Lock()
+	defer i.reviewMu.Unlock()
+	if len(i.comments) == 0 {
+		return nil
+	}
+	return slices.Clone(i.comments)
+}
+
+// DeleteComment removes the comment with the given ID.
+func (i *Instance) DeleteComment(id string) error {
+	i.reviewMu.Lock()
+	defer i.reviewMu.Unlock()
+	for n, c := range i.comments {
+		if c.ID == id {
+			i.comments = slices.Delete(i.comments, n, n+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("comment not found: %s", id)
+}
+
+// SendReview queues the comments that weren't sent or resolved yet as one prompt, and marks them sent. Returns the
+// prompt.
+func (i *Instance) SendReview() (string, error) {
+	i.reviewMu.Lock()
+	defer i.reviewMu.Unlock()
+	var pending []ReviewComment
+	for _, c := range i.comments {
+		if !c.Sent && !c.Resolved {
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		return "", fmt.Errorf("no comments to send")
+	}
+
+	prompt := ReviewPrompt(pending)
+	if err := i.Enqueue(prompt); err != nil {
+		return "", err
+	}
+	for n := range i.comments {
+		if !i.comments[n].Resolved {
+			i.comments[n].Sent = true
+		}
+	}
+	return prompt, nil
+}
+
+// ReviewPrompt formats comments as a prompt asking the program to address them.
+func ReviewPrompt(comments []ReviewComment) string {
+	var out strings.Builder
+	out.WriteString("Please address these review comments on your changes:\n")
+	for _, c := range comments {
+		location := fmt.Sprintf("%s:%d", c.Path, c.StartLine)
+		if c.EndLine > c.StartLine {
+			location += fmt.Sprintf("-%d", c.EndLine)
+		}
+		fmt.Fprintf(&out, "\nIn %s", location)
+		if len(c.Lines) > 0 {
+			out.WriteString(", where you have:\n")
+			for _, line := range c.Lines {
+				out.WriteString("    " + line + "\n")
+			}
+		} else {
+			out.WriteString(":\n")
+		}
+		out.WriteString(strings.TrimSpace(c.Body) + "\n")
+	}
+	return out.String()
+}
+
+// updateComments moves the open comments along with their lines, and resolves the ones whose lines changed.
+func (i *Instance) updateComments() {
+	i.reviewMu.Lock()
+	defer i.reviewMu.Unlock()
+	files := make(map[string][]string)
+	for n := range i.comments {
+		c := &i.comments[n]
+		if c.Resolved {
+			continue
+		}
+		lines, ok := files[c.Path]
+		if !ok {
+			var err error
+			if lines, err = i.worktreeLines(c.Path); err != nil {
+				// Removed, or can't be read anymore
+				lines = nil
+			}
+			files[c.Path] = lines
+		}
+		start := findLines(lines, c.Lines, c.StartLine)
+		if start == 0 {
+			c.Resolved = true
+			continue
+		}
+		c.EndLine += start - c.StartLine
+		c.StartLine = start
+	}
+}
+
+// worktreeLines reads the lines of a file in the instance's worktree.
+func (i *Instance) worktreeLines(path string) ([]string, error) {
+	if path == "" || filepath.IsAbs(path) || !filepath.IsLocal(filepath.FromSlash(path)) {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	content, err := os.ReadFile(filepath.Join(i.gitWorktree.GetWorktreePath(), filepath.FromSlash(path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// findLines returns the line where block starts in lines, picking the one closest to near if there are several, or
+// 0 if it isn't there.
+func findLines(lines, block []string, near int) int {
+	found := 0
+	for n := 0; n+len(block) <= len(lines); n++ {
+		if !slices.Equal(lines[n:n+len(block)], block) {
+			continue
+		}
+		if line := n + 1; found == 0 || abs(line-near) < abs(found-near) {
+			found = line
+		}
+	}
+	return found
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
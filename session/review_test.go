@@ -0,0 +1,71 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewComments(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	write("main.go", "package main\n\nfunc main() {\n\tpanic(1)\n}\n")
+	write("util.go", "package main\n\nvar x = 1\n")
+	instance := &Instance{
+		Title:       "review",
+		Status:      Ready,
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(dir, dir, "review", "review", ""),
+	}
+
+	_, err := instance.AddComment(ReviewComment{Path: "main.go", StartLine: 6, Body: "past the end"})
+	assert.Error(t, err)
+	_, err = instance.AddComment(ReviewComment{Path: "../outside.go", StartLine: 1, Body: "outside"})
+	assert.Error(t, err)
+	_, err = instance.AddComment(ReviewComment{Path: "main.go", StartLine: 4, Body: " "})
+	assert.Error(t, err)
+
+	panicComment, err := instance.AddComment(ReviewComment{Path: "main.go", StartLine: 3, EndLine: 4,
+		Body: "return an error instead of panicking"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"func main() {", "\tpanic(1)"}, panicComment.Lines)
+	varComment, err := instance.AddComment(ReviewComment{Path: "util.go", StartLine: 3, Body: "name this better"})
+	require.NoError(t, err)
+	assert.NotEqual(t, panicComment.ID, varComment.ID)
+
+	// Lines added above a comment move it, and changing its lines resolves it.
+	write("main.go", "package main\n\nimport \"os\"\n\nfunc main() {\n\tpanic(1)\n}\n")
+	write("util.go", "package main\n\nvar count = 1\n")
+	instance.updateComments()
+	comments := instance.Comments()
+	require.Len(t, comments, 2)
+	assert.Equal(t, 5, comments[0].StartLine)
+	assert.Equal(t, 6, comments[0].EndLine)
+	assert.False(t, comments[0].Resolved)
+	assert.True(t, comments[1].Resolved)
+
+	// Only the open comment is sent, once.
+	prompt, err := instance.SendReview()
+	require.NoError(t, err)
+	assert.Equal(t, "Please address these review comments on your changes:\n\n"+
+		"In main.go:5-6, where you have:\n    func main() {\n    \tpanic(1)\nreturn an error instead of panicking\n",
+		prompt)
+	assert.Equal(t, []string{prompt}, instance.QueuedPrompts())
+	assert.True(t, instance.Comments()[0].Sent)
+	_, err = instance.SendReview()
+	assert.Error(t, err)
+
+	// Comments survive a round trip through storage.
+	restored := &Instance{comments: instance.ToInstanceData().Comments}
+	assert.Equal(t, instance.Comments(), restored.Comments())
+
+	require.NoError(t, instance.DeleteComment(varComment.ID))
+	assert.Len(t, instance.Comments(), 1)
+	assert.Error(t, instance.DeleteComment(varComment.ID))
+}
@@ -18,6 +18,24 @@ type InstanceData struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	AutoYes   bool      `json:"auto_yes"`
+	Backend   string    `json:"backend,omitempty"`
+
+	HistoryLimit int      `json:"history_limit,omitempty"`
+	Windows      []Window `json:"windows,omitempty"`
+	// LastPrompt is the last prompt sent to the program.
+	LastPrompt string `json:"last_prompt,omitempty"`
+	// PauseReason says why the instance was paused automatically. It's empty if it was paused by hand.
+	PauseReason string `json:"pause_reason,omitempty"`
+	// Group is shared by the sibling instances of a fan-out.
+	Group string `json:"group,omitempty"`
+	// TimeToReady is how long the program took to become ready after its first prompt.
+	TimeToReady time.Duration `json:"time_to_ready,omitempty"`
+	// Queue holds the prompts waiting to be sent to the program.
+	Queue []string `json:"queue,omitempty"`
+	// Comments are the review comments on the instance's changes.
+	Comments []ReviewComment `json:"comments,omitempty"`
+	// DiffHistory holds the snapshots of the diff over time.
+	DiffHistory []DiffSnapshot `json:"diff_history,omitempty"`
 
 	Program   string          `json:"program"`
 	Worktree  GitWorktreeData `json:"worktree"`
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"claude-squad/session/headless"
+	"claude-squad/session/tmux"
+	"fmt"
+	"os/exec"
+)
+
+const (
+	// BackendTmux runs the program in a tmux session. Sessions survive restarts of claude-squad.
+	BackendTmux = "tmux"
+	// BackendHeadless runs the program directly under a PTY. It doesn't require tmux, but sessions end when
+	// claude-squad exits.
+	BackendHeadless = "headless"
+)
+
+// Terminal is the backend that runs an instance's program and exposes its screen.
+type Terminal interface {
+	// Start runs the program in workDir.
+	Start(workDir string) error
+	// Restore reconnects to a session started by a previous process.
+	Restore() error
+	// SendKeys writes keys to the program's input.
+	SendKeys(keys string) error
+	// TapEnter sends an enter keystroke to the program.
+	TapEnter() error
+	// Capture returns the visible screen, including ANSI escape sequences.
+	Capture() (string, error)
+	// Scrollback returns lines from through to, inclusive, of the retained history followed by the visible
+	// screen. Line 0 is the oldest retained line and a negative to means through the last line.
+	Scrollback(from, to int) (string, error)
+	// HasUpdated checks if the screen changed since the last call, and whether the program is showing a
+	// permission prompt.
+	HasUpdated() (updated bool, hasPrompt bool)
+	// Resize sets the size of the terminal while detached.
+	Resize(width, height int) error
+	// Attach connects stdin and stdout to the program. The returned channel is closed on detach.
+	Attach() (chan struct{}, error)
+	// Record appends the session's output to the asciicast recording at path.
+	Record(path string) error
+	// OpenWindow starts command in workDir in a companion window named name, alongside the program. An empty
+	// command starts the user's shell.
+	OpenWindow(name, workDir, command string) error
+	// CloseWindow terminates the companion window.
+	CloseWindow(name string) error
+	// SendWindowKeys writes keys to the companion window's input.
+	SendWindowKeys(name, keys string) error
+	// CaptureWindow returns the visible screen of the companion window.
+	CaptureWindow(name string) (string, error)
+	// AttachWindow is like Attach, but shows the companion window.
+	AttachWindow(name string) (chan struct{}, error)
+	// WindowAlive returns true if the companion window's command is still running.
+	WindowAlive(name string) bool
+	// Alive returns true if the session is still running.
+	Alive() bool
+	// Close terminates the session.
+	Close() error
+}
+
+// ResolveBackend returns the backend to use. An empty backend means tmux if it's installed, headless otherwise.
+func ResolveBackend(backend string) string {
+	if backend != "" {
+		return backend
+	}
+	if _, err := exec.LookPath("tmux"); err == nil {
+		return BackendTmux
+	}
+	return BackendHeadless
+}
+
+// newTerminal creates the terminal for the given backend. historyLimit is the number of scrollback lines to retain;
+// zero uses the backend's default.
+func newTerminal(backend string, name string, program string, historyLimit int) (Terminal, error) {
+	switch ResolveBackend(backend) {
+	case BackendTmux:
+		t := tmux.NewTmuxSession(name, program)
+		t.SetHistoryLimit(historyLimit)
+		return t, nil
+	case BackendHeadless:
+		return headless.NewHeadlessSession(name, program), nil
+	default:
+		return nil, fmt.Errorf("unknown terminal backend: %s", backend)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"claude-squad/cmd"
 	"claude-squad/log"
+	"claude-squad/session/agent"
 	"context"
 	"crypto/sha256"
 	"errors"
@@ -12,6 +13,8 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,10 +22,10 @@ import (
 	"github.com/creack/pty"
 )
 
-const ProgramClaude = "claude"
+const ProgramClaude = agent.ProgramClaude
 
-const ProgramAider = "aider"
-const ProgramGemini = "gemini"
+const ProgramAider = agent.ProgramAider
+const ProgramGemini = agent.ProgramGemini
 
 // TmuxSession represents a managed tmux session
 type TmuxSession struct {
@@ -35,6 +38,8 @@ type TmuxSession struct {
 	ptyFactory PtyFactory
 	// cmdExec is used to execute commands in the tmux session.
 	cmdExec cmd.Executor
+	// historyLimit is the number of scrollback lines the pane retains. Zero uses the tmux server's default.
+	historyLimit int
 
 	// Initialized by Start or Restore
 	//
@@ -81,6 +86,12 @@ func newTmuxSession(name string, program string, ptyFactory PtyFactory, cmdExec
 	}
 }
 
+// SetHistoryLimit sets the number of scrollback lines retained by sessions created by Start. Zero uses the tmux
+// server's default.
+func (t *TmuxSession) SetHistoryLimit(limit int) {
+	t.historyLimit = limit
+}
+
 // Start creates and starts a new tmux session, then attaches to it. Program is the command to run in
 // the session (ex. claude). workdir is the git worktree directory.
 func (t *TmuxSession) Start(workDir string) error {
@@ -91,6 +102,15 @@ func (t *TmuxSession) Start(workDir string) error {
 
 	// Create a new detached tmux session and start claude in it
 	cmd := exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, t.program)
+	if t.historyLimit > 0 {
+		// tmux sizes a pane's history when the pane is created, so setting the option on a running session has no
+		// effect. Start the session with a placeholder window, set the option, run the program in a new window and
+		// drop the placeholder. This is done in one invocation so the server never sees a session without a window.
+		cmd = exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir,
+			";", "set-option", "-t", t.sanitizedName, "history-limit", strconv.Itoa(t.historyLimit),
+			";", "new-window", "-t", t.sanitizedName, "-c", workDir, t.program,
+			";", "kill-window", "-t", t.sanitizedName+":^")
+	}
 
 	ptmx, err := t.ptyFactory.Start(cmd)
 	if err != nil {
@@ -129,24 +149,16 @@ func (t *TmuxSession) Start(workDir string) error {
 		return fmt.Errorf("error restoring tmux session: %w", err)
 	}
 
-	if t.program == ProgramClaude || strings.HasPrefix(t.program, ProgramAider) || strings.HasPrefix(t.program, ProgramGemini) {
-		searchString := "Do you trust the files in this folder?"
-		tapFunc := t.TapEnter
-		iterations := 5
-		if t.program != ProgramClaude {
-			searchString = "Open documentation url for more info"
-			tapFunc = t.TapDAndEnter
-			iterations = 10 // Aider takes longer to start :/
-		}
-		// Deal with "do you trust the files" screen by sending an enter keystroke.
-		for i := 0; i < iterations; i++ {
+	if trust := agent.GetTrustScreen(t.program); trust != nil {
+		// Deal with "do you trust the files" screen by sending the response keystrokes.
+		for i := 0; i < trust.Iterations; i++ {
 			time.Sleep(200 * time.Millisecond)
 			content, err := t.CapturePaneContent()
 			if err != nil {
 				log.ErrorLog.Printf("could not check 'do you trust the files screen': %v", err)
 			}
-			if strings.Contains(content, searchString) {
-				if err := tapFunc(); err != nil {
+			if strings.Contains(content, trust.SearchString) {
+				if err := t.SendKeys(string(trust.Response)); err != nil {
 					log.ErrorLog.Printf("could not tap enter on trust screen: %v", err)
 				}
 				break
@@ -216,14 +228,8 @@ func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 		return false, false
 	}
 
-	// Only set hasPrompt for claude and aider. Use these strings to check for a prompt.
-	if t.program == ProgramClaude {
-		hasPrompt = strings.Contains(content, "No, and tell Claude what to do differently")
-	} else if strings.HasPrefix(t.program, ProgramAider) {
-		hasPrompt = strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
-	} else if strings.HasPrefix(t.program, ProgramGemini) {
-		hasPrompt = strings.Contains(content, "Yes, allow once")
-	}
+	// Only set hasPrompt for claude, aider and gemini.
+	hasPrompt = agent.HasPrompt(t.program, content)
 
 	if !bytes.Equal(t.monitor.hash(content), t.monitor.prevOutputHash) {
 		t.monitor.prevOutputHash = t.monitor.hash(content)
@@ -343,6 +349,13 @@ func (t *TmuxSession) Detach() {
 	// Cancel goroutines created by Attach.
 	t.cancel()
 	t.wg.Wait()
+
+	// The user may have switched to a companion window while attached. Switch back to the program's window, which
+	// is always the first, since the PTY and capture-pane act on the active window.
+	selectCmd := exec.Command("tmux", "select-window", "-t", t.sanitizedName+":^")
+	if err := t.cmdExec.Run(selectCmd); err != nil {
+		log.ErrorLog.Printf("error selecting program window: %v", err)
+	}
 }
 
 // Close terminates the tmux session and cleans up resources
@@ -397,6 +410,116 @@ func (t *TmuxSession) DoesSessionExist() bool {
 	return t.cmdExec.Run(existsCmd) == nil
 }
 
+// Capture captures the visible content of the tmux pane.
+func (t *TmuxSession) Capture() (string, error) {
+	return t.CapturePaneContent()
+}
+
+// Resize sets the size of the tmux pane while detached.
+func (t *TmuxSession) Resize(width, height int) error {
+	return t.SetDetachedSize(width, height)
+}
+
+// Record pipes the pane output into the asciicast recording at path. The pipe is owned by the tmux server, so
+// recording continues while claude-squad isn't running. It's a no-op if the pane is already being piped.
+func (t *TmuxSession) Record(path string) error {
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	cols, rows := 80, 24
+	if t.ptmx != nil {
+		if size, err := pty.GetsizeFull(t.ptmx); err == nil && size.Cols > 0 && size.Rows > 0 {
+			cols, rows = int(size.Cols), int(size.Rows)
+		}
+	}
+
+	pipeCmd := fmt.Sprintf("%s record --width %d --height %d --title %s %s",
+		shellQuote(execPath), cols, rows, shellQuote(t.sanitizedName), shellQuote(path))
+	cmd := exec.Command("tmux", "pipe-pane", "-o", "-t", t.sanitizedName, pipeCmd)
+	if err := t.cmdExec.Run(cmd); err != nil {
+		return fmt.Errorf("error piping tmux pane to recording: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for use as a single word in a shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// windowTarget returns the tmux target for the companion window with the given name.
+func (t *TmuxSession) windowTarget(name string) string {
+	// `=` makes tmux match the window name exactly instead of as a prefix.
+	return fmt.Sprintf("%s:=%s", t.sanitizedName, name)
+}
+
+// OpenWindow starts command in a new window of the session named name, without switching to it. An empty
+// command starts the user's shell.
+func (t *TmuxSession) OpenWindow(name, workDir, command string) error {
+	// Insert after the last window so the program's window stays the first one, even if a lower index is free.
+	args := []string{"new-window", "-d", "-a", "-t", t.sanitizedName + ":{end}", "-n", name, "-c", workDir}
+	if command != "" {
+		args = append(args, command)
+	}
+	if err := t.cmdExec.Run(exec.Command("tmux", args...)); err != nil {
+		return fmt.Errorf("error creating tmux window %s: %w", name, err)
+	}
+	return nil
+}
+
+// CloseWindow kills the companion window with the given name.
+func (t *TmuxSession) CloseWindow(name string) error {
+	if err := t.cmdExec.Run(exec.Command("tmux", "kill-window", "-t", t.windowTarget(name))); err != nil {
+		return fmt.Errorf("error killing tmux window %s: %w", name, err)
+	}
+	return nil
+}
+
+// SendWindowKeys types keys into the companion window with the given name.
+func (t *TmuxSession) SendWindowKeys(name, keys string) error {
+	cmd := exec.Command("tmux", "send-keys", "-t", t.windowTarget(name), "-l", keys)
+	if err := t.cmdExec.Run(cmd); err != nil {
+		return fmt.Errorf("error sending keys to tmux window %s: %w", name, err)
+	}
+	return nil
+}
+
+// CaptureWindow captures the visible content of the companion window with the given name.
+func (t *TmuxSession) CaptureWindow(name string) (string, error) {
+	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-t", t.windowTarget(name))
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error capturing tmux window %s: %v", name, err)
+	}
+	return string(output), nil
+}
+
+// AttachWindow attaches to the session showing the companion window with the given name.
+func (t *TmuxSession) AttachWindow(name string) (chan struct{}, error) {
+	if err := t.cmdExec.Run(exec.Command("tmux", "select-window", "-t", t.windowTarget(name))); err != nil {
+		return nil, fmt.Errorf("error selecting tmux window %s: %w", name, err)
+	}
+	return t.Attach()
+}
+
+// WindowAlive returns true if the session has a window with the given name. tmux closes a window when its command
+// exits.
+func (t *TmuxSession) WindowAlive(name string) bool {
+	cmd := exec.Command("tmux", "list-windows", "-t", "="+t.sanitizedName, "-F", "#{window_name}")
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(strings.Split(strings.TrimSpace(string(output)), "\n"), name)
+}
+
+// Alive returns true if the tmux session exists.
+func (t *TmuxSession) Alive() bool {
+	return t.DoesSessionExist()
+}
+
 // CapturePaneContent captures the content of the tmux pane
 func (t *TmuxSession) CapturePaneContent() (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
@@ -408,6 +531,27 @@ func (t *TmuxSession) CapturePaneContent() (string, error) {
 	return string(output), nil
 }
 
+// Scrollback captures lines from through to of the pane's history and visible screen, inclusive. Line 0 is the
+// oldest line tmux still retains. A negative to captures through the last visible line.
+func (t *TmuxSession) Scrollback(from, to int) (string, error) {
+	cmd := exec.Command("tmux", "display-message", "-p", "-t", t.sanitizedName, "#{history_size}")
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error getting tmux history size: %v", err)
+	}
+	historySize, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("error parsing tmux history size %q: %v", output, err)
+	}
+
+	// tmux numbers the first visible line 0 and history lines negatively.
+	end := "-"
+	if to >= 0 {
+		end = strconv.Itoa(to - historySize)
+	}
+	return t.CapturePaneContentWithOptions(strconv.Itoa(from-historySize), end)
+}
+
 // CapturePaneContentWithOptions captures the pane content with additional options
 // start and end specify the starting and ending line numbers (use "-" for the start/end of history)
 func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string, error) {
@@ -420,31 +564,56 @@ func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string,
 	return string(output), nil
 }
 
-// CleanupSessions kills all tmux sessions that start with "session-"
-func CleanupSessions(cmdExec cmd.Executor) error {
-	// First try to list sessions
-	cmd := exec.Command("tmux", "ls")
-	output, err := cmdExec.Output(cmd)
+// SessionName returns the name of the tmux session for the instance with the given title.
+func SessionName(title string) string {
+	return toClaudeSquadTmuxName(title)
+}
+
+// ListSessions returns the names of all tmux sessions created by claude-squad, ie. those that start with TmuxPrefix.
+func ListSessions(cmdExec cmd.Executor) ([]string, error) {
+	output, err := cmdExec.Output(exec.Command("tmux", "ls", "-F", "#{session_name}"))
 
 	// If there's an error and it's because no server is running, that's fine
 	// Exit code 1 typically means no sessions exist
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return nil // No sessions to clean up
+			return nil, nil
+		}
+		// Without tmux, there are no sessions either.
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, nil
 		}
-		return fmt.Errorf("failed to list tmux sessions: %v", err)
+		return nil, fmt.Errorf("failed to list tmux sessions: %v", err)
 	}
 
-	re := regexp.MustCompile(fmt.Sprintf(`%s.*:`, TmuxPrefix))
-	matches := re.FindAllString(string(output), -1)
-	for i, match := range matches {
-		matches[i] = match[:strings.Index(match, ":")]
+	var sessions []string
+	for _, name := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(name, TmuxPrefix) {
+			sessions = append(sessions, name)
+		}
+	}
+	return sessions, nil
+}
+
+// KillSession kills the tmux session with the given name.
+func KillSession(cmdExec cmd.Executor, name string) error {
+	if err := cmdExec.Run(exec.Command("tmux", "kill-session", fmt.Sprintf("-t=%s", name))); err != nil {
+		return fmt.Errorf("failed to kill tmux session %s: %v", name, err)
+	}
+	return nil
+}
+
+// CleanupSessions kills all tmux sessions created by claude-squad.
+func CleanupSessions(cmdExec cmd.Executor) error {
+	sessions, err := ListSessions(cmdExec)
+	if err != nil {
+		return err
 	}
 
-	for _, match := range matches {
-		log.InfoLog.Printf("cleaning up session: %s", match)
-		if err := cmdExec.Run(exec.Command("tmux", "kill-session", "-t", match)); err != nil {
-			return fmt.Errorf("failed to kill tmux session %s: %v", match, err)
+	for _, name := range sessions {
+		log.InfoLog.Printf("cleaning up session: %s", name)
+		if err := KillSession(cmdExec, name); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -86,3 +86,97 @@ func TestStartTmuxSession(t *testing.T) {
 	_, err = ptyFactory.files[1].Stat()
 	require.NoError(t, err)
 }
+
+func TestStartTmuxSessionWithHistoryLimit(t *testing.T) {
+	ptyFactory := NewMockPtyFactory(t)
+
+	created := false
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			if strings.Contains(cmd.String(), "has-session") && !created {
+				created = true
+				return fmt.Errorf("session already exists")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("output"), nil
+		},
+	}
+
+	workdir := t.TempDir()
+	session := newTmuxSession("test-session", "claude", ptyFactory, cmdExec)
+	session.SetHistoryLimit(50000)
+
+	err := session.Start(workdir)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(ptyFactory.cmds))
+	require.Equal(t, fmt.Sprintf("tmux new-session -d -s claudesquad_test-session -c %s ; "+
+		"set-option -t claudesquad_test-session history-limit 50000 ; "+
+		"new-window -t claudesquad_test-session -c %s claude ; "+
+		"kill-window -t claudesquad_test-session:^", workdir, workdir),
+		cmd2.ToString(ptyFactory.cmds[0]))
+}
+
+func TestScrollback(t *testing.T) {
+	var captured []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error { return nil },
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			if strings.Contains(cmd.String(), "display-message") {
+				return []byte("120\n"), nil
+			}
+			captured = append(captured, cmd2.ToString(cmd))
+			return []byte("output"), nil
+		},
+	}
+	session := newTmuxSession("test-session", "claude", NewMockPtyFactory(t), cmdExec)
+
+	_, err := session.Scrollback(0, -1)
+	require.NoError(t, err)
+	_, err = session.Scrollback(100, 130)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"tmux capture-pane -p -e -J -S -120 -E - -t claudesquad_test-session",
+		"tmux capture-pane -p -e -J -S -20 -E 10 -t claudesquad_test-session",
+	}, captured)
+}
+
+func TestWindows(t *testing.T) {
+	var ran []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			ran = append(ran, cmd2.ToString(cmd))
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			ran = append(ran, cmd2.ToString(cmd))
+			if cmd.Args[1] == "list-windows" {
+				return []byte("claudesquad_test-session\nshell\n"), nil
+			}
+			return []byte("output"), nil
+		},
+	}
+	session := newTmuxSession("test-session", "claude", NewMockPtyFactory(t), cmdExec)
+
+	require.NoError(t, session.OpenWindow("shell", "/work", ""))
+	require.NoError(t, session.OpenWindow("test", "/work", "go test ./..."))
+	require.NoError(t, session.SendWindowKeys("shell", "ls\r"))
+	content, err := session.CaptureWindow("shell")
+	require.NoError(t, err)
+	require.Equal(t, "output", content)
+	require.NoError(t, session.CloseWindow("test"))
+	require.True(t, session.WindowAlive("shell"))
+	require.False(t, session.WindowAlive("test"))
+
+	require.Equal(t, []string{
+		"tmux new-window -d -a -t claudesquad_test-session:{end} -n shell -c /work",
+		"tmux new-window -d -a -t claudesquad_test-session:{end} -n test -c /work go test ./...",
+		"tmux send-keys -t claudesquad_test-session:=shell -l ls\r",
+		"tmux capture-pane -p -e -J -t claudesquad_test-session:=shell",
+		"tmux kill-window -t claudesquad_test-session:=test",
+		"tmux list-windows -t =claudesquad_test-session -F #{window_name}",
+		"tmux list-windows -t =claudesquad_test-session -F #{window_name}",
+	}, ran)
+}
@@ -0,0 +1,147 @@
+// Package tasks creates sessions in bulk from a manifest of tasks and waits for them to finish.
+package tasks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConcurrency is the number of tasks worked on at once if neither the manifest nor the caller sets it.
+const DefaultConcurrency = 4
+
+// Manifest is a list of tasks to run, read from a YAML or JSON file.
+type Manifest struct {
+	// Concurrency is the number of tasks worked on at once. Zero uses DefaultConcurrency.
+	Concurrency int `yaml:"concurrency" json:"concurrency,omitempty"`
+	// Tasks are the tasks to run, in order.
+	Tasks []Task `yaml:"tasks" json:"tasks"`
+}
+
+// Task is a session to create, and the prompts to work through in it.
+type Task struct {
+	// Title is the title of the session. It must be unique.
+	Title string `yaml:"title" json:"title"`
+	// Repo is the path of the repository. A relative path is relative to the manifest.
+	Repo string `yaml:"repo" json:"repo"`
+	// Base is the branch or commit the session's branch starts from. Empty uses the configured base branch.
+	Base string `yaml:"base" json:"base,omitempty"`
+	// Program is the program to run. Empty uses the configured default program.
+	Program string `yaml:"program" json:"program,omitempty"`
+	// Prompt is the first prompt sent to the program.
+	Prompt string `yaml:"prompt" json:"prompt"`
+	// FollowUps are sent one at a time after the prompt, each time the program is ready for input again.
+	FollowUps []string `yaml:"follow_ups" json:"follow_ups,omitempty"`
+	// AutoYes accepts all of the program's prompts, so the task doesn't wait for someone to answer them.
+	AutoYes bool `yaml:"auto_yes" json:"auto_yes,omitempty"`
+}
+
+// Prompts returns the prompt followed by the follow-ups.
+func (t Task) Prompts() []string {
+	return append([]string{t.Prompt}, t.FollowUps...)
+}
+
+// Load reads the manifest at path and checks it. JSON is read as YAML, which it's a subset of. Repository paths are
+// made absolute.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	manifest, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest directory: %w", err)
+	}
+	for i := range manifest.Tasks {
+		repo, err := expandPath(manifest.Tasks[i].Repo, dir)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Tasks[i].Repo = repo
+	}
+	return manifest, nil
+}
+
+// Parse parses and checks a manifest. Unknown keys are rejected, since they're most likely typos.
+func Parse(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Validate returns an error for every problem with the manifest.
+func (m *Manifest) Validate() error {
+	var errs []error
+	if m.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency: must not be negative, got %d", m.Concurrency))
+	}
+	if len(m.Tasks) == 0 {
+		errs = append(errs, fmt.Errorf("tasks: no tasks"))
+	}
+	titles := make(map[string]bool)
+	for i, task := range m.Tasks {
+		invalid := func(format string, args ...any) {
+			name := fmt.Sprintf("tasks[%d]", i)
+			if task.Title != "" {
+				name = fmt.Sprintf("%s (%s)", name, task.Title)
+			}
+			errs = append(errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+		}
+		switch {
+		case task.Title == "":
+			invalid("title is required")
+		case len(task.Title) > 32:
+			invalid("title must not be longer than 32 characters")
+		case titles[task.Title]:
+			invalid("title is used by more than one task")
+		}
+		titles[task.Title] = true
+		if task.Repo == "" {
+			invalid("repo is required")
+		}
+		if strings.HasPrefix(task.Base, "-") {
+			invalid("base %q must not start with \"-\"", task.Base)
+		}
+		if strings.TrimSpace(task.Prompt) == "" {
+			invalid("prompt is required")
+		}
+		for j, prompt := range task.FollowUps {
+			if strings.TrimSpace(prompt) == "" {
+				invalid("follow_ups[%d] is empty", j)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expandPath makes path absolute, expanding a leading ~ and resolving relative paths against dir.
+func expandPath(path, dir string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %w", path, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
@@ -0,0 +1,229 @@
+package tasks
+
+import (
+	"claude-squad/pkg/engine"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultIdleAfter is how long a session has to sit ready, with nothing left to send, to count as finished.
+const DefaultIdleAfter = 30 * time.Second
+
+// Status is how a task ended.
+type Status string
+
+const (
+	// StatusDone means every prompt was sent and the program went idle.
+	StatusDone Status = "done"
+	// StatusPaused means the session was paused before it went idle, ex. by the idle policy.
+	StatusPaused Status = "paused"
+	// StatusCrashed means the session's terminal died.
+	StatusCrashed Status = "crashed"
+	// StatusTimeout means the task didn't finish within the timeout.
+	StatusTimeout Status = "timeout"
+	// StatusFailed means the session couldn't be created or its prompts couldn't be queued.
+	StatusFailed Status = "failed"
+	// StatusCanceled means the run was canceled before the task finished.
+	StatusCanceled Status = "canceled"
+)
+
+// Result is the outcome of a task.
+type Result struct {
+	Task      Task
+	SessionID string
+	Status    Status
+	// Err is set if the task failed.
+	Err error
+	// Diff is the session's last diff against its base, or nil if it has no changes.
+	Diff *engine.DiffStats
+	// Queued is the number of prompts that weren't sent when the task ended.
+	Queued   int
+	Duration time.Duration
+}
+
+// OK returns true if the task got through all of its prompts. A session paused with prompts still queued didn't.
+func (r Result) OK() bool {
+	return r.Status == StatusDone || r.Status == StatusPaused && r.Queued == 0
+}
+
+// Runner creates a session for each task of a manifest and waits for them to go idle.
+type Runner struct {
+	engine *engine.Engine
+
+	// Concurrency is the number of tasks worked on at once. Zero uses the manifest's concurrency.
+	Concurrency int
+	// IdleAfter is how long a session has to sit ready, with nothing left to send, to count as finished. It must be
+	// positive.
+	IdleAfter time.Duration
+	// Timeout is how long each task may take. Zero means no limit.
+	Timeout time.Duration
+	// Progress receives a line whenever a task starts or ends. It may be nil.
+	Progress   io.Writer
+	progressMu sync.Mutex
+}
+
+// NewRunner creates a runner that creates sessions with eng, which must be started.
+func NewRunner(eng *engine.Engine) *Runner {
+	return &Runner{engine: eng, IdleAfter: DefaultIdleAfter}
+}
+
+// Run works through the tasks of manifest and returns their results in the manifest's order. The sessions are left
+// running, so they can be reviewed afterwards. Canceling ctx stops waiting, but doesn't stop the sessions.
+func (r *Runner) Run(ctx context.Context, manifest *Manifest) []Result {
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = manifest.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([]Result, len(manifest.Tasks))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, task := range manifest.Tasks {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = Result{Task: task, Status: StatusCanceled, Err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = r.runTask(ctx, task)
+			r.progress("%s: %s", task.Title, results[i].Status)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runTask creates the session of a task, queues its prompts and waits for it to go idle.
+func (r *Runner) runTask(ctx context.Context, task Task) Result {
+	start := time.Now()
+	result := Result{Task: task}
+
+	r.progress("%s: starting in %s", task.Title, task.Repo)
+	id, err := r.engine.StartSession(ctx, engine.SessionOpts{
+		Title:   task.Title,
+		Path:    task.Repo,
+		Program: task.Program,
+		AutoYes: task.AutoYes,
+		BaseRef: task.Base,
+	})
+	if err != nil {
+		result.Status = StatusFailed
+		result.Err = err
+		return result
+	}
+	result.SessionID = id
+
+	events, err := r.engine.Events(id)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Err = err
+		return result
+	}
+	// Queue the first prompt too, so it's only sent once the program is ready for it.
+	for _, prompt := range task.Prompts() {
+		if err := r.engine.Enqueue(id, prompt); err != nil {
+			result.Status = StatusFailed
+			result.Err = fmt.Errorf("failed to queue prompt: %w", err)
+			return result
+		}
+	}
+
+	result.Status, result.Diff, result.Queued = r.waitIdle(ctx, events, len(task.Prompts()))
+	result.Duration = time.Since(start)
+	if result.Status == StatusTimeout {
+		result.Err = fmt.Errorf("still busy after %s", r.Timeout)
+	}
+	return result
+}
+
+// waitIdle follows the events of a session until it has been ready, with nothing queued, for IdleAfter. It returns
+// how the session ended, its last diff and the number of prompts still queued.
+func (r *Runner) waitIdle(ctx context.Context, events <-chan engine.Event, queued int) (Status, *engine.DiffStats,
+	int) {
+	var timeout <-chan time.Time
+	if r.Timeout > 0 {
+		timer := time.NewTimer(r.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	idle := time.NewTimer(r.IdleAfter)
+	defer idle.Stop()
+
+	status := engine.StatusRunning
+	var diff *engine.DiffStats
+	for {
+		select {
+		case <-ctx.Done():
+			return StatusCanceled, diff, queued
+		case <-timeout:
+			return StatusTimeout, diff, queued
+		case event, ok := <-events:
+			if !ok {
+				return StatusCanceled, diff, queued
+			}
+			switch payload := event.Payload.(type) {
+			case engine.StateEvent:
+				status = payload.Current
+				switch status {
+				case engine.StatusCrashed:
+					return StatusCrashed, diff, queued
+				case engine.StatusPaused:
+					return StatusPaused, diff, queued
+				}
+			case engine.QueueEvent:
+				queued = len(payload.Prompts)
+			case engine.DiffEvent:
+				diff = payload.Stats
+			}
+			// Any sign of life restarts the wait.
+			idle.Reset(r.IdleAfter)
+		case <-idle.C:
+			if status == engine.StatusReady && queued == 0 {
+				return StatusDone, diff, queued
+			}
+			idle.Reset(r.IdleAfter)
+		}
+	}
+}
+
+func (r *Runner) progress(format string, args ...any) {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	if r.Progress != nil {
+		fmt.Fprintf(r.Progress, format+"\n", args...)
+	}
+}
+
+// WriteSummary writes a table of results to w.
+func WriteSummary(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tSTATUS\tADDED\tREMOVED\tDURATION\tERROR")
+	for _, result := range results {
+		added, removed := "-", "-"
+		if result.Diff != nil {
+			added = fmt.Sprintf("+%d", result.Diff.Added)
+			removed = fmt.Sprintf("-%d", result.Diff.Removed)
+		}
+		duration := "-"
+		if result.Duration > 0 {
+			duration = result.Duration.Round(time.Second).String()
+		}
+		errText := ""
+		if result.Err != nil {
+			errText = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Task.Title, result.Status, added, removed, duration, errText)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/pkg/engine"
+	"claude-squad/session"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+
+	os.Exit(m.Run())
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+
+	t.Run("yaml", func(t *testing.T) {
+		path := filepath.Join(dir, "tasks.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+concurrency: 2
+tasks:
+  - title: one
+    repo: ../app
+    base: main
+    program: aider
+    prompt: Refactor the parser
+    follow_ups: [Now add tests, Now run the linter]
+    auto_yes: true
+  - title: two
+    repo: ~/src/app
+    prompt: Fix the flaky test
+`), 0644))
+		manifest, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, manifest.Concurrency)
+		assert.Equal(t, []Task{
+			{
+				Title:     "one",
+				Repo:      filepath.Join(filepath.Dir(dir), "app"),
+				Base:      "main",
+				Program:   "aider",
+				Prompt:    "Refactor the parser",
+				FollowUps: []string{"Now add tests", "Now run the linter"},
+				AutoYes:   true,
+			},
+			{Title: "two", Repo: filepath.Join(home, "src", "app"), Prompt: "Fix the flaky test"},
+		}, manifest.Tasks)
+		assert.Equal(t, []string{"Refactor the parser", "Now add tests", "Now run the linter"},
+			manifest.Tasks[0].Prompts())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "tasks.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"tasks": [{"title": "one", "repo": "/src/app", "prompt": "go"}]}`), 0644))
+		manifest, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, []Task{{Title: "one", Repo: "/src/app", Prompt: "go"}}, manifest.Tasks)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for content, want := range map[string]string{
+			`tasks: []`: "tasks: no tasks",
+			`{"tasks": [{"title": "one", "repo": "/app", "promt": "go"}]}`:                               "field promt not found",
+			"tasks:\n  - {title: one, repo: /app, prompt: go}\n  - {title: one, repo: /app, prompt: go}": "tasks[1] (one): title is used by more than one task",
+			"tasks:\n  - {repo: /app, prompt: go, base: --all}":                                          "tasks[0]: title is required\ntasks[0]: base \"--all\" must not start with \"-\"",
+			"tasks:\n  - {title: one, prompt: ' ', follow_ups: ['']}":                                    "tasks[0] (one): repo is required\ntasks[0] (one): prompt is required\ntasks[0] (one): follow_ups[0] is empty",
+		} {
+			_, err := Parse([]byte(content))
+			assert.ErrorContains(t, err, want, content)
+		}
+	})
+}
+
+// memoryState keeps the state in memory instead of the state file.
+type memoryState struct {
+	instances json.RawMessage
+}
+
+func (s *memoryState) SaveInstances(instancesJSON json.RawMessage) error {
+	s.instances = instancesJSON
+	return nil
+}
+
+func (s *memoryState) GetInstances() json.RawMessage        { return s.instances }
+func (s *memoryState) DeleteAllInstances() error            { s.instances = nil; return nil }
+func (s *memoryState) GetHelpScreensSeen() uint32           { return 0 }
+func (s *memoryState) SetHelpScreensSeen(seen uint32) error { return nil }
+
+func TestRunner(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	cfg := &config.Config{DefaultProgram: "cat", DaemonPollInterval: 1000, BranchPrefix: "test/",
+		TerminalBackend: session.BackendHeadless}
+	eng, err := engine.New(cfg, &memoryState{instances: json.RawMessage("[]")})
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+	defer eng.Close()
+
+	manifest := &Manifest{Tasks: []Task{
+		{Title: "first", Repo: repo, Base: "main", Prompt: "hello", FollowUps: []string{"again"}},
+		{Title: "missing-base", Repo: repo, Base: "nope", Prompt: "hello"},
+		{Title: "second", Repo: repo, Prompt: "hi"},
+	}}
+	var progress bytes.Buffer
+	runner := NewRunner(eng)
+	runner.Concurrency = 2
+	runner.IdleAfter = 1500 * time.Millisecond
+	runner.Timeout = 20 * time.Second
+	runner.Progress = &progress
+	results := runner.Run(context.Background(), manifest)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, StatusDone, results[0].Status, results[0].Err)
+	assert.Equal(t, StatusFailed, results[1].Status)
+	assert.ErrorContains(t, results[1].Err, "failed to resolve base branch nope")
+	assert.Equal(t, StatusDone, results[2].Status, results[2].Err)
+	assert.Contains(t, progress.String(), "first: done")
+
+	var summary bytes.Buffer
+	require.NoError(t, WriteSummary(&summary, results))
+	assert.Regexp(t, `TASK +STATUS +ADDED +REMOVED +DURATION +ERROR\n`, summary.String())
+	assert.Regexp(t, `\nmissing-base +failed +- +- +- +failed to start instance`, summary.String())
+}
+
+func TestResultOK(t *testing.T) {
+	assert.True(t, Result{Status: StatusDone}.OK())
+	// A session paused once it sent everything finished its task, but not one paused halfway.
+	assert.True(t, Result{Status: StatusPaused}.OK())
+	assert.False(t, Result{Status: StatusPaused, Queued: 2}.OK())
+	assert.False(t, Result{Status: StatusCrashed}.OK())
+}
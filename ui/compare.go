@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxCompareFiles is the number of files listed for the selected instance.
+const maxCompareFiles = 8
+
+// CompareAction is what the app should do after a key press in the compare panel.
+type CompareAction int
+
+const (
+	// CompareActionNone means the panel handled the key itself.
+	CompareActionNone CompareAction = iota
+	// CompareActionKeep means the user wants to keep the selected instance and kill its siblings.
+	CompareActionKeep
+	// CompareActionClose means the panel should be closed.
+	CompareActionClose
+)
+
+// ComparePanel shows the instances of a fan-out side by side: how much each changed, which files and how long its
+// program took to become ready after the prompt.
+type ComparePanel struct {
+	group     string
+	instances []*session.Instance
+	selected  int
+	width     int
+}
+
+// NewComparePanel creates a compare panel for the instances of group, with selected highlighted.
+func NewComparePanel(group string, instances []*session.Instance, selected *session.Instance) *ComparePanel {
+	c := &ComparePanel{group: group, instances: instances}
+	for i, instance := range instances {
+		if instance == selected {
+			c.selected = i
+		}
+	}
+	return c
+}
+
+// SetWidth sets the width of the panel, including its border.
+func (c *ComparePanel) SetWidth(width int) {
+	c.width = width
+}
+
+// Selected returns the highlighted instance.
+func (c *ComparePanel) Selected() *session.Instance {
+	return c.instances[c.selected]
+}
+
+// HandleKeyPress handles a key press in the panel and returns what the app should do next.
+func (c *ComparePanel) HandleKeyPress(msg tea.KeyMsg) CompareAction {
+	switch msg.String() {
+	case "up", "k":
+		c.selected = max(c.selected-1, 0)
+	case "down", "j":
+		c.selected = min(c.selected+1, len(c.instances)-1)
+	case "enter":
+		return CompareActionKeep
+	case "esc", "q":
+		return CompareActionClose
+	}
+	return CompareActionNone
+}
+
+// Render renders the panel.
+func (c *ComparePanel) Render() string {
+	rows := [][]string{{"SESSION", "PROGRAM", "STATUS", "ADDED", "REMOVED", "FILES", "READY IN"}}
+	for _, instance := range c.instances {
+		added, removed, files := "-", "-", "-"
+		if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+			added = fmt.Sprintf("+%d", stats.Added)
+			removed = fmt.Sprintf("-%d", stats.Removed)
+			files = fmt.Sprint(len(stats.Files))
+		}
+		readyIn := "-"
+		if instance.TimeToReady > 0 {
+			readyIn = instance.TimeToReady.Round(time.Second).String()
+		}
+		rows = append(rows, []string{
+			instance.Title, instance.Program, statusText(instance.Status), added, removed, files, readyIn,
+		})
+	}
+
+	// Size the columns to their widest cell, and let the program column give way if the panel is too narrow.
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(cell))
+		}
+	}
+	// Leave room for the border, padding and the gaps between columns.
+	if over := sum(widths) + 2*(len(widths)-1) - max(c.width-6, 0); c.width > 0 && over > 0 {
+		widths[1] = max(widths[1]-over, 5)
+	}
+
+	lines := []string{queueTitleStyle.Render(fmt.Sprintf("Fan-out %s", c.group)), ""}
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cell = ansi.Truncate(cell, widths[j], "…")
+			cells[j] = cell + strings.Repeat(" ", widths[j]-ansi.StringWidth(cell))
+		}
+		line := strings.Join(cells, "  ")
+		switch {
+		case i == 0:
+			line = descStyle.Render(line)
+		case i-1 == c.selected:
+			line = queueSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", descStyle.Render(fmt.Sprintf("Files changed by %s:", c.Selected().Title)))
+	var files []string
+	if stats := c.Selected().GetDiffStats(); stats != nil && stats.Error == nil {
+		files = stats.Paths()
+	}
+	if len(files) == 0 {
+		lines = append(lines, descStyle.Render("  none yet"))
+	}
+	for i, file := range files {
+		if i == maxCompareFiles {
+			lines = append(lines, descStyle.Render(fmt.Sprintf("  and %d more", len(files)-maxCompareFiles)))
+			break
+		}
+		lines = append(lines, "  "+file)
+	}
+
+	lines = append(lines, "",
+		keyStyle.Render("↑/↓")+descStyle.Render(" select • ")+
+			keyStyle.Render("enter")+descStyle.Render(" keep this one, kill the rest • ")+
+			keyStyle.Render("esc")+descStyle.Render(" close"),
+	)
+
+	style := queuePanelStyle
+	if c.width > 0 {
+		style = style.Width(c.width)
+	}
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// statusText describes an instance status in a word.
+func statusText(status session.Status) string {
+	switch status {
+	case session.Running:
+		return "running"
+	case session.Ready:
+		return "ready"
+	case session.Loading:
+		return "loading"
+	case session.Paused:
+		return "paused"
+	case session.Crashed:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"claude-squad/session/git"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ConflictAction is what the app should do after a key press in the conflict panel.
+type ConflictAction int
+
+const (
+	// ConflictActionNone means the panel handled the key itself.
+	ConflictActionNone ConflictAction = iota
+	// ConflictActionMerge means the user wants to simulate merging the instance with the selected one.
+	ConflictActionMerge
+	// ConflictActionClose means the panel should be closed.
+	ConflictActionClose
+)
+
+// mergeCheck is a simulated merge with another instance: running, or done with a result or an error.
+type mergeCheck struct {
+	result *git.MergeResult
+	err    error
+}
+
+// ConflictPanel shows the other instances of the same repository that changed the same files as an instance, and the
+// lines of each file both changed. Merging the instance with one of them can be simulated, to see whether the
+// overlapping changes actually conflict.
+type ConflictPanel struct {
+	instance  *session.Instance
+	conflicts []session.Conflict
+	selected  int
+	width     int
+	// checks are the simulated merges with the other instances. A check without a result or error is running.
+	checks map[*session.Instance]*mergeCheck
+}
+
+// NewConflictPanel creates a conflict panel for instance, with what it changed that others changed too.
+func NewConflictPanel(instance *session.Instance, conflicts []session.Conflict) *ConflictPanel {
+	return &ConflictPanel{
+		instance:  instance,
+		conflicts: conflicts,
+		checks:    make(map[*session.Instance]*mergeCheck),
+	}
+}
+
+// SetWidth sets the width of the panel, including its border.
+func (c *ConflictPanel) SetWidth(width int) {
+	c.width = width
+}
+
+// Instance returns the instance the panel is about.
+func (c *ConflictPanel) Instance() *session.Instance {
+	return c.instance
+}
+
+// Selected returns the highlighted other instance, or nil if no instance overlaps.
+func (c *ConflictPanel) Selected() *session.Instance {
+	if len(c.conflicts) == 0 {
+		return nil
+	}
+	return c.conflicts[c.selected].Other
+}
+
+// SetMergeResult records the outcome of simulating the merge with other.
+func (c *ConflictPanel) SetMergeResult(other *session.Instance, result *git.MergeResult, err error) {
+	c.checks[other] = &mergeCheck{result: result, err: err}
+}
+
+// HandleKeyPress handles a key press in the panel and returns what the app should do next.
+func (c *ConflictPanel) HandleKeyPress(msg tea.KeyMsg) ConflictAction {
+	switch msg.String() {
+	case "up", "k":
+		c.selected = max(c.selected-1, 0)
+	case "down", "j":
+		c.selected = max(min(c.selected+1, len(c.conflicts)-1), 0)
+	case "enter", "m":
+		other := c.Selected()
+		if other == nil {
+			return ConflictActionNone
+		}
+		if check, ok := c.checks[other]; ok && check.result == nil && check.err == nil {
+			// Already running
+			return ConflictActionNone
+		}
+		c.checks[other] = &mergeCheck{}
+		return ConflictActionMerge
+	case "esc", "q":
+		return ConflictActionClose
+	}
+	return ConflictActionNone
+}
+
+// Render renders the panel.
+func (c *ConflictPanel) Render() string {
+	lines := []string{queueTitleStyle.Render(fmt.Sprintf("Overlapping changes of %s", c.instance.Title)), ""}
+	if len(c.conflicts) == 0 {
+		lines = append(lines, descStyle.Render("No other session changed the same files."), "",
+			keyStyle.Render("esc")+descStyle.Render(" close"))
+		return c.style().Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	}
+
+	rows := [][]string{{"SESSION", "FILES", "HUNKS", "MERGE"}}
+	for _, conflict := range c.conflicts {
+		rows = append(rows, []string{
+			conflict.Other.Title, fmt.Sprint(len(conflict.Files)), fmt.Sprint(conflict.Hunks()),
+			c.mergeText(conflict.Other),
+		})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(cell))
+		}
+	}
+	// Leave room for the border, padding and the gaps between columns, and let the session column give way.
+	if over := sum(widths) + 2*(len(widths)-1) - max(c.width-6, 0); c.width > 0 && over > 0 {
+		widths[0] = max(widths[0]-over, 5)
+	}
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cell = ansi.Truncate(cell, widths[j], "…")
+			cells[j] = cell + strings.Repeat(" ", widths[j]-ansi.StringWidth(cell))
+		}
+		line := strings.Join(cells, "  ")
+		switch {
+		case i == 0:
+			line = descStyle.Render(line)
+		case i-1 == c.selected:
+			line = queueSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	conflict := c.conflicts[c.selected]
+	lines = append(lines, "", descStyle.Render("Files both changed, with the lines both changed:"))
+	for i, file := range conflict.Files {
+		if i == maxCompareFiles {
+			lines = append(lines, descStyle.Render(
+				fmt.Sprintf("  and %d more", len(conflict.Files)-maxCompareFiles)))
+			break
+		}
+		line := "  " + file.Path
+		if len(file.Lines) > 0 {
+			line += conflictStyle.Render(" " + lineRangesText(file.Lines))
+		}
+		lines = append(lines, ansi.Truncate(line, max(c.width-6, 0), "…"))
+	}
+	if check := c.checks[conflict.Other]; check != nil && check.result != nil && len(check.result.Conflicts) > 0 {
+		lines = append(lines, "", descStyle.Render("Conflicts when merged:"))
+		for i, path := range check.result.Conflicts {
+			if i == maxCompareFiles {
+				lines = append(lines, descStyle.Render(
+					fmt.Sprintf("  and %d more", len(check.result.Conflicts)-maxCompareFiles)))
+				break
+			}
+			lines = append(lines, crashedStyle.Render("  "+path))
+		}
+	} else if check != nil && check.err != nil {
+		lines = append(lines, "", crashedStyle.Render(ansi.Truncate(check.err.Error(), max(c.width-6, 0), "…")))
+	}
+
+	lines = append(lines, "",
+		keyStyle.Render("↑/↓")+descStyle.Render(" select • ")+
+			keyStyle.Render("enter")+descStyle.Render(" simulate merge • ")+
+			keyStyle.Render("esc")+descStyle.Render(" close"),
+	)
+	return c.style().Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// mergeText describes the simulated merge with other in a few words.
+func (c *ConflictPanel) mergeText(other *session.Instance) string {
+	check, ok := c.checks[other]
+	switch {
+	case !ok:
+		return "-"
+	case check.err != nil:
+		return "failed"
+	case check.result == nil:
+		return "checking…"
+	case check.result.Clean:
+		return "clean"
+	default:
+		return fmt.Sprintf("%d conflicted", len(check.result.Conflicts))
+	}
+}
+
+func (c *ConflictPanel) style() lipgloss.Style {
+	style := queuePanelStyle
+	if c.width > 0 {
+		style = style.Width(c.width)
+	}
+	return style
+}
+
+// lineRangesText formats line ranges, ex. "lines 3, 10-12".
+func lineRangesText(ranges []session.LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = fmt.Sprint(r.Start)
+		if r.End > r.Start {
+			parts[i] += fmt.Sprintf("-%d", r.End)
+		}
+	}
+	label := "line "
+	if len(ranges) > 1 || ranges[0].End > ranges[0].Start {
+		label = "lines "
+	}
+	return label + strings.Join(parts, ", ")
+}
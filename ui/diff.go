@@ -2,100 +2,287 @@ package ui
 
 import (
 	"claude-squad/session"
+	"claude-squad/session/git"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 var (
-	AdditionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
-	DeletionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ef4444"))
-	HunkStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#0ea5e9"))
+	AdditionStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
+	DeletionStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ef4444"))
+	HunkStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#0ea5e9"))
+	FileHeaderStyle = lipgloss.NewStyle().Bold(true)
+
+	fileListSeparatorStyle = lipgloss.NewStyle().Foreground(highlightColor)
+	fileCursorStyle        = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"}).
+				Background(lipgloss.AdaptiveColor{Light: "#dde4f0", Dark: "#dde4f0"})
+	fileSelectedStyle  = lipgloss.NewStyle().Bold(true).Foreground(highlightColor)
+	dirStyle           = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+	reviewCommentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD700"))
+	sentCommentStyle   = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+	sparklineStyle     = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+)
+
+// DiffAction is what the app should do after a key press in the diff navigator.
+type DiffAction int
+
+const (
+	// DiffActionNone means the pane handled the key itself.
+	DiffActionNone DiffAction = iota
+	// DiffActionComment means the user wants to type a comment on the line under the cursor; see CommentTarget.
+	DiffActionComment
+	// DiffActionReview means the user wants to send the instance's comments to its program.
+	DiffActionReview
+	// DiffActionClose means the user is done navigating the diff.
+	DiffActionClose
+)
+
+const (
+	// minFileListDiffWidth is the narrowest the diff pane can be and still show the file list.
+	minFileListDiffWidth = 60
+	// maxFileListWidth is the widest the file list gets.
+	maxFileListWidth = 40
+	// maxSparklineWidth is the widest the sparkline of the diff history gets.
+	maxSparklineWidth = 40
 )
 
+// sparkBars are the bars of the sparkline, from the smallest to the largest diff.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
 type DiffPane struct {
 	viewport viewport.Model
-	diff     string
 	stats    string
-	width    int
-	height   int
+	// message is shown instead of the diff, ex. "No changes". It's empty if there's a diff to show.
+	message string
+	width   int
+	height  int
+
+	// instance and content are what the pane last showed, so a refresh with the same diff keeps the scroll position.
+	instance *session.Instance
+	content  string
+
+	files []git.FileDiff
+	// rows are the visible rows of the file list: directories and files, in tree order.
+	rows []diffRow
+	// collapsed holds the paths of the directories whose files are hidden.
+	collapsed map[string]bool
+	// cursor is the row of the file list under the cursor.
+	cursor int
+	// selected is the path of the file whose diff is shown.
+	selected string
+	// listOffset is the first row of the file list that's shown.
+	listOffset int
+	// hunkOffsets are the lines of the shown file's diff that start a hunk.
+	hunkOffsets []int
+	// lines are the rendered lines of the shown file's diff, with its comments under the lines they're on.
+	// lineNumbers holds the line of the new file each shows, or 0, and lineComments the ID of the comment each
+	// shows, or "".
+	lines        []string
+	lineNumbers  []int
+	lineComments []string
+	// lineCursor is the line of the shown file's diff under the cursor, which comments go on.
+	lineCursor int
+	// comments are the instance's review comments, as last shown.
+	comments []session.ReviewComment
+	// hideFiles is true if the user hid the file list.
+	hideFiles bool
+	// split is true if the old and new lines are shown side by side, when the pane is wide enough.
+	split bool
+	// focused is true while the user navigates the diff with the keyboard.
+	focused bool
+	// history holds the snapshots of the instance's diff over time, as last shown.
+	history []session.DiffSnapshot
+	// turn is the turn of the program whose diff is shown, counting from 1, or 0 for the current diff.
+	turn int
 }
 
 func NewDiffPane() *DiffPane {
 	return &DiffPane{
-		viewport: viewport.New(0, 0),
+		viewport:  viewport.New(0, 0),
+		collapsed: make(map[string]bool),
 	}
 }
 
 func (d *DiffPane) SetSize(width, height int) {
 	d.width = width
 	d.height = height
-	d.viewport.Width = width
-	d.viewport.Height = height
-	// Update viewport content if diff exists
-	if d.diff != "" || d.stats != "" {
-		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
-	}
+	d.viewport.Width = width - d.fileListWidth()
+	// The stats take a line at the top
+	d.viewport.Height = max(height-1, 0)
+	d.showSelected(false)
 }
 
 func (d *DiffPane) SetDiff(instance *session.Instance) {
-	centeredFallbackMessage := lipgloss.Place(
-		d.width,
-		d.height,
-		lipgloss.Center,
-		lipgloss.Center,
-		"No changes",
-	)
+	if instance != d.instance {
+		// Start over for another instance
+		d.instance = instance
+		d.content = ""
+		d.collapsed = make(map[string]bool)
+		d.cursor = 0
+		d.selected = ""
+		d.listOffset = 0
+		d.comments = nil
+		d.turn = 0
+	}
 
 	if instance == nil || !instance.Started() {
-		d.viewport.SetContent(centeredFallbackMessage)
+		d.setMessage("No changes")
+		return
+	}
+
+	history := instance.DiffHistory()
+	historyChanged := len(history) != len(d.history) ||
+		(len(history) > 0 && history[len(history)-1] != d.history[len(d.history)-1])
+	d.history = history
+	if d.turn > 0 {
+		d.showTurn()
 		return
 	}
 
 	stats := instance.GetDiffStats()
 	if stats == nil {
 		// Show loading message if worktree is not ready
-		centeredMessage := lipgloss.Place(
-			d.width,
-			d.height,
-			lipgloss.Center,
-			lipgloss.Center,
-			"Setting up worktree...",
-		)
-		d.viewport.SetContent(centeredMessage)
+		d.setMessage("Setting up worktree...")
 		return
 	}
 
 	if stats.Error != nil {
-		// Show error message
-		centeredMessage := lipgloss.Place(
-			d.width,
-			d.height,
-			lipgloss.Center,
-			lipgloss.Center,
-			fmt.Sprintf("Error: %v", stats.Error),
-		)
-		d.viewport.SetContent(centeredMessage)
+		d.setMessage(fmt.Sprintf("Error: %v", stats.Error))
 		return
 	}
 
 	if stats.IsEmpty() {
-		d.stats = ""
-		d.diff = ""
-		d.viewport.SetContent(centeredFallbackMessage)
-	} else {
-		additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", stats.Added))
-		deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", stats.Removed))
-		d.stats = lipgloss.JoinHorizontal(lipgloss.Center, additions, " ", deletions)
-		d.diff = colorizeDiff(stats.Content)
-		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
+		d.setMessage("No changes")
+		return
+	}
+
+	comments := instance.Comments()
+	if d.message == "" && stats.Content == d.content && !historyChanged {
+		if !reflect.DeepEqual(comments, d.comments) {
+			d.comments = comments
+			d.showSelected(false)
+		}
+		return
 	}
+	d.comments = comments
+
+	additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", stats.Added))
+	deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", stats.Removed))
+	d.setStats(stats, lipgloss.JoinHorizontal(lipgloss.Center, additions, " ", deletions, " ", filesText(len(stats.Files))),
+		-1)
+}
+
+// showTurn shows the diff as it was at the end of the selected turn of the program.
+func (d *DiffPane) showTurn() {
+	turns := turnSnapshots(d.history)
+	if len(turns) == 0 {
+		d.turn = 0
+		d.SetDiff(d.instance)
+		return
+	}
+	d.turn = min(d.turn, len(turns))
+	index := turns[d.turn-1]
+	snapshot := d.history[index]
+	summary := fmt.Sprintf("Turn %d of %d, %s: ", d.turn, len(turns), snapshot.Time.Local().Format("15:04"))
+	counts := fmt.Sprintf("+%d -%d %s", snapshot.Added, snapshot.Removed, filesText(snapshot.Files))
+	if snapshot.Content == "" {
+		d.setMessage(summary + counts + "\n\nSet diff.keep_turn_diffs in the config to keep the whole diff of each turn.")
+		return
+	}
+	if d.message == "" && snapshot.Content == d.content {
+		return
+	}
+
+	summary += AdditionStyle.Render(fmt.Sprintf("+%d", snapshot.Added)) + " " +
+		DeletionStyle.Render(fmt.Sprintf("-%d", snapshot.Removed)) + " " + filesText(snapshot.Files)
+	if d.turn > 1 {
+		previous := d.history[turns[d.turn-2]]
+		summary += descStyle.Render(fmt.Sprintf(" (%+d added, %+d removed since turn %d)",
+			snapshot.Added-previous.Added, snapshot.Removed-previous.Removed, d.turn-1))
+	}
+	d.setStats(git.NewDiffStats(snapshot.Content), summary, index)
+}
+
+// setStats shows the diff of stats, under a line with summary and the sparkline of the history. highlight is the
+// snapshot of the history to highlight in the sparkline, or -1.
+func (d *DiffPane) setStats(stats *git.DiffStats, summary string, highlight int) {
+	d.message = ""
+	d.content = stats.Content
+	d.files = stats.Files
+
+	d.stats = summary
+	if width := min(d.width-ansi.StringWidth(summary)-2, maxSparklineWidth); len(d.history) > 1 && width > 0 {
+		spark := sparkline(d.history, width, highlight)
+		d.stats += strings.Repeat(" ", d.width-ansi.StringWidth(summary)-ansi.StringWidth(spark)) + spark
+	}
+
+	d.rows = buildDiffRows(d.files, d.collapsed)
+	// Stay on the same file if it's still changed
+	d.cursor = min(d.cursor, max(len(d.rows)-1, 0))
+	for i, row := range d.rows {
+		if !row.dir && row.path == d.selected {
+			d.cursor = i
+		}
+	}
+	if d.selectedFile() == nil {
+		d.selectFirstFile()
+	}
+	d.showSelected(false)
+}
+
+// StepTurn shows the diff at the end of an earlier turn of the program if delta is negative, or a later one if it's
+// positive. Stepping past the last turn shows the current diff again.
+func (d *DiffPane) StepTurn(delta int) {
+	turns := len(turnSnapshots(d.history))
+	if turns == 0 {
+		return
+	}
+	switch {
+	case d.turn == 0 && delta < 0:
+		d.turn = turns
+	case d.turn == 0:
+		return
+	default:
+		d.turn = max(d.turn+delta, 1)
+		if d.turn > turns {
+			d.turn = 0
+		}
+	}
+	// Render again, even if the diff is the same
+	d.content = ""
+	d.SetDiff(d.instance)
+}
+
+// setMessage shows message instead of a diff.
+func (d *DiffPane) setMessage(message string) {
+	d.message = message
+	d.content = ""
+	d.stats = ""
+	d.files = nil
+	d.rows = nil
+	d.hunkOffsets = nil
+	d.lines, d.lineNumbers, d.lineComments = nil, nil, nil
 }
 
 func (d *DiffPane) String() string {
-	return d.viewport.View()
+	if d.message != "" {
+		return lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center, d.message)
+	}
+
+	body := d.viewport.View()
+	if listWidth := d.fileListWidth(); listWidth > 0 {
+		separator := fileListSeparatorStyle.Render(strings.TrimSuffix(strings.Repeat("│ \n", d.viewport.Height), "\n"))
+		body = lipgloss.JoinHorizontal(lipgloss.Top, d.renderFileList(listWidth-2), separator, body)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, d.stats, body)
 }
 
 // ScrollUp scrolls the viewport up
@@ -108,30 +295,410 @@ func (d *DiffPane) ScrollDown() {
 	d.viewport.LineDown(1)
 }
 
-func colorizeDiff(diff string) string {
-	var coloredOutput strings.Builder
-
-	lines := strings.Split(diff, "\n")
-	for _, line := range lines {
-		if len(line) > 0 {
-			if strings.HasPrefix(line, "@@") {
-				// Color hunk headers cyan
-				coloredOutput.WriteString(HunkStyle.Render(line) + "\n")
-			} else if line[0] == '+' && (len(line) == 1 || line[1] != '+') {
-				// Color added lines green, excluding metadata like '+++'
-				coloredOutput.WriteString(AdditionStyle.Render(line) + "\n")
-			} else if line[0] == '-' && (len(line) == 1 || line[1] != '-') {
-				// Color removed lines red, excluding metadata like '---'
-				coloredOutput.WriteString(DeletionStyle.Render(line) + "\n")
+// ToggleSplit switches between showing the diff unified and side by side.
+func (d *DiffPane) ToggleSplit() {
+	d.split = !d.split
+	d.showSelected(false)
+}
+
+// Focus starts keyboard navigation of the files, hunks and lines of the diff.
+func (d *DiffPane) Focus() {
+	d.focused = true
+	d.renderLines()
+}
+
+// HandleKeyPress handles a key press while the diff is focused and returns what the app should do next.
+func (d *DiffPane) HandleKeyPress(msg tea.KeyMsg) DiffAction {
+	switch msg.String() {
+	case "esc", "q", "f", "ctrl+c":
+		d.focused = false
+		d.renderLines()
+		if d.turn > 0 {
+			// Back to the current diff
+			d.turn = 0
+			d.content = ""
+			d.SetDiff(d.instance)
+		}
+		return DiffActionClose
+	case "up", "k":
+		d.moveCursor(-1)
+	case "down", "j":
+		d.moveCursor(1)
+	case "left", "h":
+		d.collapse()
+	case "right", "l":
+		d.expand()
+	case "enter", " ":
+		if row := d.cursorRow(); row != nil && row.dir {
+			if d.collapsed[row.path] {
+				d.expand()
 			} else {
-				// Print metadata and unchanged lines without color
-				coloredOutput.WriteString(line + "\n")
+				d.collapse()
+			}
+		}
+	case "n", "]":
+		d.jumpHunk(1)
+	case "N", "[":
+		d.jumpHunk(-1)
+	case "shift+up":
+		d.moveLine(-1)
+	case "shift+down":
+		d.moveLine(1)
+	case "pgup", "b":
+		d.viewport.ViewUp()
+		d.keepLineInView()
+	case "pgdown":
+		d.viewport.ViewDown()
+		d.keepLineInView()
+	case "t":
+		d.hideFiles = !d.hideFiles
+		d.SetSize(d.width, d.height)
+	case "v":
+		d.ToggleSplit()
+	case "<", ",":
+		d.StepTurn(-1)
+	case ">", ".":
+		d.StepTurn(1)
+	case "c":
+		if _, line := d.CommentTarget(); line > 0 {
+			return DiffActionComment
+		}
+	case "x":
+		if d.lineCursor < len(d.lineComments) && d.lineComments[d.lineCursor] != "" && d.instance != nil {
+			if err := d.instance.DeleteComment(d.lineComments[d.lineCursor]); err == nil {
+				d.comments = d.instance.Comments()
+				d.showSelected(false)
 			}
+		}
+	case "r":
+		return DiffActionReview
+	}
+	return DiffActionNone
+}
+
+// CommentTarget returns the file and the line of it under the cursor, which a new comment goes on. The line is 0 if
+// the cursor isn't on a line of the new version of the file, ex. on a removed line, or if the diff of a past turn is
+// shown.
+func (d *DiffPane) CommentTarget() (string, int) {
+	if d.lineCursor >= len(d.lineNumbers) || d.turn > 0 {
+		return d.selected, 0
+	}
+	return d.selected, d.lineNumbers[d.lineCursor]
+}
+
+// moveLine moves the line cursor delta lines down the shown file's diff, scrolling to keep it in view.
+func (d *DiffPane) moveLine(delta int) {
+	d.lineCursor = max(min(d.lineCursor+delta, len(d.lines)-1), 0)
+	if d.lineCursor < d.viewport.YOffset {
+		d.viewport.SetYOffset(d.lineCursor)
+	} else if d.lineCursor >= d.viewport.YOffset+d.viewport.Height {
+		d.viewport.SetYOffset(d.lineCursor - d.viewport.Height + 1)
+	}
+	d.renderLines()
+}
+
+// keepLineInView moves the line cursor onto the lines in view after scrolling.
+func (d *DiffPane) keepLineInView() {
+	d.lineCursor = max(min(d.lineCursor, d.viewport.YOffset+d.viewport.Height-1), d.viewport.YOffset)
+	d.renderLines()
+}
+
+// moveCursor moves the cursor delta rows down the file list and shows the file under it.
+func (d *DiffPane) moveCursor(delta int) {
+	if len(d.rows) == 0 {
+		return
+	}
+	d.cursor = max(min(d.cursor+delta, len(d.rows)-1), 0)
+	if row := d.cursorRow(); !row.dir && row.path != d.selected {
+		d.selected = row.path
+		d.showSelected(true)
+	}
+}
+
+// collapse hides the files of the directory under the cursor. On a file or a collapsed directory, it moves the
+// cursor to the parent directory instead.
+func (d *DiffPane) collapse() {
+	row := d.cursorRow()
+	if row == nil {
+		return
+	}
+	if row.dir && !d.collapsed[row.path] {
+		d.collapsed[row.path] = true
+		d.rebuildRows()
+		return
+	}
+	for i := d.cursor - 1; i >= 0; i-- {
+		if d.rows[i].dir && d.rows[i].depth < row.depth {
+			d.cursor = i
+			return
+		}
+	}
+}
+
+// expand shows the files of the directory under the cursor.
+func (d *DiffPane) expand() {
+	if row := d.cursorRow(); row != nil && row.dir && d.collapsed[row.path] {
+		delete(d.collapsed, row.path)
+		d.rebuildRows()
+	}
+}
+
+// rebuildRows rebuilds the file list after a directory was collapsed or expanded, keeping the cursor on its row.
+func (d *DiffPane) rebuildRows() {
+	path := d.cursorRow().path
+	d.rows = buildDiffRows(d.files, d.collapsed)
+	for i, row := range d.rows {
+		if row.path == path {
+			d.cursor = i
+			return
+		}
+	}
+}
+
+// jumpHunk scrolls to the next hunk of the shown file if delta is positive, or the previous one if it's negative.
+func (d *DiffPane) jumpHunk(delta int) {
+	if delta > 0 {
+		for _, offset := range d.hunkOffsets {
+			if offset > d.viewport.YOffset {
+				d.scrollToHunk(offset)
+				return
+			}
+		}
+		return
+	}
+	for i := len(d.hunkOffsets) - 1; i >= 0; i-- {
+		if d.hunkOffsets[i] < d.viewport.YOffset {
+			d.scrollToHunk(d.hunkOffsets[i])
+			return
+		}
+	}
+}
+
+// scrollToHunk scrolls to the hunk starting at offset, and puts the line cursor on its first line.
+func (d *DiffPane) scrollToHunk(offset int) {
+	d.viewport.SetYOffset(offset)
+	d.lineCursor = min(offset+1, max(len(d.lines)-1, 0))
+	d.renderLines()
+}
+
+func (d *DiffPane) cursorRow() *diffRow {
+	if d.cursor >= len(d.rows) {
+		return nil
+	}
+	return &d.rows[d.cursor]
+}
+
+// selectedFile returns the file whose diff is shown, or nil if it's no longer in the diff.
+func (d *DiffPane) selectedFile() *git.FileDiff {
+	for i := range d.files {
+		if d.files[i].Path == d.selected {
+			return &d.files[i]
+		}
+	}
+	return nil
+}
+
+// selectFirstFile moves the cursor to the first visible file.
+func (d *DiffPane) selectFirstFile() {
+	d.selected = ""
+	for i, row := range d.rows {
+		if !row.dir {
+			d.cursor = i
+			d.selected = row.path
+			return
+		}
+	}
+	// Every directory is collapsed. Show the first file anyway.
+	if len(d.files) > 0 {
+		d.selected = d.files[0].Path
+	}
+}
+
+// showSelected renders the diff of the selected file, with its open comments, into the viewport. If top is true, it
+// scrolls to the top.
+func (d *DiffPane) showSelected(top bool) {
+	file := d.selectedFile()
+	d.lines, d.lineNumbers, d.lineComments, d.hunkOffsets = nil, nil, nil, nil
+	if file == nil {
+		d.viewport.SetContent("")
+		return
+	}
+	content, hunkOffsets, newLines := renderFileDiff(file, d.viewport.Width, d.split)
+
+	comments := make(map[int][]session.ReviewComment)
+	for _, c := range d.comments {
+		// Comments are on the current lines, so they're not shown on the diff of a past turn
+		if c.Path == file.Path && !c.Resolved && d.turn == 0 {
+			comments[c.EndLine] = append(comments[c.EndLine], c)
+		}
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if len(d.hunkOffsets) < len(hunkOffsets) && hunkOffsets[len(d.hunkOffsets)] == i {
+			d.hunkOffsets = append(d.hunkOffsets, len(d.lines))
+		}
+		d.lines = append(d.lines, line)
+		d.lineNumbers = append(d.lineNumbers, newLines[i])
+		d.lineComments = append(d.lineComments, "")
+		if newLines[i] == 0 {
+			continue
+		}
+		for _, c := range comments[newLines[i]] {
+			for _, commentLine := range renderComment(c, d.viewport.Width) {
+				d.lines = append(d.lines, commentLine)
+				d.lineNumbers = append(d.lineNumbers, 0)
+				d.lineComments = append(d.lineComments, c.ID)
+			}
+		}
+	}
+
+	if top {
+		d.viewport.GotoTop()
+		d.lineCursor = 0
+		if len(d.hunkOffsets) > 0 {
+			d.lineCursor = min(d.hunkOffsets[0]+1, len(d.lines)-1)
+		}
+	}
+	d.lineCursor = max(min(d.lineCursor, len(d.lines)-1), 0)
+	d.renderLines()
+}
+
+// renderLines puts the rendered lines in the viewport, highlighting the line under the cursor while the diff is
+// focused.
+func (d *DiffPane) renderLines() {
+	lines := d.lines
+	if d.focused && d.lineCursor < len(lines) {
+		lines = slices.Clone(lines)
+		lines[d.lineCursor] = fileCursorStyle.Render(ansi.Strip(lines[d.lineCursor]))
+	}
+	d.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// renderComment renders a comment to show under the lines it's on, cut to width.
+func renderComment(c session.ReviewComment, width int) []string {
+	style, label := reviewCommentStyle, "💬 "
+	if c.Sent {
+		style, label = sentCommentStyle, "💬 (sent) "
+	}
+	var lines []string
+	for i, line := range strings.Split(strings.TrimSpace(c.Body), "\n") {
+		prefix := "   "
+		if i == 0 {
+			prefix = label
+		}
+		lines = append(lines, style.Render(ansi.Truncate("  "+prefix+line, max(width, 0), "…")))
+	}
+	return lines
+}
+
+// filesText returns "in n files".
+func filesText(n int) string {
+	if n == 1 {
+		return "in 1 file"
+	}
+	return fmt.Sprintf("in %d files", n)
+}
+
+// turnSnapshots returns the indexes of the snapshots of history taken at the end of a turn.
+func turnSnapshots(history []session.DiffSnapshot) []int {
+	var turns []int
+	for i, snapshot := range history {
+		if snapshot.Turn {
+			turns = append(turns, i)
+		}
+	}
+	return turns
+}
+
+// sparkline draws the lines changed in the last snapshots of history as bars, one per snapshot, at most width wide.
+// The bar of the snapshot at index highlight is highlighted.
+func sparkline(history []session.DiffSnapshot, width, highlight int) string {
+	start := max(len(history)-width, 0)
+	largest := 0
+	for _, snapshot := range history[start:] {
+		largest = max(largest, snapshot.Added+snapshot.Removed)
+	}
+	var b strings.Builder
+	for i := start; i < len(history); i++ {
+		bar := 0
+		if largest > 0 {
+			bar = (history[i].Added + history[i].Removed) * (len(sparkBars) - 1) / largest
+		}
+		if i == highlight {
+			b.WriteString(fileSelectedStyle.Render(string(sparkBars[bar])))
+		} else {
+			b.WriteString(sparklineStyle.Render(string(sparkBars[bar])))
+		}
+	}
+	return b.String()
+}
+
+// fileListWidth returns the width of the file list, including the separator after it. It's zero if the list is hidden.
+func (d *DiffPane) fileListWidth() int {
+	if d.hideFiles || d.width < minFileListDiffWidth {
+		return 0
+	}
+	return min(d.width/3, maxFileListWidth)
+}
+
+// renderFileList renders the visible part of the file list, width wide.
+func (d *DiffPane) renderFileList(width int) string {
+	height := d.viewport.Height
+	// Scroll the list to keep the cursor in view
+	if d.cursor < d.listOffset {
+		d.listOffset = d.cursor
+	} else if height > 0 && d.cursor >= d.listOffset+height {
+		d.listOffset = d.cursor - height + 1
+	}
+	d.listOffset = max(min(d.listOffset, len(d.rows)-height), 0)
+
+	lines := make([]string, 0, height)
+	for i := d.listOffset; i < len(d.rows) && len(lines) < height; i++ {
+		row := d.rows[i]
+		counts := fmt.Sprintf("+%d -%d", row.added, row.removed)
+		if row.file != nil && row.file.Binary {
+			counts = "bin"
+		}
+
+		name := row.name
+		if row.dir {
+			marker := "▾ "
+			if d.collapsed[row.path] {
+				marker = "▸ "
+			}
+			name = marker + name + "/"
 		} else {
-			// Preserve empty lines
-			coloredOutput.WriteString("\n")
+			name = fileStatusMarker(row.file.Status) + " " + name
+		}
+		name = strings.Repeat("  ", row.depth) + name
+		nameWidth := max(width-len(counts)-1, 1)
+		name = ansi.Truncate(name, nameWidth, "…")
+		line := name + strings.Repeat(" ", max(width-ansi.StringWidth(name)-len(counts), 1)) + counts
+
+		switch {
+		case i == d.cursor && d.focused:
+			line = fileCursorStyle.Render(line)
+		case !row.dir && row.path == d.selected:
+			line = fileSelectedStyle.Render(line)
+		case row.dir:
+			line = dirStyle.Render(line)
 		}
+		lines = append(lines, line)
 	}
+	return lipgloss.NewStyle().Width(width).Height(height).Render(strings.Join(lines, "\n"))
+}
 
-	return coloredOutput.String()
+// fileStatusMarker returns a letter for a file status, like git status --short.
+func fileStatusMarker(status git.FileStatus) string {
+	switch status {
+	case git.FileAdded:
+		return AdditionStyle.Render("A")
+	case git.FileDeleted:
+		return DeletionStyle.Render("D")
+	case git.FileRenamed:
+		return HunkStyle.Render("R")
+	case git.FileBinary:
+		return "B"
+	default:
+		return "M"
+	}
 }
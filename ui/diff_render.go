@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"claude-squad/session/git"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	addedWordStyle   = AdditionStyle.Background(lipgloss.AdaptiveColor{Light: "#bbf7d0", Dark: "#14532d"})
+	removedWordStyle = DeletionStyle.Background(lipgloss.AdaptiveColor{Light: "#fecaca", Dark: "#7f1d1d"})
+	lineNumberStyle  = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+)
+
+const (
+	// minSplitDiffWidth is the narrowest the diff can be and still show the old and new lines side by side.
+	minSplitDiffWidth = 80
+	// diffTabWidth is the number of spaces a tab in a diff is shown as.
+	diffTabWidth = 4
+)
+
+// splitRow is a row of a side-by-side diff: the indexes of the old and new line in the hunk, or -1 where a side is
+// empty. Both are the same for a context line.
+type splitRow struct {
+	old, new int
+}
+
+// splitRows pairs up the lines of a hunk for a side-by-side diff. Lines removed are shown next to the lines added in
+// their place.
+func splitRows(lines []git.DiffLine) []splitRow {
+	var rows []splitRow
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == git.LineContext {
+			rows = append(rows, splitRow{i, i})
+			i++
+			continue
+		}
+		removedStart := i
+		for i < len(lines) && lines[i].Kind == git.LineRemoved {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Kind == git.LineAdded {
+			i++
+		}
+		removed, added := addedStart-removedStart, i-addedStart
+		for j := 0; j < max(removed, added); j++ {
+			row := splitRow{-1, -1}
+			if j < removed {
+				row.old = removedStart + j
+			}
+			if j < added {
+				row.new = addedStart + j
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// hunkWordChanges returns the changed words of each line of a hunk that replaces another, by line index.
+func hunkWordChanges(lines []git.DiffLine, rows []splitRow) map[int][]span {
+	changes := make(map[int][]span)
+	for _, row := range rows {
+		if row.old < 0 || row.new < 0 || row.old == row.new {
+			continue
+		}
+		oldChanged, newChanged := changedWords(expandTabs(lines[row.old].Content), expandTabs(lines[row.new].Content))
+		changes[row.old] = oldChanged
+		changes[row.new] = newChanged
+	}
+	return changes
+}
+
+// renderFileDiff renders the diff of a file width wide, side by side if split is true and there's room, and returns
+// the lines where its hunks start and, for each line, the line of the new file it shows, or 0 if it shows none.
+func renderFileDiff(file *git.FileDiff, width int, split bool) (string, []int, []int) {
+	var out strings.Builder
+	var hunkOffsets, newLines []int
+	lang := languageFor(file.Path)
+	split = split && width >= minSplitDiffWidth
+
+	// Head the file with its path and how it changed
+	header := file.Path
+	if file.Status == git.FileRenamed {
+		header = fmt.Sprintf("%s → %s", file.OldPath, file.Path)
+	}
+	out.WriteString(FileHeaderStyle.Render(fmt.Sprintf("%s (%s)", header, file.Status)) + "\n")
+	newLines = append(newLines, 0)
+	if file.Binary {
+		out.WriteString("Binary file\n")
+		newLines = append(newLines, 0)
+	}
+	if file.Truncated {
+		out.WriteString(fmt.Sprintf("Diff too large to show (+%d -%d)\n", file.Added, file.Removed))
+		newLines = append(newLines, 0)
+	}
+
+	// Number the lines in a side-by-side diff, as wide as the largest number.
+	numberWidth := 1
+	for _, hunk := range file.Hunks {
+		numberWidth = max(numberWidth, len(fmt.Sprint(hunk.OldStart+hunk.OldLines)),
+			len(fmt.Sprint(hunk.NewStart+hunk.NewLines)))
+	}
+	// Each side has the line number, a space and the +/- sign before the code, and they're split by a bar.
+	sideWidth := (width - 1) / 2
+	codeWidth := sideWidth - numberWidth - 2
+
+	for _, hunk := range file.Hunks {
+		hunkOffsets = append(hunkOffsets, len(newLines))
+		// Color hunk headers cyan
+		out.WriteString(HunkStyle.Render(hunk.Header) + "\n")
+		newLines = append(newLines, 0)
+
+		rows := splitRows(hunk.Lines)
+		changes := hunkWordChanges(hunk.Lines, rows)
+		if !split {
+			for i, line := range hunk.Lines {
+				out.WriteString(renderDiffLine(line, lang, changes[i], -1) + "\n")
+				if line.Kind == git.LineRemoved {
+					newLines = append(newLines, 0)
+				} else {
+					newLines = append(newLines, line.NewLine)
+				}
+			}
+			continue
+		}
+
+		for _, row := range rows {
+			left := strings.Repeat(" ", sideWidth)
+			if row.old >= 0 {
+				line := hunk.Lines[row.old]
+				left = lineNumberStyle.Render(fmt.Sprintf("%*d ", numberWidth, line.OldLine)) +
+					renderDiffLine(line, lang, changes[row.old], codeWidth)
+			}
+			right, newLine := "", 0
+			if row.new >= 0 {
+				line := hunk.Lines[row.new]
+				newLine = line.NewLine
+				right = lineNumberStyle.Render(fmt.Sprintf("%*d ", numberWidth, line.NewLine)) +
+					renderDiffLine(line, lang, changes[row.new], codeWidth)
+			}
+			out.WriteString(left + fileListSeparatorStyle.Render("│") + right + "\n")
+			newLines = append(newLines, newLine)
+		}
+	}
+
+	return out.String(), hunkOffsets, newLines
+}
+
+// renderDiffLine renders a line of a diff with its sign, its syntax colored and its changed words highlighted. If
+// width isn't negative, the code is cut or padded to it.
+func renderDiffLine(line git.DiffLine, lang *language, changed []span, width int) string {
+	sign, base, wordStyle := " ", lipgloss.NewStyle(), lipgloss.NewStyle()
+	switch line.Kind {
+	case git.LineAdded:
+		sign, base, wordStyle = "+", AdditionStyle, addedWordStyle
+	case git.LineRemoved:
+		sign, base, wordStyle = "-", DeletionStyle, removedWordStyle
+	}
+
+	code := expandTabs(line.Content)
+	cut := false
+	if width >= 0 && ansi.StringWidth(code) > width {
+		code = ansi.Truncate(code, max(width-1, 0), "")
+		cut = true
+	}
+
+	// Split the code wherever its coloring changes: at the edges of tokens and of changed words.
+	tokens := lang.tokenize(code)
+	bounds := []int{0, len(code)}
+	for _, s := range slices.Concat(tokens, changed) {
+		bounds = append(bounds, min(s.start, len(code)), min(s.end, len(code)))
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var out strings.Builder
+	out.WriteString(base.Render(sign))
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		style := base
+		if spanAt(changed, start) != nil {
+			style = wordStyle
+		}
+		if token := spanAt(tokens, start); token != nil {
+			style = tokenStyle(style, token.kind)
+		}
+		out.WriteString(style.Render(code[start:end]))
+	}
+	codeWidth := ansi.StringWidth(code)
+	if cut {
+		out.WriteString(base.Render("…"))
+		codeWidth++
+	}
+	if width >= 0 {
+		out.WriteString(strings.Repeat(" ", max(width-codeWidth, 0)))
+	}
+	return out.String()
+}
+
+// spanAt returns the span that includes pos, or nil if there's none.
+func spanAt(spans []span, pos int) *span {
+	for i := range spans {
+		if spans[i].start <= pos && pos < spans[i].end {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+// expandTabs replaces the tabs of a line with spaces, so its width is known.
+func expandTabs(line string) string {
+	return strings.ReplaceAll(line, "\t", strings.Repeat(" ", diffTabWidth))
+}
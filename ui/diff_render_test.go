@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"claude-squad/session/git"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitRows(t *testing.T) {
+	lines := []git.DiffLine{
+		{Kind: git.LineContext, Content: "a"},
+		{Kind: git.LineRemoved, Content: "b"},
+		{Kind: git.LineRemoved, Content: "c"},
+		{Kind: git.LineAdded, Content: "B"},
+		{Kind: git.LineContext, Content: "d"},
+		{Kind: git.LineAdded, Content: "e"},
+	}
+	// Removed lines are next to the lines added in their place, and the other side is empty where there's none.
+	assert.Equal(t, []splitRow{{0, 0}, {1, 3}, {2, -1}, {4, 4}, {-1, 5}}, splitRows(lines))
+}
+
+func TestChangedWords(t *testing.T) {
+	oldLine, newLine := "return foo(bar, 1)", "return foo(baz, 1) // done"
+	oldChanged, newChanged := changedWords(oldLine, newLine)
+	words := func(line string, spans []span) []string {
+		var words []string
+		for _, s := range spans {
+			words = append(words, line[s.start:s.end])
+		}
+		return words
+	}
+	assert.Equal(t, []string{"bar"}, words(oldLine, oldChanged))
+	assert.Equal(t, []string{"baz", " // done"}, words(newLine, newChanged))
+
+	// A line replaced by something else entirely isn't highlighted word by word.
+	oldChanged, newChanged = changedWords("foo", "bar")
+	assert.Empty(t, oldChanged)
+	assert.Empty(t, newChanged)
+}
+
+func TestRenderFileDiffSplit(t *testing.T) {
+	file := git.ParseDiff(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@
+ package main
+-var x = 1
++var x = 2 // a comment long enough to be cut at this width, so the line doesn't spill into the other side
+`)[0]
+
+	content, hunkOffsets, newLines := renderFileDiff(&file, 80, true)
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	assert.Equal(t, []int{1}, hunkOffsets)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, []int{0, 0, 1, 2}, newLines)
+	for _, line := range lines[2:] {
+		assert.Contains(t, line, "│")
+		assert.LessOrEqual(t, ansi.StringWidth(line), 80)
+	}
+	assert.Contains(t, lines[3], "…")
+
+	// Too narrow for two sides, it's shown unified.
+	content, _, newLines = renderFileDiff(&file, 60, true)
+	assert.NotContains(t, content, "│")
+	assert.Len(t, strings.Split(strings.TrimSuffix(content, "\n"), "\n"), 5)
+	assert.Equal(t, []int{0, 0, 1, 0, 2}, newLines)
+}
@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	history := []session.DiffSnapshot{
+		{Added: 100},
+		{Added: 0},
+		{Added: 2, Removed: 2},
+		{Added: 4, Removed: 4, Turn: true},
+		{Added: 4},
+	}
+	assert.Equal(t, "▁▄█▄", ansi.Strip(sparkline(history, 4, -1)))
+	assert.Equal(t, "█▁▁▁▁", ansi.Strip(sparkline(history, 10, 3)))
+	assert.Equal(t, []int{3}, turnSnapshots(history))
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"claude-squad/session/git"
+	"path"
+	"sort"
+	"strings"
+)
+
+// diffRow is a row of the diff pane's file list: a directory or a changed file.
+type diffRow struct {
+	// path is the path of the directory or file, relative to the repository root.
+	path string
+	// name is the last component of the path.
+	name  string
+	depth int
+	dir   bool
+	// file is the diff of the file. It's nil for directories.
+	file *git.FileDiff
+	// added and removed count the lines changed in the file, or in all the files under the directory.
+	added, removed int
+}
+
+// buildDiffRows lays out files as a tree, directories first, and returns its rows. The files under a collapsed
+// directory are left out.
+func buildDiffRows(files []git.FileDiff, collapsed map[string]bool) []diffRow {
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return treeLess(files[order[a]].Path, files[order[b]].Path)
+	})
+
+	// Count the changes under each directory.
+	dirAdded := make(map[string]int)
+	dirRemoved := make(map[string]int)
+	for _, file := range files {
+		for _, dir := range parentDirs(file.Path) {
+			dirAdded[dir] += file.Added
+			dirRemoved[dir] += file.Removed
+		}
+	}
+
+	var rows []diffRow
+	added := make(map[string]bool)
+	for _, i := range order {
+		file := &files[i]
+		dirs := parentDirs(file.Path)
+		hidden := false
+		for depth, dir := range dirs {
+			if hidden {
+				break
+			}
+			if !added[dir] {
+				added[dir] = true
+				rows = append(rows, diffRow{
+					path: dir, name: path.Base(dir), depth: depth, dir: true,
+					added: dirAdded[dir], removed: dirRemoved[dir],
+				})
+			}
+			hidden = collapsed[dir]
+		}
+		if hidden {
+			continue
+		}
+		rows = append(rows, diffRow{
+			path: file.Path, name: path.Base(file.Path), depth: len(dirs), file: file,
+			added: file.Added, removed: file.Removed,
+		})
+	}
+	return rows
+}
+
+// parentDirs returns the directories a path is in, outermost first. For "a/b/c.go", that's "a" and "a/b".
+func parentDirs(p string) []string {
+	var dirs []string
+	for i, c := range p {
+		if c == '/' {
+			dirs = append(dirs, p[:i])
+		}
+	}
+	return dirs
+}
+
+// treeLess orders paths like a file tree: by component, with the directories of a directory before its files.
+func treeLess(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		aDir, bDir := i < len(as)-1, i < len(bs)-1
+		if aDir != bDir {
+			return aDir
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"claude-squad/session/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDiffRows(t *testing.T) {
+	files := []git.FileDiff{
+		{Path: "README.md", Added: 1},
+		{Path: "ui/list.go", Added: 2, Removed: 1},
+		{Path: "ui/overlay/menu.go", Removed: 3},
+		{Path: "main.go", Added: 4},
+		{Path: "ui/diff.go", Added: 5},
+	}
+	type row struct {
+		path           string
+		depth          int
+		dir            bool
+		added, removed int
+	}
+	summarize := func(rows []diffRow) []row {
+		var summary []row
+		for _, r := range rows {
+			summary = append(summary, row{r.path, r.depth, r.dir, r.added, r.removed})
+		}
+		return summary
+	}
+
+	// Directories come before the files next to them, and count the changes under them.
+	assert.Equal(t, []row{
+		{"ui", 0, true, 7, 4},
+		{"ui/overlay", 1, true, 0, 3},
+		{"ui/overlay/menu.go", 2, false, 0, 3},
+		{"ui/diff.go", 1, false, 5, 0},
+		{"ui/list.go", 1, false, 2, 1},
+		{"README.md", 0, false, 1, 0},
+		{"main.go", 0, false, 4, 0},
+	}, summarize(buildDiffRows(files, nil)))
+
+	assert.Equal(t, []row{
+		{"ui", 0, true, 7, 4},
+		{"README.md", 0, false, 1, 0},
+		{"main.go", 0, false, 4, 0},
+	}, summarize(buildDiffRows(files, map[string]bool{"ui": true})))
+
+	assert.Equal(t, []row{
+		{"ui", 0, true, 7, 4},
+		{"ui/overlay", 1, true, 0, 3},
+		{"ui/diff.go", 1, false, 5, 0},
+		{"ui/list.go", 1, false, 2, 1},
+		{"README.md", 0, false, 1, 0},
+		{"main.go", 0, false, 4, 0},
+	}, summarize(buildDiffRows(files, map[string]bool{"ui/overlay": true})))
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxWordDiffCells caps the size of the table used to compare two lines word by word. Lines too long to compare have
+// nothing highlighted.
+const maxWordDiffCells = 250_000
+
+// splitWords splits a line into words, runs of spaces and single punctuation characters, so a changed identifier is
+// highlighted without its neighbours.
+func splitWords(line string) []string {
+	var words []string
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		end := i + size
+		switch {
+		case isWordRune(r):
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += size
+			}
+		case unicode.IsSpace(r):
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				end += size
+			}
+		}
+		words = append(words, line[i:end])
+		i = end
+	}
+	return words
+}
+
+// changedWords compares a removed line with the line that replaced it, and returns the byte ranges of the words that
+// differ in each of them. Lines with nothing in common have nothing highlighted, as the whole line changed.
+func changedWords(oldLine, newLine string) (oldChanged, newChanged []span) {
+	oldWords, newWords := splitWords(oldLine), splitWords(newLine)
+	if (len(oldWords)+1)*(len(newWords)+1) > maxWordDiffCells {
+		return nil, nil
+	}
+
+	// common[i][j] is the length of the longest common subsequence of oldWords[i:] and newWords[j:].
+	common := make([][]int, len(oldWords)+1)
+	for i := range common {
+		common[i] = make([]int, len(newWords)+1)
+	}
+	for i := len(oldWords) - 1; i >= 0; i-- {
+		for j := len(newWords) - 1; j >= 0; j-- {
+			if oldWords[i] == newWords[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	if common[0][0] == 0 {
+		return nil, nil
+	}
+
+	i, j, oldPos, newPos := 0, 0, 0, 0
+	for i < len(oldWords) || j < len(newWords) {
+		switch {
+		case i < len(oldWords) && j < len(newWords) && oldWords[i] == newWords[j]:
+			oldPos += len(oldWords[i])
+			newPos += len(newWords[j])
+			i++
+			j++
+		case j == len(newWords) || (i < len(oldWords) && common[i+1][j] >= common[i][j+1]):
+			oldChanged = appendSpan(oldChanged, oldPos, oldPos+len(oldWords[i]))
+			oldPos += len(oldWords[i])
+			i++
+		default:
+			newChanged = appendSpan(newChanged, newPos, newPos+len(newWords[j]))
+			newPos += len(newWords[j])
+			j++
+		}
+	}
+	return oldChanged, newChanged
+}
+
+// appendSpan adds a range to spans, merging it with the last one if they touch.
+func appendSpan(spans []span, start, end int) []span {
+	if n := len(spans); n > 0 && spans[n-1].end == start {
+		spans[n-1].end = end
+		return spans
+	}
+	return append(spans, span{start, end, tokenPlain})
+}
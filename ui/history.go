@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	historyStatusStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"}).
+				Background(lipgloss.AdaptiveColor{Light: "#DDDADA", Dark: "#3C3C3C"})
+	historyMatchStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#1a1a1a")).
+				Background(lipgloss.Color("#FFD700"))
+)
+
+// HistoryView is a scrollable, searchable view of a session's scrollback. It is shown in the preview tab while
+// browsing history.
+type HistoryView struct {
+	viewport viewport.Model
+	width    int
+	height   int
+
+	// lines are the scrollback lines with ANSI escape sequences. plain are the same lines without them, used for
+	// searching and for highlighting matches.
+	lines []string
+	plain []string
+
+	// searching is true while a search query is being typed into input. query is the last submitted query.
+	searching bool
+	input     string
+	query     string
+	// matches are the indexes of the lines matching query. current is the index into matches of the selected match,
+	// or -1 if there is none.
+	matches []int
+	current int
+}
+
+// NewHistoryView creates a history view of content, scrolled to the bottom.
+func NewHistoryView(content string, width, height int) *HistoryView {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	plain := make([]string, len(lines))
+	for i, line := range lines {
+		plain[i] = ansi.Strip(line)
+	}
+	h := &HistoryView{
+		viewport: viewport.New(0, 0),
+		lines:    lines,
+		plain:    plain,
+		current:  -1,
+	}
+	h.SetSize(width, height)
+	h.viewport.GotoBottom()
+	return h
+}
+
+// SetSize sets the size of the view, including the status line.
+func (h *HistoryView) SetSize(width, height int) {
+	h.width = width
+	h.height = height
+	h.viewport.Width = width
+	h.viewport.Height = max(height-1, 1)
+	h.render()
+}
+
+// render sets the viewport content, highlighting the selected match.
+func (h *HistoryView) render() {
+	lines := h.lines
+	if h.current >= 0 {
+		lines = append([]string(nil), h.lines...)
+		idx := h.matches[h.current]
+		lines[idx] = historyMatchStyle.Render(h.plain[idx])
+	}
+	h.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// HandleKeyPress handles a key press. It returns true if the view should be closed.
+func (h *HistoryView) HandleKeyPress(msg tea.KeyMsg) (shouldClose bool) {
+	if h.searching {
+		switch msg.Type {
+		case tea.KeyEnter:
+			h.searching = false
+			h.query = h.input
+			h.search()
+		case tea.KeyEsc, tea.KeyCtrlC:
+			h.searching = false
+		case tea.KeyBackspace:
+			if len(h.input) > 0 {
+				runes := []rune(h.input)
+				h.input = string(runes[:len(runes)-1])
+			}
+		case tea.KeySpace:
+			h.input += " "
+		case tea.KeyRunes:
+			h.input += string(msg.Runes)
+		}
+		return false
+	}
+
+	switch msg.String() {
+	case "esc", "q", "h", "ctrl+c":
+		return true
+	case "up", "k":
+		h.viewport.LineUp(1)
+	case "down", "j":
+		h.viewport.LineDown(1)
+	case "pgup", "b", "shift+up":
+		h.viewport.ViewUp()
+	case "pgdown", "f", " ", "shift+down":
+		h.viewport.ViewDown()
+	case "home", "g":
+		h.viewport.GotoTop()
+	case "end", "G":
+		h.viewport.GotoBottom()
+	case "/":
+		h.searching = true
+		h.input = ""
+	case "n":
+		h.step(1)
+	case "N":
+		h.step(-1)
+	}
+	return false
+}
+
+// ScrollUp scrolls up by a few lines. Used for the mouse wheel.
+func (h *HistoryView) ScrollUp() {
+	h.viewport.LineUp(3)
+}
+
+// ScrollDown scrolls down by a few lines. Used for the mouse wheel.
+func (h *HistoryView) ScrollDown() {
+	h.viewport.LineDown(3)
+}
+
+// search finds the lines matching the query, case-insensitively, and selects the last match at or above the
+// bottom of the view.
+func (h *HistoryView) search() {
+	h.matches = nil
+	h.current = -1
+	if h.query != "" {
+		query := strings.ToLower(h.query)
+		for i, line := range h.plain {
+			if strings.Contains(strings.ToLower(line), query) {
+				h.matches = append(h.matches, i)
+			}
+		}
+	}
+	if len(h.matches) == 0 {
+		h.render()
+		return
+	}
+
+	bottom := h.viewport.YOffset + h.viewport.Height - 1
+	h.current = 0
+	for i, idx := range h.matches {
+		if idx <= bottom {
+			h.current = i
+		}
+	}
+	h.showCurrent()
+}
+
+// step moves the selection delta matches forward, wrapping around.
+func (h *HistoryView) step(delta int) {
+	if len(h.matches) == 0 {
+		return
+	}
+	h.current = (h.current + delta + len(h.matches)) % len(h.matches)
+	h.showCurrent()
+}
+
+// showCurrent highlights the selected match and scrolls it to the middle of the view.
+func (h *HistoryView) showCurrent() {
+	h.render()
+	h.viewport.SetYOffset(h.matches[h.current] - h.viewport.Height/2)
+}
+
+// String renders the view with a status line at the bottom.
+func (h *HistoryView) String() string {
+	var status string
+	switch {
+	case h.searching:
+		status = "/" + h.input + "█"
+	default:
+		first := h.viewport.YOffset + 1
+		last := min(h.viewport.YOffset+h.viewport.Height, len(h.lines))
+		status = fmt.Sprintf("history: lines %d-%d of %d", first, last, len(h.lines))
+		if h.query != "" {
+			if len(h.matches) == 0 {
+				status += fmt.Sprintf(" │ /%s: no matches", h.query)
+			} else {
+				status += fmt.Sprintf(" │ /%s: %d of %d", h.query, h.current+1, len(h.matches))
+			}
+		}
+		status += " │ ↑/↓ scroll • / search • n/N next/prev • esc exit"
+	}
+	status = ansi.Truncate(status, h.width, "…")
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		h.viewport.View(),
+		historyStatusStyle.Width(h.width).Render(status),
+	)
+}
@@ -13,6 +13,9 @@ import (
 
 const readyIcon = "● "
 const pausedIcon = "⏸ "
+const crashedIcon = "✗ "
+const groupIcon = "⑂"
+const conflictIcon = "⚠"
 
 var readyStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
@@ -26,6 +29,15 @@ var removedLinesStyle = lipgloss.NewStyle().
 var pausedStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#888888", Dark: "#888888"})
 
+var crashedStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#de613e"))
+
+var groupStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7D56F4"))
+
+var conflictStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFA500"))
+
 var titleStyle = lipgloss.NewStyle().
 	Padding(1, 1, 0, 1).
 	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
@@ -62,6 +74,8 @@ type List struct {
 	// map of repo name to number of instances using it. Used to display the repo name only if there are
 	// multiple repos in play.
 	repos map[string]int
+	// conflicts are the instances each instance changed the same files as, from session.FindConflicts.
+	conflicts map[*session.Instance][]session.Conflict
 }
 
 func NewList(spinner *spinner.Model, autoYes bool) *List {
@@ -73,6 +87,21 @@ func NewList(spinner *spinner.Model, autoYes bool) *List {
 	}
 }
 
+// SetAutoYes sets whether the list shows the auto-yes badge.
+func (l *List) SetAutoYes(autoYes bool) {
+	l.autoyes = autoYes
+}
+
+// SetConflicts sets the conflicts between instances, which are flagged with a warning badge.
+func (l *List) SetConflicts(conflicts map[*session.Instance][]session.Conflict) {
+	l.conflicts = conflicts
+}
+
+// GetConflicts returns what the instance changed that other instances changed too.
+func (l *List) GetConflicts(instance *session.Instance) []session.Conflict {
+	return l.conflicts[instance]
+}
+
 // SetSize sets the height and width of the list.
 func (l *List) SetSize(width, height int) {
 	l.width = width
@@ -84,7 +113,7 @@ func (l *List) SetSize(width, height int) {
 // width and height.
 func (l *List) SetSessionPreviewSize(width, height int) (err error) {
 	for i, item := range l.items {
-		if !item.Started() || item.Paused() {
+		if !item.Started() || item.Paused() || item.Crashed() {
 			continue
 		}
 
@@ -113,7 +142,8 @@ func (r *InstanceRenderer) setWidth(width int) {
 // ɹ and ɻ are other options.
 const branchIcon = "Ꮧ"
 
-func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool) string {
+func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool,
+	conflicts []session.Conflict) string {
 	prefix := fmt.Sprintf(" %d. ", idx)
 	if idx >= 10 {
 		prefix = prefix[:len(prefix)-1]
@@ -134,19 +164,35 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 		join = readyStyle.Render(readyIcon)
 	case session.Paused:
 		join = pausedStyle.Render(pausedIcon)
+	case session.Crashed:
+		join = crashedStyle.Render(crashedIcon)
 	default:
 	}
 
+	// Flag the instance if other instances changed the same files, with how many did.
+	var badge string
+	if len(conflicts) > 0 {
+		badge = conflictStyle.Background(titleS.GetBackground()).
+			Render(fmt.Sprintf("%s%d", conflictIcon, len(conflicts))) +
+			lipgloss.Style{}.Background(titleS.GetBackground()).Render(" ")
+	}
+	badgeWidth := lipgloss.Width(badge)
+
 	// Cut the title if it's too long
 	titleText := i.Title
-	widthAvail := r.width - 3 - len(prefix) - 1
+	if i.Paused() && i.PauseReason != "" {
+		titleText += " (auto-paused)"
+	}
+	widthAvail := r.width - 3 - badgeWidth - len(prefix) - 1
 	if widthAvail > 0 && widthAvail < len(titleText) && len(titleText) >= widthAvail-3 {
 		titleText = titleText[:widthAvail-3] + "..."
 	}
 	title := titleS.Render(lipgloss.JoinHorizontal(
 		lipgloss.Left,
-		lipgloss.Place(r.width-3, 1, lipgloss.Left, lipgloss.Center, fmt.Sprintf("%s %s", prefix, titleText)),
+		lipgloss.Place(r.width-3-badgeWidth, 1, lipgloss.Left, lipgloss.Center,
+			fmt.Sprintf("%s %s", prefix, titleText)),
 		" ",
+		badge,
 		join,
 	))
 
@@ -251,7 +297,12 @@ func (l *List) String() string {
 
 	// Render the list.
 	for i, item := range l.items {
-		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1))
+		// Head the sessions of a fan-out, which sit next to each other, with the name of their group.
+		if item.Group != "" && (i == 0 || l.items[i-1].Group != item.Group) {
+			b.WriteString(groupStyle.Render(fmt.Sprintf(" %s %s (%d)", groupIcon, item.Group, len(l.GetGroup(item.Group)))))
+			b.WriteString("\n")
+		}
+		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1, l.conflicts[item]))
 		if i != len(l.items)-1 {
 			b.WriteString("\n\n")
 		}
@@ -274,18 +325,27 @@ func (l *List) Kill() {
 	if len(l.items) == 0 {
 		return
 	}
-	targetInstance := l.items[l.selectedIdx]
+	l.kill(l.selectedIdx)
+}
+
+// KillInstance kills instance and removes it from the list. Noop if it isn't in the list.
+func (l *List) KillInstance(instance *session.Instance) {
+	for idx, item := range l.items {
+		if item == instance {
+			l.kill(idx)
+			return
+		}
+	}
+}
+
+func (l *List) kill(idx int) {
+	targetInstance := l.items[idx]
 
 	// Kill the tmux session
 	if err := targetInstance.Kill(); err != nil {
 		log.ErrorLog.Printf("could not kill instance: %v", err)
 	}
 
-	// If you delete the last one in the list, select the previous one.
-	if l.selectedIdx == len(l.items)-1 {
-		defer l.Up()
-	}
-
 	// Unregister the reponame.
 	repoName, err := targetInstance.RepoName()
 	if err != nil {
@@ -294,8 +354,12 @@ func (l *List) Kill() {
 		l.rmRepo(repoName)
 	}
 
-	// Since there's items after this, the selectedIdx can stay the same.
-	l.items = append(l.items[:l.selectedIdx], l.items[l.selectedIdx+1:]...)
+	l.items = append(l.items[:idx], l.items[idx+1:]...)
+	// Keep the same instance selected. If the selected one was deleted, select the one after it, or the previous one
+	// if it was the last.
+	if l.selectedIdx > idx || l.selectedIdx >= len(l.items) {
+		l.selectedIdx = max(l.selectedIdx-1, 0)
+	}
 }
 
 func (l *List) Attach() (chan struct{}, error) {
@@ -348,6 +412,17 @@ func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
 	}
 }
 
+// GetGroup returns the instances of a fan-out group in list order.
+func (l *List) GetGroup(group string) []*session.Instance {
+	var instances []*session.Instance
+	for _, item := range l.items {
+		if item.Group == group {
+			instances = append(instances, item)
+		}
+	}
+	return instances
+}
+
 // GetSelectedInstance returns the currently selected instance
 func (l *List) GetSelectedInstance() *session.Instance {
 	if len(l.items) == 0 {
@@ -126,7 +126,7 @@ func (m *Menu) addInstanceOptions() {
 
 	// Action group
 	actionGroup := []keys.KeyName{keys.KeyEnter, keys.KeySubmit}
-	if m.instance.Status == session.Paused {
+	if m.instance.Status == session.Paused || m.instance.Status == session.Crashed {
 		actionGroup = append(actionGroup, keys.KeyResume)
 	} else {
 		actionGroup = append(actionGroup, keys.KeyCheckout)
@@ -134,7 +134,13 @@ func (m *Menu) addInstanceOptions() {
 
 	// Navigation group (when in diff tab)
 	if m.isInDiffTab {
-		actionGroup = append(actionGroup, keys.KeyShiftUp)
+		actionGroup = append(actionGroup, keys.KeyShiftUp, keys.KeyFiles, keys.KeySplit)
+	} else if m.instance.Status != session.Paused && m.instance.Status != session.Crashed {
+		actionGroup = append(actionGroup, keys.KeyHistory)
+	}
+	actionGroup = append(actionGroup, keys.KeyQueue)
+	if m.instance.Group != "" {
+		actionGroup = append(actionGroup, keys.KeyCompare)
 	}
 
 	// System group
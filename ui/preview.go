@@ -16,6 +16,8 @@ type PreviewPane struct {
 	height int
 
 	previewState previewState
+	// history is the scrollback browser. It's non-nil while browsing history, which freezes the live preview.
+	history *HistoryView
 }
 
 type previewState struct {
@@ -32,6 +34,24 @@ func NewPreviewPane() *PreviewPane {
 func (p *PreviewPane) SetSize(width, maxHeight int) {
 	p.width = width
 	p.height = maxHeight
+	if p.history != nil {
+		p.history.SetSize(width, maxHeight)
+	}
+}
+
+// EnterHistory replaces the live preview with a history view of the scrollback content.
+func (p *PreviewPane) EnterHistory(content string) {
+	p.history = NewHistoryView(content, p.width, p.height)
+}
+
+// ExitHistory returns to the live preview.
+func (p *PreviewPane) ExitHistory() {
+	p.history = nil
+}
+
+// InHistory returns true if the history view is shown.
+func (p *PreviewPane) InHistory() bool {
+	return p.history != nil
 }
 
 // setFallbackState sets the preview state with fallback text and a message
@@ -44,23 +64,41 @@ func (p *PreviewPane) setFallbackState(message string) {
 
 // Updates the preview pane content with the tmux pane content
 func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
+	if p.history != nil {
+		return nil
+	}
+
 	switch {
 	case instance == nil:
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
 		return nil
 	case instance.Status == session.Paused:
+		message := "Session is paused. Press 'r' to resume."
+		checkout := fmt.Sprintf("The instance can be checked out at '%s' (copied to your clipboard)", instance.Branch)
+		if instance.PauseReason != "" {
+			message = "Session was paused automatically after sitting idle. Press 'r' to resume."
+			if instance.PauseReason == session.PauseReasonMaxRunning {
+				message = "Session was paused automatically to stay under max_running_sessions. Press 'r' to resume."
+			}
+			// Automatic pauses leave the clipboard alone.
+			checkout = fmt.Sprintf("The instance can be checked out at '%s'", instance.Branch)
+		}
 		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
-			"Session is paused. Press 'r' to resume.",
+			message,
 			"",
 			lipgloss.NewStyle().
 				Foreground(lipgloss.AdaptiveColor{
 					Light: "#FFD700",
 					Dark:  "#FFD700",
 				}).
-				Render(fmt.Sprintf(
-					"The instance can be checked out at '%s' (copied to your clipboard)",
-					instance.Branch,
-				)),
+				Render(checkout),
+		))
+		return nil
+	case instance.Status == session.Crashed:
+		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
+			"Session crashed. Press 'r' to restart it in its worktree.",
+			"",
+			"Its worktree and branch were kept.",
 		))
 		return nil
 	}
@@ -88,6 +126,10 @@ func (p *PreviewPane) String() string {
 		return strings.Repeat("\n", p.height)
 	}
 
+	if p.history != nil {
+		return p.history.String()
+	}
+
 	if p.previewState.fallback {
 		// Calculate available height for fallback text
 		availableHeight := p.height - 3 - 4 // 2 for borders, 1 for margin, 1 for padding
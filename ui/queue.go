@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	queuePanelStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(1, 2)
+	queueTitleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+	queueSelectedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"}).
+				Background(lipgloss.AdaptiveColor{Light: "#dde4f0", Dark: "#dde4f0"})
+)
+
+// QueueAction is what the app should do after a key press in the queue panel.
+type QueueAction int
+
+const (
+	// QueueActionNone means the panel handled the key itself.
+	QueueActionNone QueueAction = iota
+	// QueueActionAdd means the user wants to type a prompt to add to the queue.
+	QueueActionAdd
+	// QueueActionClose means the panel should be closed.
+	QueueActionClose
+)
+
+// QueuePanel shows the prompts queued for an instance and lets the user reorder and cancel them. The instance sends
+// them one at a time as it becomes ready, so the queue can shrink while the panel is open.
+type QueuePanel struct {
+	instance *session.Instance
+	selected int
+	width    int
+}
+
+// NewQueuePanel creates a queue panel for instance.
+func NewQueuePanel(instance *session.Instance) *QueuePanel {
+	return &QueuePanel{instance: instance}
+}
+
+// SetWidth sets the width of the panel, including its border.
+func (q *QueuePanel) SetWidth(width int) {
+	q.width = width
+}
+
+// HandleKeyPress handles a key press in the panel and returns what the app should do next.
+func (q *QueuePanel) HandleKeyPress(msg tea.KeyMsg) (QueueAction, error) {
+	prompts := q.instance.QueuedPrompts()
+	q.clampSelection(len(prompts))
+
+	switch msg.String() {
+	case "up", "k":
+		q.selected = max(q.selected-1, 0)
+	case "down", "j":
+		q.selected = min(q.selected+1, max(len(prompts)-1, 0))
+	case "shift+up", "K":
+		if q.selected > 0 {
+			if err := q.instance.MoveQueued(q.selected, q.selected-1, prompts[q.selected]); err != nil {
+				return QueueActionNone, err
+			}
+			q.selected--
+		}
+	case "shift+down", "J":
+		if q.selected < len(prompts)-1 {
+			if err := q.instance.MoveQueued(q.selected, q.selected+1, prompts[q.selected]); err != nil {
+				return QueueActionNone, err
+			}
+			q.selected++
+		}
+	case "d", "x", "delete":
+		if len(prompts) > 0 {
+			if err := q.instance.CancelQueued(q.selected, prompts[q.selected]); err != nil {
+				return QueueActionNone, err
+			}
+			q.clampSelection(len(prompts) - 1)
+		}
+	case "a", "n":
+		return QueueActionAdd, nil
+	case "esc", "q":
+		return QueueActionClose, nil
+	}
+	return QueueActionNone, nil
+}
+
+// Render renders the panel.
+func (q *QueuePanel) Render() string {
+	prompts := q.instance.QueuedPrompts()
+	q.clampSelection(len(prompts))
+
+	// Leave room for the border, padding and the number in front of each prompt.
+	textWidth := max(q.width-6-5, 10)
+	lines := []string{queueTitleStyle.Render(fmt.Sprintf("Prompt queue for %s", q.instance.Title)), ""}
+	if len(prompts) == 0 {
+		lines = append(lines, descStyle.Render("Nothing queued. Press 'a' to add a prompt."))
+	}
+	for i, prompt := range prompts {
+		// Show multi-line prompts on one line.
+		text := ansi.Truncate(strings.Join(strings.Fields(prompt), " "), textWidth, "...")
+		line := fmt.Sprintf("%2d. %s", i+1, text)
+		if i == q.selected {
+			line = queueSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "",
+		descStyle.Render("Prompts are sent one at a time, each time the session is ready for input."),
+		keyStyle.Render("a")+descStyle.Render(" add • ")+
+			keyStyle.Render("d")+descStyle.Render(" cancel • ")+
+			keyStyle.Render("shift+↑/↓")+descStyle.Render(" move • ")+
+			keyStyle.Render("esc")+descStyle.Render(" close"),
+	)
+
+	style := queuePanelStyle
+	if q.width > 0 {
+		style = style.Width(q.width)
+	}
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// clampSelection keeps the selection within a queue of n prompts.
+func (q *QueuePanel) clampSelection(n int) {
+	q.selected = max(min(q.selected, n-1), 0)
+}
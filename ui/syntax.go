@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	keywordStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#8839ef", Dark: "#c678dd"})
+	stringStyle  = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#40a02b", Dark: "#98c379"})
+	commentStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#8c8fa1", Dark: "#7f848e"}).Italic(true)
+	numberStyle  = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#fe640b", Dark: "#d19a66"})
+)
+
+// tokenKind is how a piece of source code is colored.
+type tokenKind int
+
+const (
+	tokenPlain tokenKind = iota
+	tokenKeyword
+	tokenString
+	tokenComment
+	tokenNumber
+)
+
+// span is a byte range of a line, ex. a token or a changed word.
+type span struct {
+	start, end int
+	kind       tokenKind
+}
+
+// language describes just enough of a programming language to color its lines one at a time. Comments and strings
+// that span lines aren't recognized past their first line.
+type language struct {
+	keywords map[string]bool
+	// comments are the markers that start a comment running to the end of the line.
+	comments []string
+	// blockComment is the marker that starts a comment closed by blockCommentEnd on the same line.
+	blockComment, blockCommentEnd string
+	// quotes are the characters that start and end a string.
+	quotes string
+}
+
+func newLanguage(keywords string, comments []string, blockComment, blockCommentEnd, quotes string) *language {
+	l := &language{
+		keywords:        make(map[string]bool),
+		comments:        comments,
+		blockComment:    blockComment,
+		blockCommentEnd: blockCommentEnd,
+		quotes:          quotes,
+	}
+	for _, keyword := range strings.Fields(keywords) {
+		l.keywords[keyword] = true
+	}
+	return l
+}
+
+var (
+	goLanguage = newLanguage(`break case chan const continue default defer else fallthrough for func go goto if
+		import interface map package range return select struct switch type var nil true false iota`,
+		[]string{"//"}, "/*", "*/", "\"'`")
+	pythonLanguage = newLanguage(`and as assert async await break class continue def del elif else except finally
+		for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False
+		self`, []string{"#"}, "", "", "\"'")
+	jsLanguage = newLanguage(`async await break case catch class const continue debugger default delete do else
+		export extends finally for from function if import in instanceof interface let new null of return static super
+		switch this throw try type typeof undefined var void while yield true false`,
+		[]string{"//"}, "/*", "*/", "\"'`")
+	rustLanguage = newLanguage(`as async await break const continue crate else enum extern false fn for if impl in
+		let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where
+		while`, []string{"//"}, "/*", "*/", "\"")
+	cLanguage = newLanguage(`auto break case catch char class const continue default delete do double else enum
+		extern final float for friend if inline int long namespace new nullptr private protected public return short
+		signed sizeof static struct switch template this throw try typedef typename union unsigned using virtual void
+		volatile while true false boolean byte extends implements import instanceof package super synchronized`,
+		[]string{"//"}, "/*", "*/", "\"'")
+	shellLanguage = newLanguage(`if then else elif fi case esac for while until do done in function return local
+		export exit`, []string{"#"}, "", "", "\"'")
+	rubyLanguage = newLanguage(`alias and begin break case class def do else elsif end ensure false for if in
+		module next nil not or redo rescue retry return self super then true undef unless until when while yield`,
+		[]string{"#"}, "", "", "\"'")
+	yamlLanguage = newLanguage(`true false null yes no`, []string{"#"}, "", "", "\"'")
+	jsonLanguage = newLanguage(`true false null`, nil, "", "", "\"")
+)
+
+// languages maps file extensions to their language.
+var languages = map[string]*language{
+	".go":   goLanguage,
+	".py":   pythonLanguage,
+	".js":   jsLanguage,
+	".jsx":  jsLanguage,
+	".mjs":  jsLanguage,
+	".ts":   jsLanguage,
+	".tsx":  jsLanguage,
+	".rs":   rustLanguage,
+	".c":    cLanguage,
+	".h":    cLanguage,
+	".cc":   cLanguage,
+	".cpp":  cLanguage,
+	".hpp":  cLanguage,
+	".java": cLanguage,
+	".kt":   cLanguage,
+	".cs":   cLanguage,
+	".sh":   shellLanguage,
+	".bash": shellLanguage,
+	".zsh":  shellLanguage,
+	".rb":   rubyLanguage,
+	".yml":  yamlLanguage,
+	".yaml": yamlLanguage,
+	".toml": yamlLanguage,
+	".json": jsonLanguage,
+}
+
+// languageFor returns the language of a file from its extension, or nil if it's not one we color.
+func languageFor(path string) *language {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+// tokenize splits a line into the spans to color. Plain text isn't included.
+func (l *language) tokenize(line string) []span {
+	if l == nil {
+		return nil
+	}
+	var spans []span
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		if l.blockComment != "" && strings.HasPrefix(rest, l.blockComment) {
+			end := len(line)
+			if j := strings.Index(rest[len(l.blockComment):], l.blockCommentEnd); j >= 0 {
+				end = i + len(l.blockComment) + j + len(l.blockCommentEnd)
+			}
+			spans = append(spans, span{i, end, tokenComment})
+			i = end
+			continue
+		}
+		if l.isComment(rest) {
+			spans = append(spans, span{i, len(line), tokenComment})
+			break
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case strings.ContainsRune(l.quotes, r):
+			end := i + size
+			for end < len(line) && line[end] != byte(r) {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			spans = append(spans, span{i, end, tokenString})
+			i = end
+		case isWordRune(r):
+			end := i + size
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += size
+			}
+			word := line[i:end]
+			if l.keywords[word] {
+				spans = append(spans, span{i, end, tokenKeyword})
+			} else if unicode.IsDigit(r) {
+				spans = append(spans, span{i, end, tokenNumber})
+			}
+			i = end
+		default:
+			i += size
+		}
+	}
+	return spans
+}
+
+func (l *language) isComment(s string) bool {
+	for _, comment := range l.comments {
+		if strings.HasPrefix(s, comment) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenStyle returns the style of a token: its color, over the background of base.
+func tokenStyle(base lipgloss.Style, kind tokenKind) lipgloss.Style {
+	switch kind {
+	case tokenKeyword:
+		return keywordStyle.Inherit(base)
+	case tokenString:
+		return stringStyle.Inherit(base)
+	case tokenComment:
+		return commentStyle.Inherit(base)
+	case tokenNumber:
+		return numberStyle.Inherit(base)
+	default:
+		return base
+	}
+}
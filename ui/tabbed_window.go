@@ -3,6 +3,7 @@ package ui
 import (
 	"claude-squad/session"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -112,15 +113,62 @@ func (w *TabbedWindow) UpdateDiff(instance *session.Instance) {
 func (w *TabbedWindow) ScrollUp() {
 	if w.activeTab == 1 { // Diff tab
 		w.diff.ScrollUp()
+	} else if w.preview.InHistory() {
+		w.preview.history.ScrollUp()
 	}
 }
 
 func (w *TabbedWindow) ScrollDown() {
 	if w.activeTab == 1 { // Diff tab
 		w.diff.ScrollDown()
+	} else if w.preview.InHistory() {
+		w.preview.history.ScrollDown()
 	}
 }
 
+// EnterHistory switches to the preview tab and shows a history view of the scrollback content.
+func (w *TabbedWindow) EnterHistory(content string) {
+	w.activeTab = PreviewTab
+	w.preview.EnterHistory(content)
+}
+
+// IsInHistory returns true if the preview tab is showing the history view.
+func (w *TabbedWindow) IsInHistory() bool {
+	return w.activeTab == PreviewTab && w.preview.InHistory()
+}
+
+// HandleHistoryKey passes a key press to the history view. It returns true if the history view was closed.
+func (w *TabbedWindow) HandleHistoryKey(msg tea.KeyMsg) bool {
+	if !w.preview.InHistory() {
+		return true
+	}
+	if w.preview.history.HandleKeyPress(msg) {
+		w.preview.ExitHistory()
+		return true
+	}
+	return false
+}
+
+// FocusDiff starts keyboard navigation of the diff's files, hunks and lines.
+func (w *TabbedWindow) FocusDiff() {
+	w.diff.Focus()
+}
+
+// ToggleDiffSplit switches the diff between unified and side by side.
+func (w *TabbedWindow) ToggleDiffSplit() {
+	w.diff.ToggleSplit()
+}
+
+// HandleDiffKey passes a key press to the diff pane, and returns what the app should do next.
+func (w *TabbedWindow) HandleDiffKey(msg tea.KeyMsg) DiffAction {
+	return w.diff.HandleKeyPress(msg)
+}
+
+// DiffCommentTarget returns the file and line of the diff under the cursor, which a new comment goes on.
+func (w *TabbedWindow) DiffCommentTarget() (string, int) {
+	return w.diff.CommentTarget()
+}
+
 // IsInDiffTab returns true if the diff tab is currently active
 func (w *TabbedWindow) IsInDiffTab() bool {
 	return w.activeTab == 1